@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codecraft3r/packwiz/cmdshared"
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/dixonwille/wmenu.v4"
+)
+
+// adoptExtensions are the extensions of files that can be looked up by adopt
+var adoptExtensions = []string{".jar", ".litemod", ".zip"}
+
+// adoptFile is a file in the pack that adopt has looked up, with the conversion that will be done for it
+type adoptFile struct {
+	// Path is the path of the file in the index
+	Path   string
+	File   core.IdentifyFile
+	Result core.IdentifyResult
+	// Mod is the metadata the file will be converted to, if any
+	Mod *core.Mod
+	// Existing is metadata already in the pack for the same project or metadata path, which is replaced
+	Existing *core.Mod
+	// Partial is set if Mod was chosen from the partial matches for the file, to replace the file with a different one
+	Partial bool
+}
+
+func (f adoptFile) status() string {
+	switch {
+	case f.Result.Mod == nil && len(f.Result.Candidates) > 0:
+		if f.Mod != nil {
+			return "partial match (replace)"
+		}
+		return fmt.Sprintf("partial match (%d candidates)", len(f.Result.Candidates))
+	case f.Mod == nil:
+		return "skipped"
+	case f.Existing != nil:
+		return "replaces " + f.Existing.Name
+	default:
+		return "convert"
+	}
+}
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt [paths...]",
+	Short: "Convert files added directly to the modpack into metadata files, by finding them on Modrinth or CurseForge",
+	Long: `Convert files added directly to the modpack into metadata files, by finding them on Modrinth or CurseForge.
+
+Every .jar, .litemod and .zip file in the modpack (or in the given files and folders) is looked up on Modrinth by its
+hash, and then on CurseForge by its fingerprint. The proposed conversions are listed before any changes are made;
+files that only partially match a CurseForge file, or that are another version of a project already in the modpack,
+can be resolved interactively.
+
+A file is only removed once the hash of its new metadata file has been checked against it, unless you choose to replace
+it with a partially matching file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Loading modpack...")
+		pack, err := core.LoadPack()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		index, err := pack.LoadIndex()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// Do a refresh to ensure the index contains every file
+		err = index.Refresh()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		mods, err := index.LoadAllMods()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		var roots []string
+		for _, arg := range args {
+			rel, err := index.RelIndexPath(arg)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			roots = append(roots, rel)
+		}
+		paths := findAdoptFiles(index, roots)
+		if len(paths) == 0 {
+			fmt.Println("No files found to convert!")
+			return
+		}
+
+		fmt.Printf("Hashing %d files...\n", len(paths))
+		files := make([]adoptFile, 0, len(paths))
+		identifyFiles := make([]core.IdentifyFile, 0, len(paths))
+		for _, p := range paths {
+			file, err := core.HashIdentifyFile(index.ResolveIndexPath(p))
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			files = append(files, adoptFile{Path: p, File: file})
+			identifyFiles = append(identifyFiles, file)
+		}
+
+		fmt.Println("Looking up files...")
+		results, err := core.IdentifyFiles(identifyFiles, pack)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		for i := range files {
+			files[i].Result = results[i]
+			files[i].Mod = results[i].Mod
+		}
+
+		var matched []*adoptFile
+		unmatched := 0
+		for i := range files {
+			f := &files[i]
+			if f.Result.Mod == nil && len(f.Result.Candidates) == 0 {
+				unmatched++
+				continue
+			}
+			matched = append(matched, f)
+		}
+		if len(matched) == 0 {
+			fmt.Printf("None of the %d files were found on Modrinth or CurseForge\n", len(files))
+			return
+		}
+		for _, f := range matched {
+			if f.Mod != nil {
+				f.Existing = findExistingMod(f.Mod, mods, index)
+			}
+		}
+
+		fmt.Println()
+		printAdoptTable(matched)
+		fmt.Println()
+		if unmatched > 0 {
+			fmt.Printf("%d files were not found on Modrinth or CurseForge, and will be left as they are\n", unmatched)
+		}
+		if viper.GetBool("adopt.dry-run") {
+			return
+		}
+
+		if !viper.GetBool("non-interactive") {
+			resolveAdoptFiles(matched, mods, index)
+		}
+
+		var conversions []*adoptFile
+		for _, f := range matched {
+			if f.Mod != nil {
+				conversions = append(conversions, f)
+			}
+		}
+		if len(conversions) == 0 {
+			fmt.Println("No files to convert!")
+			return
+		}
+		if !cmdshared.PromptYesNo(fmt.Sprintf("Convert %d files into metadata files? [Y/n]: ", len(conversions))) {
+			return
+		}
+
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		converted := 0
+		written := make(map[string]string)
+		for _, f := range conversions {
+			if other, ok := written[f.Mod.GetFilePath()]; ok {
+				fmt.Printf("Not converting %s: %s has already been converted to %s\n", f.Path, other, f.Mod.GetFilePath())
+				continue
+			}
+			// Partial matches are different files by definition, which the user has chosen to replace the file with
+			if !f.Partial {
+				if err := verifyAdoptFile(f.File.Path, f.Mod); err != nil {
+					fmt.Printf("Not converting %s: %v\n", f.Path, err)
+					continue
+				}
+			}
+			if f.Existing != nil && f.Existing.GetFilePath() != f.Mod.GetFilePath() {
+				err = removeMods([]*core.Mod{f.Existing}, &index, tx)
+				if err != nil {
+					tx.Rollback()
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+			format, hash, err := tx.WriteMod(f.Mod)
+			if err == nil {
+				err = index.RefreshFileWithHash(f.Mod.GetFilePath(), format, hash, true)
+			}
+			if err != nil {
+				tx.Rollback()
+				fmt.Printf("Failed to save metadata for %s: %v\n", f.Mod.Name, err)
+				os.Exit(1)
+			}
+			written[f.Mod.GetFilePath()] = f.Path
+
+			err = tx.RemoveFile(f.File.Path)
+			if err == nil {
+				err = index.RemoveFile(f.File.Path)
+			}
+			if err != nil {
+				tx.Rollback()
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if f.Partial {
+				fmt.Printf("%s replaced with %s (%s)\n", f.Path, f.Mod.GetFilePath(), f.Mod.FileName)
+			} else {
+				fmt.Printf("%s converted to %s\n", f.Path, f.Mod.GetFilePath())
+			}
+			converted++
+		}
+
+		err = tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Converted %d/%d files!\n", converted, len(conversions))
+	},
+}
+
+// findAdoptFiles returns the files in the index that can be looked up, optionally only those in the given paths
+func findAdoptFiles(index core.Index, roots []string) []string {
+	var paths []string
+	for p, f := range index.Files {
+		if f.IsMetaFile() {
+			continue
+		}
+		ext := strings.ToLower(path.Ext(p))
+		found := false
+		for _, v := range adoptExtensions {
+			if ext == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		if len(roots) > 0 {
+			inRoot := false
+			for _, root := range roots {
+				if root == "." || p == root || strings.HasPrefix(p, root+"/") {
+					inRoot = true
+					break
+				}
+			}
+			if !inRoot {
+				continue
+			}
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// findExistingMod finds metadata in the pack for the same project as the given metadata, or at the same path
+func findExistingMod(mod *core.Mod, mods []*core.Mod, index core.Index) *core.Mod {
+	sources := core.GetModSources(mod)
+	newPath, err := index.RelIndexPath(mod.GetFilePath())
+	for _, v := range mods {
+		for s, i := range core.GetModSources(v) {
+			if sources[s] == i {
+				return v
+			}
+		}
+		if err == nil {
+			if p, err := index.RelIndexPath(v.GetFilePath()); err == nil && p == newPath {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func printAdoptTable(files []*adoptFile) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FILE\tSTATUS\tSOURCE\tPROJECT\tMETADATA FILE")
+	for _, f := range files {
+		mod := f.Mod
+		if mod == nil && len(f.Result.Candidates) > 0 {
+			mod = f.Result.Candidates[0]
+		}
+		source, project, metaFile := "", "", ""
+		if mod != nil {
+			source, _, _ = core.GetModSource(mod)
+			project = mod.Name + " (" + mod.FileName + ")"
+			metaFile = filepath.ToSlash(mod.GetFilePath())
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Path, f.status(), source, project, metaFile)
+	}
+	_ = w.Flush()
+}
+
+// resolveAdoptFiles asks the user which partial match to use for each file without an exact match, and whether to
+// replace existing metadata for files that match a project already in the pack
+func resolveAdoptFiles(files []*adoptFile, mods []*core.Mod, index core.Index) {
+	for _, f := range files {
+		if f.Mod == nil {
+			fmt.Printf("%s partially matches %d files:\n", f.Path, len(f.Result.Candidates))
+			menu := wmenu.NewMenu("Choose a number:")
+			menu.Option("Keep "+f.Path+" as it is", nil, true, nil)
+			for _, c := range f.Result.Candidates {
+				source, _, _ := core.GetModSource(c)
+				menu.Option(fmt.Sprintf("Replace with %s (%s) from %s", c.Name, c.FileName, source), c, false, nil)
+			}
+			menu.Action(func(opts []wmenu.Opt) error {
+				if len(opts) != 1 || opts[0].Value == nil {
+					return nil
+				}
+				mod, ok := opts[0].Value.(*core.Mod)
+				if !ok {
+					return errors.New("error converting interface from wmenu")
+				}
+				f.Mod = mod
+				f.Partial = true
+				f.Existing = findExistingMod(mod, mods, index)
+				return nil
+			})
+			err := menu.Run()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if f.Mod != nil && f.Existing != nil {
+			if !cmdshared.PromptYesNo(fmt.Sprintf("%s is %s, which is already in the modpack as %s; replace it? [Y/n]: ",
+				f.Path, f.Mod.Name, f.Existing.GetFilePath())) {
+				f.Mod = nil
+			}
+		}
+	}
+}
+
+// verifyAdoptFile checks that a file on disk matches the hash in its new metadata
+func verifyAdoptFile(p string, mod *core.Mod) error {
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hasher, err := core.GetHashImpl(mod.Download.HashFormat)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return err
+	}
+	if hash := hasher.HashToString(hasher.Sum(nil)); !strings.EqualFold(hash, mod.Download.Hash) {
+		return fmt.Errorf("%s hash %s doesn't match %s", mod.Download.HashFormat, hash, mod.Download.Hash)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().Bool("dry-run", false, "List the proposed conversions without making any changes")
+	_ = viper.BindPFlag("adopt.dry-run", adoptCmd.Flags().Lookup("dry-run"))
+}
@@ -0,0 +1,471 @@
+package cmd
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of downloaded files used when exporting modpacks",
+}
+
+func loadCacheIndex() *core.CacheIndex {
+	cacheIndex, err := core.LoadCacheIndex()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return cacheIndex
+}
+
+func saveCacheIndex(cacheIndex *core.CacheIndex) {
+	err := cacheIndex.Save()
+	if err != nil {
+		fmt.Printf("Failed to save cache index: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// formatSize formats a number of bytes in human-readable units
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// parseSize parses a size such as 500M, 2GB or 1.5GiB (units are powers of 1024)
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if len(s) > 0 {
+		if idx := strings.IndexByte("KMGT", s[len(s)-1]); idx >= 0 {
+			for i := 0; i <= idx; i++ {
+				multiplier *= 1024
+			}
+			s = s[:len(s)-1]
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %s", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// parseAge parses a duration, also accepting days (d) and weeks (w) such as 30d or 2w
+func parseAge(age string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(age, suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(age, suffix), 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("invalid duration %s", age)
+			}
+			return time.Duration(value * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(age)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %s", age)
+	}
+	return d, nil
+}
+
+// removeCacheEntries removes files from the cache (unless dry-run is set) and saves the index
+func removeCacheEntries(cacheIndex *core.CacheIndex, entries []core.CacheEntry, dryRun bool, reason string) {
+	if len(entries) == 0 {
+		if !dryRun {
+			// The index might have been updated with new hashes or missing files
+			saveCacheIndex(cacheIndex)
+		}
+		fmt.Println("No files to remove!")
+		return
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	if dryRun {
+		fmt.Printf("Would remove %d %s files (%s)\n", len(entries), reason, formatSize(size))
+		return
+	}
+	err := cacheIndex.Remove(entries)
+	if err != nil {
+		fmt.Println(err)
+		// Save the files that were removed
+		saveCacheIndex(cacheIndex)
+		os.Exit(1)
+	}
+	saveCacheIndex(cacheIndex)
+	fmt.Printf("Removed %d %s files (%s)\n", len(entries), reason, formatSize(size))
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size and contents of the cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheIndex := loadCacheIndex()
+		entries := cacheIndex.Entries()
+
+		var size int64
+		var missing int
+		var oldest, newest time.Time
+		for _, entry := range entries {
+			if entry.Missing {
+				missing++
+				continue
+			}
+			size += entry.Size
+			if oldest.IsZero() || entry.LastUsed.Before(oldest) {
+				oldest = entry.LastUsed
+			}
+			if entry.LastUsed.After(newest) {
+				newest = entry.LastUsed
+			}
+		}
+
+		fmt.Printf("Cache folder: %s\n", cacheIndex.Path())
+		fmt.Printf("Files: %d\n", len(entries)-missing)
+		if missing > 0 {
+			fmt.Printf("Missing files: %d (run packwiz cache prune to remove them from the index)\n", missing)
+		}
+		fmt.Printf("Total size: %s\n", formatSize(size))
+
+		formats := cacheIndex.GetHashFormats()
+		names := make([]string, 0, len(formats))
+		for format := range formats {
+			names = append(names, format)
+		}
+		sort.Strings(names)
+		for i, format := range names {
+			names[i] = fmt.Sprintf("%s (%d)", format, formats[format])
+		}
+		fmt.Printf("Hash formats: %s\n", strings.Join(names, ", "))
+		if !oldest.IsZero() {
+			fmt.Printf("Least recently used: %s\n", oldest.Format(time.DateTime))
+			fmt.Printf("Most recently used: %s\n", newest.Format(time.DateTime))
+		}
+	},
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Rehash every file in the cache, and check it matches the hashes in the cache index",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheIndex := loadCacheIndex()
+		entries := cacheIndex.Entries()
+
+		var invalid []core.CacheEntry
+		for _, entry := range entries {
+			var err error
+			if entry.Missing {
+				err = errors.New("file is missing")
+			} else {
+				err = cacheIndex.Verify(entry)
+			}
+			if err != nil {
+				fmt.Printf("%s: %v\n", entry.Path, err)
+				invalid = append(invalid, entry)
+			}
+		}
+		fmt.Printf("%d of %d files are valid\n", len(entries)-len(invalid), len(entries))
+
+		if len(invalid) > 0 {
+			if !viper.GetBool("cache.verify.fix") {
+				fmt.Println("Use --fix to remove invalid files from the cache")
+				os.Exit(1)
+			}
+			removeCacheEntries(cacheIndex, invalid, false, "invalid")
+		}
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the least recently used files from the cache",
+	Long: `Remove the least recently used files from the cache.
+
+Files that haven't been used for longer than --older-than are removed, then the least recently used files are removed
+until the cache is smaller than --max-size. Files that are missing from the cache folder are always removed from the
+index.`,
+	Example: `  packwiz cache prune --older-than 30d
+  packwiz cache prune --max-size 2GB`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var maxAge time.Duration
+		var maxSize int64 = -1
+		var err error
+		if viper.GetString("cache.prune.older-than") == "" && viper.GetString("cache.prune.max-size") == "" {
+			fmt.Println("You must specify --older-than and/or --max-size")
+			os.Exit(1)
+		}
+		if olderThan := viper.GetString("cache.prune.older-than"); olderThan != "" {
+			maxAge, err = parseAge(olderThan)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if maxSizeStr := viper.GetString("cache.prune.max-size"); maxSizeStr != "" {
+			maxSize, err = parseSize(maxSizeStr)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		cacheIndex := loadCacheIndex()
+		entries := cacheIndex.Entries()
+		// Least recently used first
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].LastUsed.Before(entries[j].LastUsed)
+		})
+
+		var toRemove []core.CacheEntry
+		var kept []core.CacheEntry
+		var keptSize int64
+		for _, entry := range entries {
+			if entry.Missing || (maxAge > 0 && time.Since(entry.LastUsed) > maxAge) {
+				toRemove = append(toRemove, entry)
+			} else {
+				kept = append(kept, entry)
+				keptSize += entry.Size
+			}
+		}
+		for i := 0; maxSize >= 0 && keptSize > maxSize && i < len(kept); i++ {
+			toRemove = append(toRemove, kept[i])
+			keptSize -= kept[i].Size
+		}
+
+		removeCacheEntries(cacheIndex, toRemove, viper.GetBool("cache.prune.dry-run"), "unused")
+	},
+}
+
+var cacheGcCmd = &cobra.Command{
+	Use:   "gc [pack files]...",
+	Short: "Remove every file from the cache that isn't used by any of the given modpacks",
+	Long: `Remove every file from the cache that isn't used by any of the given modpacks (pack.toml files), defaulting to the
+current modpack.`,
+	Example: `  packwiz cache gc ../pack-a/pack.toml ../pack-b/pack.toml`,
+	Args:    cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		packFiles := args
+		if len(packFiles) == 0 {
+			packFiles = []string{viper.GetString("pack-file")}
+		}
+
+		cacheIndex := loadCacheIndex()
+		var missing []core.CacheEntry
+		for _, entry := range cacheIndex.Entries() {
+			if entry.Missing {
+				missing = append(missing, entry)
+			}
+		}
+		// Missing files can't be rehashed when looking up files, so remove them first
+		if len(missing) > 0 {
+			err := cacheIndex.Remove(missing)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		used := make(map[string]bool)
+		for _, packFile := range packFiles {
+			pack, err := core.LoadPackFile(packFile)
+			if err != nil {
+				fmt.Printf("Failed to load %s: %v\n", packFile, err)
+				os.Exit(1)
+			}
+			index, err := pack.LoadIndex()
+			if err != nil {
+				fmt.Printf("Failed to load index of %s: %v\n", packFile, err)
+				os.Exit(1)
+			}
+			mods, err := index.LoadAllMods()
+			if err != nil {
+				fmt.Printf("Failed to load metadata files of %s: %v\n", packFile, err)
+				os.Exit(1)
+			}
+			for _, mod := range mods {
+				cacheHash, err := cacheIndex.FindCacheHash(mod.Download.HashFormat, mod.Download.Hash)
+				if err != nil {
+					fmt.Printf("Failed to look up %s in the cache: %v\n", mod.Name, err)
+					os.Exit(1)
+				}
+				if cacheHash != "" {
+					used[cacheHash] = true
+				}
+			}
+		}
+
+		var toRemove []core.CacheEntry
+		for _, entry := range cacheIndex.Entries() {
+			if !used[entry.GetCacheHash()] {
+				toRemove = append(toRemove, entry)
+			}
+		}
+		fmt.Printf("%d files in the cache are used by %d modpack(s)\n", len(used), len(packFiles))
+		removeCacheEntries(cacheIndex, toRemove, viper.GetBool("cache.gc.dry-run"), "unused")
+	},
+}
+
+var cacheImportCmd = &cobra.Command{
+	Use:   "import [folder|zip]...",
+	Short: "Add files from a folder or zip file to the cache, e.g. for CurseForge files that must be downloaded manually",
+	Long: `Add files from a folder or zip file to the cache, e.g. for CurseForge files that must be downloaded manually.
+
+Every file in the given folders (including subfolders) and zip files is added; other files are added directly. Files
+are hashed with every format used by exports, so they can be used without downloading anything. If run in a modpack
+folder, the files in the modpack that have been imported are listed.`,
+	Example: `  packwiz cache import ~/Downloads/mods
+  packwiz cache import manual-downloads.zip`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheIndex := loadCacheIndex()
+
+		var imported []map[string]string
+		existing := 0
+		importFile := func(name string, open func() (io.ReadCloser, error)) {
+			src, err := open()
+			if err != nil {
+				fmt.Printf("Failed to import %s: %v\n", name, err)
+				return
+			}
+			hashes, exists, err := cacheIndex.Import(src)
+			_ = src.Close()
+			if err != nil {
+				fmt.Printf("Failed to import %s: %v\n", name, err)
+				return
+			}
+			imported = append(imported, hashes)
+			if exists {
+				existing++
+			}
+		}
+
+		for _, arg := range args {
+			info, err := os.Stat(arg)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if info.IsDir() {
+				err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					if d.Type().IsRegular() {
+						importFile(path, func() (io.ReadCloser, error) {
+							return os.Open(path)
+						})
+					}
+					return nil
+				})
+			} else if strings.EqualFold(filepath.Ext(arg), ".zip") {
+				var zipFile *zip.ReadCloser
+				zipFile, err = zip.OpenReader(arg)
+				if err == nil {
+					for _, f := range zipFile.File {
+						if !f.FileInfo().IsDir() {
+							importFile(arg+"/"+f.Name, f.Open)
+						}
+					}
+					_ = zipFile.Close()
+				}
+			} else {
+				importFile(arg, func() (io.ReadCloser, error) {
+					return os.Open(arg)
+				})
+			}
+			if err != nil {
+				fmt.Printf("Failed to import %s: %v\n", arg, err)
+				saveCacheIndex(cacheIndex)
+				os.Exit(1)
+			}
+		}
+		saveCacheIndex(cacheIndex)
+		fmt.Printf("Imported %d files (%d were already in the cache)\n", len(imported), existing)
+
+		// List the files in the current modpack that can now be exported
+		if _, err := os.Stat(viper.GetString("pack-file")); err != nil {
+			return
+		}
+		pack, err := core.LoadPack()
+		if err != nil {
+			return
+		}
+		index, err := pack.LoadIndex()
+		if err != nil {
+			return
+		}
+		mods, err := index.LoadAllMods()
+		if err != nil {
+			return
+		}
+		var satisfied []string
+		for _, mod := range mods {
+			for _, hashes := range imported {
+				if strings.EqualFold(hashes[mod.Download.HashFormat], mod.Download.Hash) {
+					satisfied = append(satisfied, mod.Name)
+					break
+				}
+			}
+		}
+		if len(satisfied) > 0 {
+			sort.Strings(satisfied)
+			fmt.Printf("Files in this modpack that are now in the cache (%d):\n", len(satisfied))
+			for _, name := range satisfied {
+				fmt.Println(name)
+			}
+		} else {
+			fmt.Println("None of the imported files are used by this modpack")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheImportCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheVerifyCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheGcCmd)
+
+	cacheVerifyCmd.Flags().Bool("fix", false, "Remove files that don't match their hashes from the cache")
+	_ = viper.BindPFlag("cache.verify.fix", cacheVerifyCmd.Flags().Lookup("fix"))
+
+	cachePruneCmd.Flags().String("older-than", "", "Remove files that haven't been used for this long (e.g. 72h, 30d, 2w)")
+	_ = viper.BindPFlag("cache.prune.older-than", cachePruneCmd.Flags().Lookup("older-than"))
+	cachePruneCmd.Flags().String("max-size", "", "Remove the least recently used files until the cache is at most this size (e.g. 500MB, 2GB)")
+	_ = viper.BindPFlag("cache.prune.max-size", cachePruneCmd.Flags().Lookup("max-size"))
+	cachePruneCmd.Flags().Bool("dry-run", false, "Show how much would be removed without removing anything")
+	_ = viper.BindPFlag("cache.prune.dry-run", cachePruneCmd.Flags().Lookup("dry-run"))
+
+	cacheGcCmd.Flags().Bool("dry-run", false, "Show how much would be removed without removing anything")
+	_ = viper.BindPFlag("cache.gc.dry-run", cacheGcCmd.Flags().Lookup("dry-run"))
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/russross/blackfriday/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//go:embed changelog-templates/markdown.md
+var changelogMarkdownTemplate string
+
+//go:embed changelog-templates/discord.md
+var changelogDiscordTemplate string
+
+//go:embed changelog-templates/html.html
+var changelogHTMLTemplate string
+
+// changelogData is the data that changelog templates are executed with
+type changelogData struct {
+	// Name is the name of the new version of the pack, and OldVersion and NewVersion are the version fields of the two
+	// versions of the pack (which may be empty)
+	Name       string
+	OldVersion string
+	NewVersion string
+	// From and To are the pack files or git revisions that were compared
+	From     string
+	To       string
+	Versions []changelogVersion
+	Added    []changelogMod
+	Removed  []changelogMod
+	// Updated contains upgraded, downgraded and otherwise changed mods
+	Updated []changelogMod
+	Files   []core.FileChange
+}
+
+type changelogVersion struct {
+	Name string
+	Old  string
+	New  string
+}
+
+type changelogMod struct {
+	Name string
+	// Kind is the kind of change (see core.ModChange)
+	Kind    string
+	OldFile string
+	NewFile string
+	// Changelogs are the upstream changelogs of each new version of an updated mod, newest first
+	Changelogs []core.Changelog
+	// ChangelogError is set if the changelogs couldn't be retrieved
+	ChangelogError string
+
+	changes core.ModChange
+}
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate a changelog between two versions of a modpack",
+	Long: `Generate a changelog between two versions of a modpack, listing the mods that have been added, removed and updated.
+
+The versions given to --from and --to can be pack.toml files, folders containing pack.toml, or git revisions of the
+current modpack (see packwiz diff); --to defaults to the current modpack. The changelogs of each version of an updated
+mod are retrieved from Modrinth, CurseForge or GitHub.
+
+The changelog can be written as Markdown, HTML, or Markdown for Discord messages, or using a custom template (a Go
+text/template, or html/template for the html format).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := viper.GetString("changelog.format")
+		if format != "markdown" && format != "discord" && format != "html" {
+			fmt.Printf("Unknown format %s; must be markdown, discord or html\n", format)
+			os.Exit(1)
+		}
+		fromSpec, toSpec := viper.GetString("changelog.from"), viper.GetString("changelog.to")
+		from, cleanupFrom, err := loadPackSpec(fromSpec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer cleanupFrom()
+		to, cleanupTo, err := loadPackSpec(toSpec)
+		if err != nil {
+			cleanupFrom()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer cleanupTo()
+		if toSpec == "" {
+			toSpec = "current"
+		}
+
+		data := newChangelogData(core.DiffPacks(from, to), from.Pack, to.Pack)
+		data.From, data.To = fromSpec, toSpec
+		if !viper.GetBool("changelog.no-fetch") {
+			fetchChangelogs(data.Updated)
+		}
+
+		out, err := renderChangelog(format, viper.GetString("changelog.template"), data)
+		if err != nil {
+			cleanupFrom()
+			cleanupTo()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if output := viper.GetString("changelog.output"); output != "" {
+			err = os.WriteFile(output, out, 0644)
+			if err == nil {
+				fmt.Printf("Changelog written to %s\n", output)
+			}
+		} else {
+			_, err = os.Stdout.Write(out)
+		}
+		if err != nil {
+			cleanupFrom()
+			cleanupTo()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func newChangelogData(diff core.PackDiff, oldPack core.Pack, newPack core.Pack) changelogData {
+	data := changelogData{
+		Name:       newPack.Name,
+		OldVersion: oldPack.Version,
+		NewVersion: newPack.Version,
+		Files:      diff.Files,
+	}
+	for _, v := range diff.Versions {
+		data.Versions = append(data.Versions, changelogVersion{
+			Name: core.ComponentToFriendlyName(v.Component),
+			Old:  v.Old,
+			New:  v.New,
+		})
+	}
+	for _, c := range diff.Mods {
+		m := changelogMod{Name: c.Name(), Kind: c.Kind}
+		if c.Old != nil {
+			m.OldFile = c.Old.FileName
+		}
+		if c.New != nil {
+			m.NewFile = c.New.FileName
+		}
+		switch c.Kind {
+		case core.ChangeAdded:
+			data.Added = append(data.Added, m)
+		case core.ChangeRemoved:
+			data.Removed = append(data.Removed, m)
+		case core.ChangeUpgraded, core.ChangeDowngraded, core.ChangeUpdated:
+			m.changes = c
+			data.Updated = append(data.Updated, m)
+		}
+	}
+	return data
+}
+
+// fetchChangelogs retrieves the upstream changelogs of upgraded and updated mods
+func fetchChangelogs(mods []changelogMod) {
+	if len(mods) > 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Retrieving changelogs...")
+	}
+	for i := range mods {
+		m := &mods[i]
+		if m.Kind == core.ChangeDowngraded {
+			continue
+		}
+		changelogs, err := core.GetChangelogs(m.changes.Old, m.changes.New)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to retrieve changelogs for %s: %v\n", m.Name, err)
+			m.ChangelogError = err.Error()
+			continue
+		}
+		m.Changelogs = changelogs
+	}
+}
+
+var (
+	markdownImageRegex   = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownHeadingRegex = regexp.MustCompile(`(?m)^#{1,6}\s+(.*?)\s*#*\s*$`)
+	markdownLinkRegex    = regexp.MustCompile(`\]\((https?://[^)\s]+)\)`)
+	blankLinesRegex      = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+)
+
+// truncateText shortens a string to at most n characters, ending it with an ellipsis if it was shortened
+func truncateText(n int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+var changelogFuncs = map[string]any{
+	// quote turns text into a Markdown block quote
+	"quote": func(s string) string {
+		lines := strings.Split(strings.TrimSpace(s), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+	"truncate": truncateText,
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	},
+	// discord converts Markdown to the subset supported in Discord messages, without embedded links or images
+	"discord": func(s string) string {
+		s = markdownImageRegex.ReplaceAllString(s, "")
+		s = markdownHeadingRegex.ReplaceAllString(s, "**$1**")
+		s = markdownLinkRegex.ReplaceAllString(s, "](<$1>)")
+		s = blankLinesRegex.ReplaceAllString(s, "\n\n")
+		return strings.TrimSpace(s)
+	},
+	"markdown": func(s string) htmlTemplate.HTML {
+		return htmlTemplate.HTML(blackfriday.Run([]byte(s),
+			blackfriday.WithRenderer(blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+				Flags: blackfriday.CommonHTMLFlags | blackfriday.SkipHTML | blackfriday.SkipImages | blackfriday.Safelink,
+			}))))
+	},
+}
+
+// renderChangelog executes the template for a format, or a custom template file if one is given
+func renderChangelog(format string, templateFile string, data changelogData) ([]byte, error) {
+	name, text := "changelog", ""
+	switch format {
+	case "markdown":
+		text = changelogMarkdownTemplate
+	case "discord":
+		text = changelogDiscordTemplate
+	case "html":
+		text = changelogHTMLTemplate
+	}
+	if templateFile != "" {
+		content, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		name, text = templateFile, string(content)
+	}
+
+	var buf bytes.Buffer
+	var t interface {
+		Execute(io.Writer, any) error
+	}
+	var err error
+	if format == "html" {
+		t, err = htmlTemplate.New(name).Funcs(changelogFuncs).Parse(text)
+	} else {
+		t, err = template.New(name).Funcs(changelogFuncs).Parse(text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().String("from", "", "The old version of the modpack: a pack.toml file, a folder containing it, or a git revision")
+	_ = viper.BindPFlag("changelog.from", changelogCmd.Flags().Lookup("from"))
+	_ = changelogCmd.MarkFlagRequired("from")
+	changelogCmd.Flags().String("to", "", "The new version of the modpack: a pack.toml file, a folder containing it, or a git revision (default the current modpack)")
+	_ = viper.BindPFlag("changelog.to", changelogCmd.Flags().Lookup("to"))
+	changelogCmd.Flags().StringP("format", "f", "markdown", "The output format (markdown, discord or html)")
+	_ = viper.BindPFlag("changelog.format", changelogCmd.Flags().Lookup("format"))
+	changelogCmd.Flags().String("template", "", "A template file to use instead of the built-in template for the format")
+	_ = viper.BindPFlag("changelog.template", changelogCmd.Flags().Lookup("template"))
+	changelogCmd.Flags().StringP("output", "o", "", "The file to write the changelog to (default standard output)")
+	_ = viper.BindPFlag("changelog.output", changelogCmd.Flags().Lookup("output"))
+	changelogCmd.Flags().Bool("no-fetch", false, "Don't retrieve the changelogs of updated mods")
+	_ = viper.BindPFlag("changelog.no-fetch", changelogCmd.Flags().Lookup("no-fetch"))
+}
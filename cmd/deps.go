@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/cmdshared"
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// depsCmd represents the deps command
+var depsCmd = &cobra.Command{
+	Use:     "deps",
+	Aliases: []string{"dependencies"},
+	Short:   "View and manage dependencies between files in the modpack",
+	Long: `View and manage dependencies between files in the modpack.
+
+Dependencies are recorded in metadata files when files are added or updated from Modrinth or CurseForge; use
+"packwiz deps refresh" to record them for files that were added before this was supported.
+Files that were added automatically as dependencies are marked as such, so that "packwiz remove" and
+"packwiz deps prune" can remove them once nothing needs them.`,
+}
+
+func loadDependencyGraph() (core.Pack, core.Index, *core.DependencyGraph) {
+	pack, err := core.LoadPack()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	index, err := pack.LoadIndex()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	mods, err := index.LoadAllMods()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return pack, index, core.NewDependencyGraph(mods)
+}
+
+func findGraphMod(graph *core.DependencyGraph, name string) *core.Mod {
+	m := graph.Find(name)
+	if m == nil {
+		fmt.Println("Can't find this file; please ensure you have run packwiz refresh and use the name of the .pw.toml file (defaults to the project slug)")
+		os.Exit(1)
+	}
+	return m
+}
+
+var depsTreeCmd = &cobra.Command{
+	Use:   "tree [name]",
+	Short: "Show the dependency tree of the modpack, or of a single file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, graph := loadDependencyGraph()
+		showOptional := viper.GetBool("deps.tree.optional")
+
+		var roots []*core.Mod
+		if len(args) > 0 {
+			roots = []*core.Mod{findGraphMod(graph, args[0])}
+		} else {
+			roots = graph.Roots()
+		}
+
+		shown := make(map[*core.Mod]bool)
+		for _, m := range roots {
+			printDependencyTree(graph, m, "", shown, showOptional)
+		}
+		if len(args) == 0 {
+			// Mods that only depend on each other have no root; show them separately
+			var remaining []*core.Mod
+			for _, m := range graph.Mods {
+				if !shown[m] {
+					remaining = append(remaining, m)
+				}
+			}
+			sort.Slice(remaining, func(i, j int) bool {
+				return strings.ToLower(remaining[i].Name) < strings.ToLower(remaining[j].Name)
+			})
+			for _, m := range remaining {
+				if !shown[m] {
+					printDependencyTree(graph, m, "", shown, showOptional)
+				}
+			}
+		}
+	},
+}
+
+// printDependencyTree prints a mod and its dependencies; the dependencies of mods that have already been shown are
+// replaced with (*)
+func printDependencyTree(graph *core.DependencyGraph, m *core.Mod, prefix string, shown map[*core.Mod]bool, showOptional bool) {
+	if prefix == "" {
+		if shown[m] {
+			fmt.Println(m.Name + " (*)")
+			return
+		}
+		fmt.Println(m.Name)
+	}
+	shown[m] = true
+
+	var edges []core.DependencyEdge
+	for _, e := range graph.Dependencies(m) {
+		switch e.Dependency.Type {
+		case core.DependencyRequired:
+			edges = append(edges, e)
+		case core.DependencyOptional:
+			if showOptional {
+				edges = append(edges, e)
+			}
+		case core.DependencyIncompatible:
+			// Only shown when the incompatible mod is installed
+			if e.Mod != nil {
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	for i, e := range edges {
+		branch, childPrefix := "├── ", prefix+"│   "
+		if i == len(edges)-1 {
+			branch, childPrefix = "└── ", prefix+"    "
+		}
+
+		var notes []string
+		if e.Dependency.Type != core.DependencyRequired {
+			notes = append(notes, e.Dependency.Type)
+		}
+		name := e.Dependency.String()
+		if e.Mod == nil {
+			notes = append(notes, "not installed")
+		} else {
+			name = e.Mod.Name
+			if shown[e.Mod] && e.Dependency.Type == core.DependencyRequired && len(graph.Dependencies(e.Mod)) > 0 {
+				notes = append(notes, "*")
+			}
+		}
+		if len(notes) > 0 {
+			name += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Println(prefix + branch + name)
+
+		if e.Mod != nil && e.Dependency.Type == core.DependencyRequired && !shown[e.Mod] {
+			printDependencyTree(graph, e.Mod, childPrefix, shown, showOptional)
+		}
+	}
+}
+
+var depsWhyCmd = &cobra.Command{
+	Use:   "why [name]",
+	Short: "Show why a file is in the modpack, by listing the files that depend on it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, graph := loadDependencyGraph()
+		m := findGraphMod(graph, args[0])
+
+		chains := graph.Why(m)
+		if len(chains) == 0 {
+			fmt.Printf("%s is not required by any other file\n", m.Name)
+			return
+		}
+		fmt.Printf("%s is required by:\n", m.Name)
+		for _, chain := range chains {
+			names := make([]string, len(chain))
+			for i, v := range chain {
+				names[i] = v.Name
+			}
+			fmt.Println("  " + strings.Join(names, " -> "))
+		}
+	},
+}
+
+var depsRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Look up and record the dependencies of every file from Modrinth or CurseForge",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pack, index, graph := loadDependencyGraph()
+
+		fmt.Println("Retrieving dependency data...")
+		modsBySource := make(map[string][]*core.Mod)
+		for _, m := range graph.Mods {
+			if source, _, ok := core.GetModSource(m); ok {
+				modsBySource[source] = append(modsBySource[source], m)
+			}
+		}
+
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		changed := 0
+		for source, mods := range modsBySource {
+			deps, err := core.DependencyResolvers[source].GetDependencies(mods, pack)
+			if err != nil {
+				fmt.Printf("Failed to retrieve dependencies from %s: %v\n", source, err)
+				continue
+			}
+			for i, m := range mods {
+				if (len(deps[i]) == 0 && len(m.Dependencies) == 0) || reflect.DeepEqual(deps[i], m.Dependencies) {
+					continue
+				}
+				m.Dependencies = deps[i]
+				format, hash, err := tx.WriteMod(m)
+				if err != nil {
+					tx.Rollback()
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				err = index.RefreshFileWithHash(m.GetFilePath(), format, hash, true)
+				if err != nil {
+					tx.Rollback()
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				changed++
+			}
+		}
+
+		// Rebuild the graph with the new dependencies
+		graph = core.NewDependencyGraph(graph.Mods)
+		for _, v := range graph.Incompatibilities() {
+			fmt.Printf("Warning: %s is incompatible with %s\n", v.Mod.Name, v.With.Name)
+		}
+
+		missing := graph.Missing()
+		if len(missing) > 0 {
+			fmt.Println("Missing dependencies:")
+			var dependents []*core.Mod
+			for _, v := range missing {
+				fmt.Printf("  %s (required by %s)\n", v.Dependency, v.Mod.Name)
+				if len(dependents) == 0 || dependents[len(dependents)-1] != v.Mod {
+					dependents = append(dependents, v.Mod)
+				}
+			}
+
+			deps, err := core.ResolveDependencies(dependents, graph.Mods, pack)
+			if err != nil {
+				fmt.Printf("Error retrieving dependency data: %v\n", err)
+			}
+			if len(deps) > 0 && cmdshared.PromptYesNo("Would you like to add them? [Y/n]: ") {
+				for _, v := range deps {
+					format, hash, err := tx.WriteMod(v)
+					if err != nil {
+						tx.Rollback()
+						fmt.Println(err)
+						os.Exit(1)
+					}
+					err = index.RefreshFileWithHash(v.GetFilePath(), format, hash, true)
+					if err != nil {
+						tx.Rollback()
+						fmt.Println(err)
+						os.Exit(1)
+					}
+					fmt.Printf("Dependency \"%s\" successfully added! (%s)\n", v.Name, v.FileName)
+					changed++
+				}
+			}
+		}
+
+		if changed == 0 {
+			fmt.Println("Dependencies are up to date!")
+			return
+		}
+		err := tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Dependencies refreshed! (%d files changed)\n", changed)
+	},
+}
+
+var depsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove files that were added as dependencies but are no longer needed by any other file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pack, index, graph := loadDependencyGraph()
+
+		unneeded := graph.Unneeded(nil)
+		if len(unneeded) == 0 {
+			fmt.Println("No unneeded dependencies found!")
+			return
+		}
+		fmt.Println("The following dependencies are no longer needed by any other file:")
+		for _, v := range unneeded {
+			fmt.Println(v.Name)
+		}
+		if viper.GetBool("deps.prune.dry-run") || !cmdshared.PromptYesNo("Would you like to remove them? [Y/n]: ") {
+			return
+		}
+
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		err := removeMods(unneeded, &index, tx)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(depsCmd)
+	depsCmd.AddCommand(depsTreeCmd)
+	depsCmd.AddCommand(depsWhyCmd)
+	depsCmd.AddCommand(depsRefreshCmd)
+	depsCmd.AddCommand(depsPruneCmd)
+
+	depsTreeCmd.Flags().Bool("optional", false, "Also show optional dependencies")
+	_ = viper.BindPFlag("deps.tree.optional", depsTreeCmd.Flags().Lookup("optional"))
+	depsPruneCmd.Flags().Bool("dry-run", false, "List unneeded dependencies without removing them")
+	_ = viper.BindPFlag("deps.prune.dry-run", depsPruneCmd.Flags().Lookup("dry-run"))
+}
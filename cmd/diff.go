@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Show the differences between two versions of a modpack",
+	Long: `Show the differences between two versions of a modpack.
+
+Each version can be a pack.toml file, a folder containing pack.toml, or a git revision (e.g. a tag, branch or commit)
+of the current modpack. If only one version is given, it is compared with the current modpack.
+
+Metadata files are matched by the project they are from (on Modrinth, CurseForge or GitHub) or their download URL, so
+mods that have been renamed or moved are shown as updated rather than removed and added.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fromSpec, toSpec := args[0], ""
+		if len(args) > 1 {
+			toSpec = args[1]
+		}
+		from, cleanupFrom, err := loadPackSpec(fromSpec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer cleanupFrom()
+		to, cleanupTo, err := loadPackSpec(toSpec)
+		if err != nil {
+			cleanupFrom()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer cleanupTo()
+
+		diff := core.DiffPacks(from, to)
+		if toSpec == "" {
+			toSpec = "current"
+		}
+		switch viper.GetString("diff.format") {
+		case "text", "":
+			err = writeDiffText(os.Stdout, diff)
+		case "json":
+			err = writeDiffJSON(os.Stdout, diff, fromSpec, toSpec, from.Index, to.Index)
+		case "markdown", "md":
+			err = writeDiffMarkdown(os.Stdout, diff, fromSpec, toSpec)
+		default:
+			err = fmt.Errorf("unknown format %s; must be text, json or markdown", viper.GetString("diff.format"))
+		}
+		if err != nil {
+			cleanupFrom()
+			cleanupTo()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// diffModNote describes the change to a mod that is in both packs, e.g. "a.jar -> b.jar, side: client -> both"
+func diffModNote(c core.ModChange) string {
+	var notes []string
+	if c.Kind != core.ChangeUnchanged {
+		if c.Old.FileName != c.New.FileName {
+			notes = append(notes, c.Old.FileName+" -> "+c.New.FileName)
+		} else {
+			notes = append(notes, c.New.FileName+" (file changed)")
+		}
+	}
+	if c.SideChanged {
+		notes = append(notes, "side: "+diffSide(c.Old.Side)+" -> "+diffSide(c.New.Side))
+	}
+	return strings.Join(notes, ", ")
+}
+
+func diffSide(side string) string {
+	if side == core.EmptySide {
+		return core.UniversalSide
+	}
+	return side
+}
+
+// diffModKinds is the order that mod changes are shown in, with their headings
+var diffModKinds = []struct {
+	kind    string
+	heading string
+}{
+	{core.ChangeAdded, "Added"},
+	{core.ChangeRemoved, "Removed"},
+	{core.ChangeUpgraded, "Upgraded"},
+	{core.ChangeDowngraded, "Downgraded"},
+	{core.ChangeUpdated, "Updated"},
+	{core.ChangeUnchanged, "Side changed"},
+}
+
+func diffVersionString(v core.VersionChange) string {
+	name := core.ComponentToFriendlyName(v.Component)
+	switch {
+	case v.Old == "":
+		return name + " " + v.New + " added"
+	case v.New == "":
+		return name + " " + v.Old + " removed"
+	default:
+		return name + ": " + v.Old + " -> " + v.New
+	}
+}
+
+func writeDiffText(w io.Writer, diff core.PackDiff) error {
+	if diff.Empty() {
+		_, err := fmt.Fprintln(w, "No differences found")
+		return err
+	}
+	var sb strings.Builder
+	for _, v := range diff.Versions {
+		sb.WriteString(diffVersionString(v) + "\n")
+	}
+	for _, k := range diffModKinds {
+		first := true
+		for _, c := range diff.Mods {
+			if c.Kind != k.kind {
+				continue
+			}
+			if first {
+				sb.WriteString(k.heading + ":\n")
+				first = false
+			}
+			switch c.Kind {
+			case core.ChangeAdded:
+				sb.WriteString("  + " + c.New.Name + " (" + c.New.FileName + ")\n")
+			case core.ChangeRemoved:
+				sb.WriteString("  - " + c.Old.Name + " (" + c.Old.FileName + ")\n")
+			default:
+				sb.WriteString("  " + c.Name() + ": " + diffModNote(c) + "\n")
+			}
+		}
+	}
+	if len(diff.Files) > 0 {
+		sb.WriteString("Files:\n")
+		for _, f := range diff.Files {
+			prefix := "~"
+			if f.Kind == core.ChangeAdded {
+				prefix = "+"
+			} else if f.Kind == core.ChangeRemoved {
+				prefix = "-"
+			}
+			sb.WriteString("  " + prefix + " " + f.Path + "\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeDiffMarkdown(w io.Writer, diff core.PackDiff, fromSpec string, toSpec string) error {
+	var sb strings.Builder
+	sb.WriteString("## Changes from " + fromSpec + " to " + toSpec + "\n\n")
+	if diff.Empty() {
+		sb.WriteString("No differences found.\n")
+	}
+	if len(diff.Versions) > 0 {
+		for _, v := range diff.Versions {
+			sb.WriteString("- " + diffVersionString(v) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	for _, k := range diffModKinds {
+		first := true
+		for _, c := range diff.Mods {
+			if c.Kind != k.kind {
+				continue
+			}
+			if first {
+				sb.WriteString("### " + k.heading + "\n\n")
+				first = false
+			}
+			switch c.Kind {
+			case core.ChangeAdded:
+				sb.WriteString("- " + c.New.Name + " (`" + c.New.FileName + "`)\n")
+			case core.ChangeRemoved:
+				sb.WriteString("- " + c.Old.Name + " (`" + c.Old.FileName + "`)\n")
+			default:
+				sb.WriteString("- " + c.Name() + ": " + diffModNote(c) + "\n")
+			}
+		}
+		if !first {
+			sb.WriteString("\n")
+		}
+	}
+	if len(diff.Files) > 0 {
+		sb.WriteString("### Files\n\n")
+		for _, f := range diff.Files {
+			sb.WriteString("- `" + f.Path + "` " + f.Kind + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(sb.String(), "\n"))
+	return err
+}
+
+type diffJSONMod struct {
+	File     string `json:"file"`
+	Side     string `json:"side"`
+	MetaFile string `json:"metafile"`
+}
+
+type diffJSONModChange struct {
+	Change      string       `json:"change"`
+	Name        string       `json:"name"`
+	Identity    string       `json:"identity"`
+	SideChanged bool         `json:"side-changed,omitempty"`
+	Old         *diffJSONMod `json:"old,omitempty"`
+	New         *diffJSONMod `json:"new,omitempty"`
+}
+
+type diffJSONFileChange struct {
+	Change string `json:"change"`
+	Path   string `json:"path"`
+}
+
+type diffJSONVersionChange struct {
+	Component string `json:"component"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+func newDiffJSONMod(m *core.Mod, index core.Index) *diffJSONMod {
+	if m == nil {
+		return nil
+	}
+	metaFile, err := index.RelIndexPath(m.GetFilePath())
+	if err != nil {
+		metaFile = filepath.ToSlash(m.GetFilePath())
+	}
+	return &diffJSONMod{File: m.FileName, Side: diffSide(m.Side), MetaFile: metaFile}
+}
+
+func writeDiffJSON(w io.Writer, diff core.PackDiff, fromSpec string, toSpec string, fromIndex core.Index, toIndex core.Index) error {
+	out := struct {
+		From     string                  `json:"from"`
+		To       string                  `json:"to"`
+		Versions []diffJSONVersionChange `json:"versions"`
+		Mods     []diffJSONModChange     `json:"mods"`
+		Files    []diffJSONFileChange    `json:"files"`
+	}{
+		From:     fromSpec,
+		To:       toSpec,
+		Versions: make([]diffJSONVersionChange, 0, len(diff.Versions)),
+		Mods:     make([]diffJSONModChange, 0, len(diff.Mods)),
+		Files:    make([]diffJSONFileChange, 0, len(diff.Files)),
+	}
+	for _, v := range diff.Versions {
+		out.Versions = append(out.Versions, diffJSONVersionChange{Component: v.Component, Old: v.Old, New: v.New})
+	}
+	for _, c := range diff.Mods {
+		identity := ""
+		if c.New != nil {
+			identity = core.ModIdentity(c.New)
+		} else {
+			identity = core.ModIdentity(c.Old)
+		}
+		out.Mods = append(out.Mods, diffJSONModChange{
+			Change:      c.Kind,
+			Name:        c.Name(),
+			Identity:    identity,
+			SideChanged: c.SideChanged,
+			Old:         newDiffJSONMod(c.Old, fromIndex),
+			New:         newDiffJSONMod(c.New, toIndex),
+		})
+	}
+	for _, f := range diff.Files {
+		out.Files = append(out.Files, diffJSONFileChange{Change: f.Kind, Path: f.Path})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringP("format", "f", "text", "The output format (text, json or markdown)")
+	_ = viper.BindPFlag("diff.format", diffCmd.Flags().Lookup("format"))
+}
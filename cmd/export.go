@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/codecraft3r/packwiz/cmdshared"
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the modpack in a format that doesn't depend on a specific platform",
+	Long: `Export the modpack in a format that doesn't depend on a specific platform.
+
+Use "packwiz modrinth export" or "packwiz curseforge export" to export the modpack for Modrinth or CurseForge.`,
+}
+
+// serverLaunchManifestFile is the name of the file describing how to launch an exported server
+const serverLaunchManifestFile = "server-launch.json"
+
+// serverLaunchManifest describes the Minecraft and mod loader versions needed to run an exported server
+type serverLaunchManifest struct {
+	Name      string              `json:"name"`
+	Version   string              `json:"version,omitempty"`
+	Minecraft string              `json:"minecraft"`
+	Loader    *serverLaunchLoader `json:"loader,omitempty"`
+	// Versions contains every component version in pack.toml, including the ones above
+	Versions map[string]string `json:"versions"`
+}
+
+type serverLaunchLoader struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+// serverExportWriter writes files to either a zip file or a folder
+type serverExportWriter interface {
+	cmdshared.ExportWriter
+	Close() error
+	// Abort removes the partially written export
+	Abort()
+}
+
+type zipExportWriter struct {
+	path string
+	file *os.File
+	zip  *zip.Writer
+}
+
+func newZipExportWriter(path string) (*zipExportWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip: %w", err)
+	}
+	return &zipExportWriter{path: path, file: file, zip: zip.NewWriter(file)}, nil
+}
+
+func (w *zipExportWriter) Create(name string) (io.Writer, error) {
+	return w.zip.Create(name)
+}
+
+func (w *zipExportWriter) Close() error {
+	err := w.zip.Close()
+	if err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func (w *zipExportWriter) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.path)
+}
+
+type folderExportWriter struct {
+	path string
+}
+
+func newFolderExportWriter(path string) (*folderExportWriter, error) {
+	entries, err := os.ReadDir(path)
+	if err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("output folder %s already exists and is not empty", path)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read output folder: %w", err)
+	}
+	err = os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output folder: %w", err)
+	}
+	return &folderExportWriter{path: path}, nil
+}
+
+// Create returns an *os.File, which is closed by cmdshared.WriteToExport
+func (w *folderExportWriter) Create(name string) (io.Writer, error) {
+	dest := filepath.Join(w.path, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(dest)
+}
+
+func (w *folderExportWriter) Close() error {
+	return nil
+}
+
+func (w *folderExportWriter) Abort() {
+	_ = os.RemoveAll(w.path)
+}
+
+// isServerMod checks if a mod should be installed on a server; optional mods are only included if they are enabled
+// by default
+func isServerMod(mod *core.Mod) bool {
+	if mod.Side != core.ServerSide && mod.Side != core.UniversalSide && mod.Side != core.EmptySide {
+		return false
+	}
+	return mod.Option == nil || !mod.Option.Optional || mod.Option.Default
+}
+
+func newServerLaunchManifest(pack core.Pack) (serverLaunchManifest, error) {
+	mcVersion, err := pack.GetMCVersion()
+	if err != nil {
+		return serverLaunchManifest{}, err
+	}
+	manifest := serverLaunchManifest{
+		Name:      pack.Name,
+		Version:   pack.Version,
+		Minecraft: mcVersion,
+		Versions:  pack.Versions,
+	}
+	if loaders := pack.GetLoaders(); len(loaders) > 0 {
+		manifest.Loader = &serverLaunchLoader{
+			Type:    loaders[0],
+			Version: pack.Versions[loaders[0]],
+		}
+	}
+	return manifest, nil
+}
+
+var exportServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Export a ready-to-run server, with every server-side file downloaded",
+	Long: `Export a ready-to-run server, with every server-side file downloaded.
+
+Files for the client side only (and optional files that are disabled by default) are left out. The export contains
+the files of the modpack in the same layout as the pack (e.g. mods/ and config/), and a ` + serverLaunchManifestFile + ` file
+describing the Minecraft and mod loader versions the server should be launched with.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Loading modpack...")
+		pack, err := core.LoadPack()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		index, err := pack.LoadIndex()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// Do a refresh to ensure files are up to date
+		err = index.Refresh()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = index.Write()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = pack.UpdateIndexHash()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = pack.Write()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		manifest, err := newServerLaunchManifest(pack)
+		if err != nil {
+			fmt.Println("Error creating launch manifest: " + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println("Reading external files...")
+		allMods, err := index.LoadAllMods()
+		if err != nil {
+			fmt.Printf("Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+		var mods []*core.Mod
+		for _, mod := range allMods {
+			if isServerMod(mod) {
+				mods = append(mods, mod)
+			}
+		}
+		sort.Slice(mods, func(i, j int) bool {
+			return mods[i].GetDestFilePath() < mods[j].GetDestFilePath()
+		})
+
+		session, err := core.CreateDownloadSession(mods, []string{})
+		if err != nil {
+			fmt.Printf("Error retrieving external files: %v\n", err)
+			os.Exit(1)
+		}
+		// Manual downloads must be in the cache before anything is written
+		cmdshared.ListManualDownloads(session)
+
+		folder := viper.GetBool("export.server.folder")
+		output := viper.GetString("export.server.output")
+		if output == "" {
+			output = pack.GetPackName() + "-server"
+			if !folder {
+				output += ".zip"
+			}
+		}
+		var exp serverExportWriter
+		if folder {
+			exp, err = newFolderExportWriter(output)
+		} else {
+			exp, err = newZipExportWriter(output)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Retrieving %v external files (%v client-only or disabled files skipped)...\n", len(mods), len(allMods)-len(mods))
+		failed := 0
+		for dl := range session.StartDownloads() {
+			if !cmdshared.AddToExport(dl, exp, "", &index) {
+				failed++
+				continue
+			}
+			fmt.Printf("%s (%s) exported\n", dl.Mod.Name, dl.Mod.FileName)
+		}
+
+		err = session.SaveIndex()
+		if err != nil {
+			fmt.Printf("Error saving cache index: %v\n", err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			exp.Abort()
+			fmt.Printf("Failed to export %v files; the server would not work without them\n", failed)
+			os.Exit(1)
+		}
+
+		if !cmdshared.AddNonMetafileFiles(&index, exp, "") {
+			exp.Abort()
+			fmt.Println("Failed to export files in the pack folder")
+			os.Exit(1)
+		}
+
+		manifestData, err := json.MarshalIndent(manifest, "", "  ")
+		if err == nil {
+			err = cmdshared.WriteToExport(exp, serverLaunchManifestFile, bytes.NewReader(append(manifestData, '\n')))
+		}
+		if err != nil {
+			exp.Abort()
+			fmt.Println("Error writing launch manifest: " + err.Error())
+			os.Exit(1)
+		}
+
+		err = exp.Close()
+		if err != nil {
+			exp.Abort()
+			fmt.Println("Error writing export: " + err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("Server exported to " + output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportServerCmd)
+
+	exportServerCmd.Flags().StringP("output", "o", "", "The file (or folder) to export the server to")
+	_ = viper.BindPFlag("export.server.output", exportServerCmd.Flags().Lookup("output"))
+	exportServerCmd.Flags().Bool("folder", false, "Export to a folder instead of a zip file")
+	_ = viper.BindPFlag("export.server.folder", exportServerCmd.Flags().Lookup("folder"))
+}
@@ -64,7 +64,18 @@ var listCmd = &cobra.Command{
 		// Print mods
 		if viper.GetBool("list.version") {
 			for _, mod := range mods {
-				fmt.Printf("%s (%s)\n", mod.Name, mod.FileName)
+				var notes []string
+				if mod.Pin {
+					notes = append(notes, "pinned")
+				}
+				if !mod.Constraint.IsEmpty() {
+					notes = append(notes, "constraint: "+mod.Constraint.String())
+				}
+				if len(notes) > 0 {
+					fmt.Printf("%s (%s) [%s]\n", mod.Name, mod.FileName, strings.Join(notes, ", "))
+				} else {
+					fmt.Printf("%s (%s)\n", mod.Name, mod.FileName)
+				}
 			}
 		} else {
 			for _, mod := range mods {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/codecraft3r/packwiz/core"
@@ -14,12 +15,15 @@ var modifyCmd = &cobra.Command{
 	Use:   "modify [mod name/path]",
 	Short: "Modify properties of an existing mod",
 	Long: `Modify properties of an existing mod such as side compatibility, 
-disabled client platforms, pin status, and optional settings.
+disabled client platforms, pin status, release channel, version constraints,
+and optional settings.
 
 Examples:
   packwiz modify jei --side client
   packwiz modify optifine --disabled-client-platforms macos,linux
   packwiz modify sodium --pin
+  packwiz modify iris --channel beta
+  packwiz modify sodium --constraint "4.x" --deny 4.2.1
   packwiz modify rei --optional --optional-description "Enhanced recipe viewing"`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -127,6 +131,70 @@ func modifyModProperties(cmd *cobra.Command, args []string) {
 		changed = true
 	}
 
+	// Handle release channel
+	if cmd.Flags().Changed("channel") {
+		channel, _ := cmd.Flags().GetString("channel")
+		channel = strings.ToLower(strings.TrimSpace(channel))
+
+		if err := core.ValidateReleaseChannel(channel); err != nil {
+			fmt.Printf("Release channel validation error: %v\n", err)
+			os.Exit(1)
+		}
+
+		oldChannel := modData.ReleaseChannel
+		if oldChannel == "" {
+			oldChannel = "pack default"
+		}
+		modData.ReleaseChannel = channel
+		if channel == "" {
+			fmt.Printf("Cleared release channel, using the pack default (was '%s')\n", oldChannel)
+		} else {
+			fmt.Printf("Changed release channel from '%s' to '%s'\n", oldChannel, channel)
+		}
+		changed = true
+	}
+
+	// Handle version constraints
+	if cmd.Flags().Changed("constraint") || cmd.Flags().Changed("deny") {
+		oldConstraint := modData.Constraint.String()
+		if oldConstraint == "" {
+			oldConstraint = "none"
+		}
+		constraint := core.ModConstraint{}
+		if modData.Constraint != nil {
+			constraint = *modData.Constraint
+		}
+
+		if cmd.Flags().Changed("constraint") {
+			versionRange, _ := cmd.Flags().GetString("constraint")
+			versionRange = strings.TrimSpace(versionRange)
+			if err := core.ValidateVersionConstraint(versionRange); err != nil {
+				fmt.Printf("Version constraint validation error: %v\n", err)
+				os.Exit(1)
+			}
+			constraint.Version = versionRange
+		}
+		if cmd.Flags().Changed("deny") {
+			deny, _ := cmd.Flags().GetStringSlice("deny")
+			constraint.Deny = nil
+			for _, v := range deny {
+				v = strings.TrimSpace(v)
+				if v != "" && !slices.Contains(constraint.Deny, v) {
+					constraint.Deny = append(constraint.Deny, v)
+				}
+			}
+		}
+
+		if constraint.IsEmpty() {
+			modData.Constraint = nil
+			fmt.Printf("Cleared version constraint (was %s)\n", oldConstraint)
+		} else {
+			modData.Constraint = &constraint
+			fmt.Printf("Changed version constraint from %s to %s\n", oldConstraint, constraint.String())
+		}
+		changed = true
+	}
+
 	// Handle optional settings
 	optionalChanged := false
 	if cmd.Flags().Changed("optional") || cmd.Flags().Changed("optional-description") || cmd.Flags().Changed("optional-default") {
@@ -177,8 +245,11 @@ func modifyModProperties(cmd *cobra.Command, args []string) {
 	}
 
 	// Save the modified mod
-	format, hash, err := modData.Write()
+	tx := core.NewTransaction()
+	defer tx.Rollback()
+	format, hash, err := tx.WriteMod(&modData)
 	if err != nil {
+		tx.Rollback()
 		fmt.Printf("Failed to write mod file: %v\n", err)
 		os.Exit(1)
 	}
@@ -186,27 +257,22 @@ func modifyModProperties(cmd *cobra.Command, args []string) {
 	// Update the index
 	err = index.RefreshFileWithHash(modPath, format, hash, true)
 	if err != nil {
+		tx.Rollback()
 		fmt.Printf("Failed to refresh index: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Write the updated index
-	err = index.Write()
+	// Write the updated index and pack
+	err = tx.WriteIndexAndPack(index, &pack)
 	if err != nil {
+		tx.Rollback()
 		fmt.Printf("Failed to write index: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Update pack hash
-	err = pack.UpdateIndexHash()
+	err = tx.Commit()
 	if err != nil {
-		fmt.Printf("Failed to update pack hash: %v\n", err)
-		os.Exit(1)
-	}
-
-	err = pack.Write()
-	if err != nil {
-		fmt.Printf("Failed to write pack: %v\n", err)
+		fmt.Printf("Failed to save changes: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -222,6 +288,9 @@ func init() {
 	modifyCmd.Flags().String("side", "", "Set the mod side (client, server, both)")
 	modifyCmd.Flags().StringSlice("disabled-client-platforms", []string{}, "Set disabled client platforms (macos, linux, windows)")
 	modifyCmd.Flags().Bool("pin", false, "Pin or unpin the mod (use --pin=true to pin, --pin=false to unpin)")
+	modifyCmd.Flags().String("constraint", "", "Set the range of versions the mod can be updated to, e.g. \">=4.0, <5.0\" or \"4.x\" (empty to remove)")
+	modifyCmd.Flags().StringSlice("deny", []string{}, "Set the version numbers, version/file IDs or file names the mod must never be updated to (empty to clear)")
+	modifyCmd.Flags().String("channel", "", "Set the release channel to update the mod from (release, beta, alpha; empty to use the pack's release-channel option)")
 	modifyCmd.Flags().Bool("optional", false, "Mark the mod as optional (use --optional=true for optional, --optional=false for required)")
 	modifyCmd.Flags().String("optional-description", "", "Set the description for the optional mod")
 	modifyCmd.Flags().Bool("optional-default", false, "Set whether the optional mod is enabled by default (use --optional-default=true or --optional-default=false)")
@@ -236,6 +305,12 @@ func init() {
   # Pin a mod to prevent updates
   packwiz modify sodium --pin=true
 
+  # Allow beta versions of a mod when updating
+  packwiz modify iris --channel beta
+
+  # Stay on 4.x of a mod, but never update to a broken version
+  packwiz modify sodium --constraint "4.x" --deny 4.2.1
+
   # Make a mod optional with a description
   packwiz modify rei --optional=true --optional-description "Enhanced recipe viewing"
 
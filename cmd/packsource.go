@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/viper"
+)
+
+// loadPackSpec loads a pack from a pack file, a folder containing pack.toml, or a git revision (e.g. a tag, branch or
+// commit) of the current pack; an empty spec loads the current pack. The returned function removes any temporary
+// files, and must be called once the pack is no longer needed.
+func loadPackSpec(spec string) (core.PackContents, func(), error) {
+	noCleanup := func() {}
+	packFile := viper.GetString("pack-file")
+	if spec != "" {
+		if info, err := os.Stat(spec); err == nil {
+			packFile = spec
+			if info.IsDir() {
+				packFile = filepath.Join(spec, "pack.toml")
+			}
+		} else {
+			contents, cleanup, gitErr := loadGitPack(packFile, spec)
+			if gitErr != nil {
+				return core.PackContents{}, nil, fmt.Errorf("%s is not a pack file or a git revision: %w", spec, gitErr)
+			}
+			return contents, cleanup, nil
+		}
+	}
+	contents, err := core.LoadPackContents(packFile)
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	return contents, noCleanup, nil
+}
+
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// loadGitPack loads the pack at the given pack file path, as it was in a git revision, by extracting the revision to a
+// temporary folder
+func loadGitPack(packFile string, rev string) (core.PackContents, func(), error) {
+	absPackFile, err := filepath.Abs(packFile)
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(filepath.Dir(absPackFile)); err == nil {
+		absPackFile = filepath.Join(resolved, filepath.Base(absPackFile))
+	}
+	packDir := filepath.Dir(absPackFile)
+	out, err := runGit(packDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	top := strings.TrimSpace(string(out))
+	if resolved, err := filepath.EvalSymlinks(top); err == nil {
+		top = resolved
+	}
+	out, err = runGit(packDir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return core.PackContents{}, nil, fmt.Errorf("unknown revision %s", rev)
+	}
+	commit := strings.TrimSpace(string(out))
+	relPackFile, err := filepath.Rel(top, absPackFile)
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	relPackFile = filepath.ToSlash(relPackFile)
+
+	archive, err := runGit(top, "archive", "--format=zip", commit)
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return core.PackContents{}, nil, fmt.Errorf("failed to read archive of %s: %w", rev, err)
+	}
+	tempDir, err := os.MkdirTemp("", "packwiz-rev-")
+	if err != nil {
+		return core.PackContents{}, nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tempDir)
+	}
+
+	// Only the folder containing the pack file is needed
+	prefix := path.Dir(relPackFile) + "/"
+	if prefix == "./" {
+		prefix = ""
+	}
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) || f.FileInfo().IsDir() {
+			continue
+		}
+		err = extractZipFile(f, filepath.Join(tempDir, filepath.FromSlash(f.Name)))
+		if err != nil {
+			cleanup()
+			return core.PackContents{}, nil, err
+		}
+	}
+
+	contents, err := core.LoadPackContents(filepath.Join(tempDir, filepath.FromSlash(relPackFile)))
+	if err != nil {
+		cleanup()
+		return core.PackContents{}, nil, fmt.Errorf("failed to load pack at %s: %w", rev, err)
+	}
+	return contents, cleanup, nil
+}
+
+func extractZipFile(f *zip.File, dest string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, src)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
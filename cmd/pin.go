@@ -31,27 +31,27 @@ func pinMod(args []string, pinned bool) {
 		os.Exit(1)
 	}
 	modData.Pin = pinned
-	format, hash, err := modData.Write()
+	tx := core.NewTransaction()
+	defer tx.Rollback()
+	format, hash, err := tx.WriteMod(&modData)
 	if err != nil {
+		tx.Rollback()
 		fmt.Println(err)
 		os.Exit(1)
 	}
 	err = index.RefreshFileWithHash(modPath, format, hash, true)
 	if err != nil {
+		tx.Rollback()
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	err = index.Write()
+	err = tx.WriteIndexAndPack(index, &pack)
 	if err != nil {
+		tx.Rollback()
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	err = pack.UpdateIndexHash()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-	err = pack.Write()
+	err = tx.Commit()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -37,17 +37,14 @@ var refreshCmd = &cobra.Command{
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = index.Write()
+		tx := core.NewTransaction()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.UpdateIndexHash()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
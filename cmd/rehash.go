@@ -53,46 +53,41 @@ var rehashCmd = &cobra.Command{
 
 		cmdshared.ListManualDownloads(session)
 
+		tx := core.NewTransaction()
+		defer tx.Rollback()
 		for dl := range session.StartDownloads() {
 			if dl.Error != nil {
 				fmt.Printf("Error retrieving %s: %v\n", dl.Mod.Name, dl.Error)
 			} else {
 				dl.Mod.Download.HashFormat = args[0]
 				dl.Mod.Download.Hash = dl.Hashes[args[0]]
-				_, _, err := dl.Mod.Write()
+				format, hash, err := tx.WriteMod(dl.Mod)
+				if err == nil {
+					err = index.RefreshFileWithHash(dl.Mod.GetFilePath(), format, hash, true)
+				}
 				if err != nil {
+					tx.Rollback()
 					fmt.Printf("Error saving mod %s: %v\n", dl.Mod.Name, err)
 					os.Exit(1)
 				}
 			}
-			// TODO pass the hash to index instead of recomputing from scratch
 		}
 
 		err = session.SaveIndex()
 		if err != nil {
+			tx.Rollback()
 			fmt.Printf("Error saving cache index: %v\n", err)
 			os.Exit(1)
 		}
 
-		err = index.Refresh()
-		if err != nil {
-			fmt.Printf("Error refreshing index: %v\n", err)
-			os.Exit(1)
-		}
-
-		err = index.Write()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Printf("Error writing index: %v\n", err)
 			os.Exit(1)
 		}
 
-		err = pack.UpdateIndexHash()
-		if err != nil {
-			fmt.Printf("Error updating index hash: %v\n", err)
-			os.Exit(1)
-		}
-
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Printf("Error writing pack: %v\n", err)
 			os.Exit(1)
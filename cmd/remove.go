@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/codecraft3r/packwiz/cmdshared"
 	"github.com/codecraft3r/packwiz/core"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // removeCmd represents the remove command
@@ -31,28 +33,53 @@ var removeCmd = &cobra.Command{
 			fmt.Println("Can't find this file; please ensure you have run packwiz refresh and use the name of the .pw.toml file (defaults to the project slug)")
 			os.Exit(1)
 		}
-		err = os.Remove(resolvedMod)
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		err = tx.RemoveFile(resolvedMod)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
 		fmt.Println("Removing file from index...")
 		err = index.RemoveFile(resolvedMod)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = index.Write()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+
+		unneeded := findUnneededDependencies(index, resolvedMod)
+		if len(unneeded) > 0 {
+			fmt.Println("The following dependencies are no longer needed by any other file:")
+			for _, v := range unneeded {
+				fmt.Println(v.Name)
+			}
+			cascade := viper.GetBool("remove.cascade")
+			if !cascade {
+				if viper.GetBool("non-interactive") {
+					fmt.Println("Keeping them; use --cascade to remove them automatically")
+				} else {
+					cascade = cmdshared.PromptYesNo("Would you like to remove them? [Y/n]: ")
+				}
+			}
+			if cascade {
+				err = removeMods(unneeded, &index, tx)
+				if err != nil {
+					tx.Rollback()
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
 		}
-		err = pack.UpdateIndexHash()
+
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -62,6 +89,55 @@ var removeCmd = &cobra.Command{
 	},
 }
 
+// findUnneededDependencies returns the mods that were added as dependencies and are only needed by the removed mod
+func findUnneededDependencies(index core.Index, removedPath string) []*core.Mod {
+	mods, err := index.LoadAllMods()
+	if err != nil {
+		fmt.Printf("Failed to check for unneeded dependencies: %v\n", err)
+		return nil
+	}
+	// The removed file has already been removed from the index, so it isn't in mods and is loaded separately
+	removed, err := core.LoadMod(removedPath)
+	if err != nil {
+		// Not a metadata file
+		return nil
+	}
+	all := append(mods, &removed)
+	graph := core.NewDependencyGraph(all)
+
+	// Only suggest dependencies that were needed before removing this mod
+	alreadyUnneeded := make(map[*core.Mod]bool)
+	for _, v := range graph.Unneeded(nil) {
+		alreadyUnneeded[v] = true
+	}
+	var unneeded []*core.Mod
+	for _, v := range graph.Unneeded([]*core.Mod{&removed}) {
+		if !alreadyUnneeded[v] {
+			unneeded = append(unneeded, v)
+		}
+	}
+	return unneeded
+}
+
+// removeMods stages the removal of metadata files, and removes them from the index
+func removeMods(mods []*core.Mod, index *core.Index, tx *core.Transaction) error {
+	for _, v := range mods {
+		err := tx.RemoveFile(v.GetFilePath())
+		if err != nil {
+			return err
+		}
+		err = index.RemoveFile(v.GetFilePath())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s removed successfully!\n", v.Name)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().Bool("cascade", false, "Also remove dependencies that are no longer needed by any other file, without asking")
+	_ = viper.BindPFlag("remove.cascade", removeCmd.Flags().Lookup("cascade"))
 }
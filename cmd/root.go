@@ -64,6 +64,13 @@ func init() {
 	rootCmd.PersistentFlags().String("cache", defaultCacheDir, "The directory where packwiz will cache downloaded mods")
 	_ = viper.BindPFlag("cache.directory", rootCmd.PersistentFlags().Lookup("cache"))
 
+	rootCmd.PersistentFlags().Int("download-concurrency", core.DefaultDownloadConcurrency, "The maximum number of files to download at once")
+	_ = viper.BindPFlag("download.concurrency", rootCmd.PersistentFlags().Lookup("download-concurrency"))
+	rootCmd.PersistentFlags().Int("download-host-concurrency", core.DefaultDownloadHostConcurrency, "The maximum number of files to download at once from a single host")
+	_ = viper.BindPFlag("download.host-concurrency", rootCmd.PersistentFlags().Lookup("download-host-concurrency"))
+	rootCmd.PersistentFlags().Int("download-retries", core.DefaultDownloadRetries, "The number of times to retry a download that fails due to a network error")
+	_ = viper.BindPFlag("download.retries", rootCmd.PersistentFlags().Lookup("download-retries"))
+
 	file, err := core.GetPackwizLocalStore()
 	if err != nil {
 		fmt.Println(err)
@@ -5,143 +5,149 @@ import (
 	_ "embed"
 	"fmt"
 	"html/template"
-	"io"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/codecraft3r/packwiz/core"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var refreshMutex sync.RWMutex
-
 //go:embed serve-templates/index.html
 var indexPage string
 
 // serveCmd represents the serve command
 var serveCmd = &cobra.Command{
-	Use:     "serve",
-	Short:   "Run a local development server",
-	Long:    `Run a local HTTP server for development, automatically refreshing the index when it is queried`,
+	Use:   "serve",
+	Short: "Run a local development server",
+	Long: `Run a local HTTP server for development, automatically refreshing the index when files in the modpack change.
+
+Files are watched for changes (respecting .packwizignore), and only changed files are rehashed; clients are always
+served the last fully refreshed version of the index.
+
+The page at the root of the server shows the contents and status of the pack, using a read-only JSON API served under
+/api/ (api/pack, api/mods and api/status).
+
+Access can be restricted with tokens (--token) or basic authentication users (--user), which can also be given in the
+PACKWIZ_SERVE_TOKENS and PACKWIZ_SERVE_USERS environment variables (separated by spaces), or listed in a file given
+with --auth-file (which is never served), e.g.:
+tokens = ["alice:secret-token"]
+users = ["bob:password"]
+Tokens and users can't be set in the pack options, as pack.toml is served to every client.
+
+HTTPS can be enabled with a certificate (--tls-cert/--tls-key) or a generated self-signed certificate
+(--tls-self-signed); these can also be set in the pack options, e.g.:
+[options.serve]
+tls-self-signed = true
+
+With --proxy, a copy of the pack is also served under /proxy/ (e.g. /proxy/pack.toml), where the download URLs of
+files from Modrinth, CurseForge and other sites point to this server. These files are downloaded into the packwiz
+cache when first requested, so each file is only downloaded once for every client on the network.
+
+Multiple modpacks can be served at once with --packs, given either a folder where each subfolder contains a pack.toml
+(served under the name of the subfolder) or a configuration file listing the path to serve each pack under, e.g.:
+[packs]
+survival = "survival/pack.toml"
+creative = "/srv/packs/creative/pack.toml"`,
 	Aliases: []string{"server"},
 	Args:    cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		port := strconv.Itoa(viper.GetInt("serve.port"))
+		scheme := "http"
+		if viper.GetString("serve.tls-cert") != "" || viper.GetBool("serve.tls-self-signed") {
+			scheme = "https"
+		}
 
+		var handler http.Handler
 		if viper.GetBool("serve.basic") {
-			http.Handle("/", http.FileServer(http.Dir(".")))
+			fileServer := http.FileServer(http.Dir("."))
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				// Never serve the file listing credentials
+				filePath, err := filepath.Abs(filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")))
+				if err == nil && serveAuthFilePath != "" && filePath == serveAuthFilePath {
+					http.NotFound(w, req)
+					return
+				}
+				fileServer.ServeHTTP(w, req)
+			})
 		} else {
-			fmt.Println("Loading modpack...")
-			pack, err := core.LoadPack()
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+			packsPath := viper.GetString("serve.packs")
+			if packsPath == "" {
+				fmt.Println("Loading modpack...")
+				// Load the pack first, to apply the pack options
+				if _, err := core.LoadPack(); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
 			}
-			index, err := pack.LoadIndex()
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+
+			// Force-disable no-internal-hashes mode (equiv to --build flag in refresh) for serving over HTTP
+			if viper.GetBool("no-internal-hashes") {
+				fmt.Println("Note: no-internal-hashes mode is set; still writing hashes for use with packwiz-installer - run packwiz refresh to remove them.")
+				viper.Set("no-internal-hashes", false)
 			}
-			packServeDir := filepath.Dir(viper.GetString("pack-file"))
-			packFileName := filepath.Base(viper.GetString("pack-file"))
 
-			t, err := template.New("index-page").Parse(indexPage)
+			var err error
+			if packsPath != "" {
+				var packs []servePack
+				packs, err = findServePacks(packsPath)
+				if err == nil {
+					handler, err = newMultiPackHandler(packs, port, scheme)
+				}
+			} else {
+				handler, err = newPackServer("", viper.GetString("pack-file"), "/", port, scheme)
+			}
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
 			}
+		}
 
-			indexPageBuf := new(bytes.Buffer)
-			err = t.Execute(indexPageBuf, struct{ Port string }{Port: port})
+		// Read after loading the pack, so the access log can be set in the pack options (unless serving multiple packs)
+		auth, closeLog, err := newServeAuth(handler, cmd.Flags())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer closeLog()
+		bind := viper.GetString("serve.bind")
+		certFile, keyFile := viper.GetString("serve.tls-cert"), viper.GetString("serve.tls-key")
+		if certFile != "" && keyFile == "" {
+			fmt.Println("A key file must be specified with --tls-key when using --tls-cert")
+			os.Exit(1)
+		}
+		if certFile == "" && viper.GetBool("serve.tls-self-signed") {
+			certFile, keyFile, err = selfSignedCert(bind)
 			if err != nil {
-				panic(fmt.Errorf("failed to compile index page template: %w", err))
+				fmt.Printf("Failed to create self-signed certificate: %v\n", err)
+				os.Exit(1)
 			}
-
-			// Force-disable no-internal-hashes mode (equiv to --build flag in refresh) for serving over HTTP
-			if viper.GetBool("no-internal-hashes") {
-				fmt.Println("Note: no-internal-hashes mode is set; still writing hashes for use with packwiz-installer - run packwiz refresh to remove them.")
-				viper.Set("no-internal-hashes", false)
+		}
+		if len(auth.users) > 0 && certFile == "" {
+			if ip := net.ParseIP(bind); bind != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				fmt.Println("Warning: credentials are sent unencrypted; use --tls-cert or --tls-self-signed to enable HTTPS")
 			}
-
-			http.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
-				if req.URL.Path == "/" {
-					_, _ = w.Write(indexPageBuf.Bytes())
-					return
-				}
-
-				// Relative to pack.toml
-				urlPath := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(req.URL.Path, "/")), "/")
-				// Convert to absolute
-				destPath := filepath.Join(packServeDir, filepath.FromSlash(urlPath))
-				// Relativisation needs to be done using filepath, as path doesn't have Rel!
-				// (now using index util function)
-				// Relative to index.toml ("pack root")
-				indexRelPath, err := index.RelIndexPath(destPath)
-				if err != nil {
-					fmt.Println("Failed to parse path", err)
-					return
-				}
-
-				if urlPath == path.Clean(pack.Index.File) {
-					// Must be done here, to ensure all paths gain the lock at some point
-					refreshMutex.RLock()
-				} else if urlPath == packFileName { // Only need to compare name - already relative to pack.toml
-					if viper.GetBool("serve.refresh") {
-						// Get write lock, to do a refresh
-						refreshMutex.Lock()
-						// Reload pack and index (might have changed on disk)
-						err = doServeRefresh(&pack, &index)
-						if err != nil {
-							fmt.Println("Failed to refresh pack", err)
-							return
-						}
-
-						// Downgrade to a read lock
-						refreshMutex.Unlock()
-					}
-					refreshMutex.RLock()
-				} else {
-					refreshMutex.RLock()
-					// Only allow indexed files
-					if _, found := index.Files[indexRelPath]; !found {
-						fmt.Printf("File not found: %s\n", destPath)
-						refreshMutex.RUnlock()
-						w.WriteHeader(404)
-						_, _ = w.Write([]byte("File not found"))
-						return
-					}
-				}
-				defer refreshMutex.RUnlock()
-
-				f, err := os.Open(destPath)
-				if err != nil {
-					fmt.Printf("Error reading file \"%s\": %s\n", destPath, err)
-					w.WriteHeader(404)
-					_, _ = w.Write([]byte("File not found"))
-					return
-				}
-				_, err = io.Copy(w, f)
-				err2 := f.Close()
-				if err == nil {
-					err = err2
-				}
-				if err != nil {
-					fmt.Printf("Error reading file \"%s\": %s\n", destPath, err)
-					w.WriteHeader(500)
-					_, _ = w.Write([]byte("Failed to read file"))
-					return
-				}
-			})
 		}
 
-		fmt.Println("Running on port " + port)
-		err := http.ListenAndServe(":"+port, nil)
+		srv := &http.Server{
+			Addr:    net.JoinHostPort(bind, port),
+			Handler: auth,
+		}
+		if bind == "" {
+			fmt.Printf("Running on port %s (%s)\n", port, scheme)
+		} else {
+			fmt.Printf("Running on %s://%s\n", scheme, srv.Addr)
+		}
+		if certFile != "" {
+			err = srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
 		if err != nil {
 			fmt.Printf("Error running server: %s\n", err)
 			os.Exit(1)
@@ -149,35 +155,63 @@ var serveCmd = &cobra.Command{
 	},
 }
 
-func doServeRefresh(pack *core.Pack, index *core.Index) error {
-	var err error
-	*pack, err = core.LoadPack()
+// newPackServer loads a modpack and starts refreshing it (if enabled); urlPath is the path it is served at
+func newPackServer(name string, packFile string, urlPath string, port string, scheme string) (*packServer, error) {
+	pack, err := core.LoadPackFile(packFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	*index, err = pack.LoadIndex()
+	index, err := pack.LoadIndex()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = index.Refresh()
+	warnServeOptionCredentials(pack)
+
+	t, err := template.New("index-page").Parse(indexPage)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = index.Write()
+	indexPageBuf := new(bytes.Buffer)
+	err = t.Execute(indexPageBuf, struct {
+		Port, Scheme, Path, ProxyPath string
+		Proxy                         bool
+	}{
+		Port:      port,
+		Scheme:    scheme,
+		Path:      path.Join(urlPath, filepath.Base(packFile)),
+		ProxyPath: path.Join(urlPath, proxyPackPath, filepath.Base(packFile)),
+		Proxy:     viper.GetBool("serve.proxy"),
+	})
 	if err != nil {
-		return err
+		panic(fmt.Errorf("failed to compile index page template: %w", err))
 	}
-	err = pack.UpdateIndexHash()
-	if err != nil {
-		return err
+
+	server := &packServer{
+		name:         name,
+		packFile:     packFile,
+		urlPath:      urlPath,
+		proxy:        viper.GetBool("serve.proxy"),
+		packServeDir: filepath.Dir(packFile),
+		packFileName: filepath.Base(packFile),
+		indexPage:    indexPageBuf.Bytes(),
+	}
+	if viper.GetBool("serve.refresh") {
+		err = server.refreshAll()
+	} else {
+		err = server.load(pack, index)
 	}
-	err = pack.Write()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	fmt.Println("Index refreshed!")
-
-	return nil
+	if viper.GetBool("serve.refresh") {
+		if !viper.GetBool("serve.watch") {
+			server.lazyRefresh = true
+		} else if err := server.watch(); err != nil {
+			server.printf("Failed to watch for file changes, refreshing when pack.toml is requested instead: %v\n", err)
+			server.lazyRefresh = true
+		}
+	}
+	return server, nil
 }
 
 func init() {
@@ -187,6 +221,29 @@ func init() {
 	_ = viper.BindPFlag("serve.port", serveCmd.Flags().Lookup("port"))
 	serveCmd.Flags().BoolP("refresh", "r", true, "Automatically refresh the index file")
 	_ = viper.BindPFlag("serve.refresh", serveCmd.Flags().Lookup("refresh"))
+	serveCmd.Flags().Bool("watch", true, "Watch files for changes and refresh the index in the background, rather than refreshing when pack.toml is requested")
+	_ = viper.BindPFlag("serve.watch", serveCmd.Flags().Lookup("watch"))
+	serveCmd.Flags().String("bind", "", "The address to listen on (defaults to all addresses)")
+	_ = viper.BindPFlag("serve.bind", serveCmd.Flags().Lookup("bind"))
+	serveCmd.Flags().String("tls-cert", "", "A certificate file to serve over HTTPS with")
+	_ = viper.BindPFlag("serve.tls-cert", serveCmd.Flags().Lookup("tls-cert"))
+	serveCmd.Flags().String("tls-key", "", "The private key file for the certificate given with --tls-cert")
+	_ = viper.BindPFlag("serve.tls-key", serveCmd.Flags().Lookup("tls-key"))
+	serveCmd.Flags().Bool("tls-self-signed", false, "Serve over HTTPS with a self-signed certificate, generated and stored in the packwiz data folder")
+	_ = viper.BindPFlag("serve.tls-self-signed", serveCmd.Flags().Lookup("tls-self-signed"))
+	serveCmd.Flags().StringArray("token", nil, "Require a token to access the server, given as a bearer token or basic authentication password; "+
+		"may be given as name:token to identify it in the access log (can be specified multiple times)")
+	serveCmd.Flags().StringArray("user", nil, "Require basic authentication with the given name:password to access the server (can be specified multiple times)")
+	serveCmd.Flags().String("auth-file", "", "A file listing tokens and users allowed to access the server (which is never served)")
+	_ = viper.BindPFlag("serve.auth-file", serveCmd.Flags().Lookup("auth-file"))
+	serveCmd.Flags().String("access-log", "", "Log requests to this file (requests are logged to the console when authentication is enabled)")
+	_ = viper.BindPFlag("serve.access-log", serveCmd.Flags().Lookup("access-log"))
+	serveCmd.Flags().String("packs", "", "Serve every modpack in the subfolders of this folder, or the modpacks listed in this configuration file, each under its own path")
+	_ = viper.BindPFlag("serve.packs", serveCmd.Flags().Lookup("packs"))
+	serveCmd.Flags().Bool("proxy", false, "Also serve a copy of the pack under /proxy/ that downloads external files through this server, caching them in the packwiz cache")
+	_ = viper.BindPFlag("serve.proxy", serveCmd.Flags().Lookup("proxy"))
+	serveCmd.Flags().String("proxy-url", "", "The URL clients use to reach this server, for download URLs in proxy mode (defaults to the address used in each request)")
+	_ = viper.BindPFlag("serve.proxy-url", serveCmd.Flags().Lookup("proxy-url"))
 	serveCmd.Flags().Bool("basic", false, "Disable refreshing and allow all files in the directory, rather than just files listed in the index")
 	_ = viper.BindPFlag("serve.basic", serveCmd.Flags().Lookup("basic"))
 }
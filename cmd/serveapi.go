@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+// apiPath is the path the JSON API of each pack is served under
+const apiPath = "api"
+
+type apiPack struct {
+	// ID is the path the pack is served under, when serving multiple packs
+	ID          string            `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Author      string            `json:"author,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Description string            `json:"description,omitempty"`
+	PackFormat  string            `json:"pack-format"`
+	Versions    map[string]string `json:"versions"`
+	// URL is the path to pack.toml, relative to the server root
+	URL string `json:"url"`
+	// ProxyURL is the path to the proxied pack.toml, if proxy mode is enabled
+	ProxyURL string `json:"proxy-url,omitempty"`
+}
+
+type apiMod struct {
+	Name        string `json:"name"`
+	FileName    string `json:"filename"`
+	MetaFile    string `json:"metafile"`
+	Side        string `json:"side"`
+	Optional    bool   `json:"optional"`
+	Default     bool   `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	Pin         bool   `json:"pin"`
+	// Source is the site the file was added from (e.g. modrinth or curseforge), or url for other files
+	Source            string `json:"source,omitempty"`
+	ProjectID         string `json:"project-id,omitempty"`
+	AddedAsDependency bool   `json:"added-as-dependency"`
+}
+
+type apiStatus struct {
+	LastRefresh time.Time `json:"last-refresh"`
+	IndexHash   string    `json:"index-hash"`
+	Files       int       `json:"files"`
+	MetaFiles   int       `json:"metafiles"`
+	Watching    bool      `json:"watching"`
+	Proxy       bool      `json:"proxy"`
+	// LastError is set if the last refresh failed; the files served are from the last successful refresh
+	LastError     string     `json:"last-error,omitempty"`
+	LastErrorTime *time.Time `json:"last-error-time,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	_ = enc.Encode(v)
+}
+
+// serveAPI serves the read-only JSON API used by the dashboard page: pack, mods and status
+func (s *packServer) serveAPI(w http.ResponseWriter, req *http.Request, endpoint string) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	snapshot := s.snapshot.Load()
+	switch endpoint {
+	case "pack":
+		writeJSON(w, s.apiPack(snapshot))
+	case "mods":
+		mods, err := snapshot.getMods()
+		if err != nil {
+			s.printf("Failed to load metadata files: %v\n", err)
+			http.Error(w, "Failed to load metadata files", http.StatusInternalServerError)
+			return
+		}
+		list := make([]apiMod, 0, len(mods))
+		for _, m := range mods {
+			list = append(list, newAPIMod(snapshot, m))
+		}
+		writeJSON(w, list)
+	case "status":
+		status := apiStatus{
+			LastRefresh: snapshot.modTime,
+			IndexHash:   snapshot.pack.Index.Hash,
+			Files:       len(snapshot.index.Files),
+			Watching:    s.watching,
+			Proxy:       s.proxy,
+		}
+		for _, f := range snapshot.index.Files {
+			if f.IsMetaFile() {
+				status.MetaFiles++
+			}
+		}
+		s.statusMu.Lock()
+		if s.lastError != nil {
+			status.LastError = s.lastError.Error()
+			errorTime := s.lastErrorTime
+			status.LastErrorTime = &errorTime
+		}
+		s.statusMu.Unlock()
+		writeJSON(w, status)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *packServer) apiPack(snapshot *serveSnapshot) apiPack {
+	pack := apiPack{
+		ID:          s.name,
+		Name:        snapshot.pack.Name,
+		Author:      snapshot.pack.Author,
+		Version:     snapshot.pack.Version,
+		Description: snapshot.pack.Description,
+		PackFormat:  snapshot.pack.PackFormat,
+		Versions:    snapshot.pack.Versions,
+		URL:         strings.TrimSuffix(s.urlPath, "/") + "/" + s.packFileName,
+	}
+	if s.proxy {
+		pack.ProxyURL = strings.TrimSuffix(s.urlPath, "/") + "/" + proxyPackPath + "/" + s.packFileName
+	}
+	return pack
+}
+
+func newAPIMod(snapshot *serveSnapshot, m *core.Mod) apiMod {
+	mod := apiMod{
+		Name:              m.Name,
+		FileName:          m.FileName,
+		Side:              m.Side,
+		Pin:               m.Pin,
+		AddedAsDependency: m.AddedAsDependency,
+	}
+	if mod.Side == core.EmptySide {
+		mod.Side = core.UniversalSide
+	}
+	if metaFile, err := snapshot.index.RelIndexPath(m.GetFilePath()); err == nil {
+		mod.MetaFile = metaFile
+	}
+	if m.Option != nil {
+		mod.Optional = m.Option.Optional
+		mod.Default = m.Option.Default
+		mod.Description = m.Option.Description
+	}
+	if source, id, ok := core.GetModSource(m); ok {
+		mod.Source, mod.ProjectID = source, id
+	} else if m.Download.URL != "" {
+		mod.Source = "url"
+	}
+	return mod
+}
+
+// getMods loads every metadata file in a snapshot, the first time it is needed
+func (snapshot *serveSnapshot) getMods() ([]*core.Mod, error) {
+	snapshot.modsOnce.Do(func() {
+		snapshot.mods, snapshot.modsErr = snapshot.index.LoadAllMods()
+		sort.Slice(snapshot.mods, func(i, j int) bool {
+			return strings.ToLower(snapshot.mods[i].Name) < strings.ToLower(snapshot.mods[j].Name)
+		})
+	})
+	return snapshot.mods, snapshot.modsErr
+}
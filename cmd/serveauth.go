@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// serveUser is a credential that allows access to the server; its name identifies it in the access log
+type serveUser struct {
+	name string
+	// token is accepted as a bearer token, or as the password of a basic authentication request with any username
+	token string
+	// password is accepted with the name of the user, using basic authentication
+	password string
+}
+
+// serveAuthFile is the configuration file format for serve --auth-file
+type serveAuthFile struct {
+	Tokens []string `toml:"tokens"`
+	Users  []string `toml:"users"`
+}
+
+// serveAuthFilePath is the absolute path of the file given to --auth-file, which is never served (even if it is in the
+// pack folder and listed in the index)
+var serveAuthFilePath string
+
+// loadServeUsers reads the tokens and users allowed to access the server, from flags, the PACKWIZ_SERVE_TOKENS and
+// PACKWIZ_SERVE_USERS environment variables (separated by spaces) or the --auth-file file. These are never read from
+// the pack options, as pack.toml is served to every client.
+func loadServeUsers(flags *pflag.FlagSet) ([]serveUser, error) {
+	tokens, _ := flags.GetStringArray("token")
+	userCreds, _ := flags.GetStringArray("user")
+	tokens = append(tokens, strings.Fields(os.Getenv("PACKWIZ_SERVE_TOKENS"))...)
+	userCreds = append(userCreds, strings.Fields(os.Getenv("PACKWIZ_SERVE_USERS"))...)
+	if authFile := viper.GetString("serve.auth-file"); authFile != "" {
+		var config serveAuthFile
+		if _, err := toml.DecodeFile(authFile, &config); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", authFile, err)
+		}
+		tokens = append(tokens, config.Tokens...)
+		userCreds = append(userCreds, config.Users...)
+		absPath, err := filepath.Abs(authFile)
+		if err != nil {
+			return nil, err
+		}
+		serveAuthFilePath = absPath
+	}
+
+	var users []serveUser
+	for i, v := range tokens {
+		name, token, found := strings.Cut(v, ":")
+		if !found {
+			name, token = "token "+strconv.Itoa(i+1), v
+		}
+		if token == "" {
+			return nil, fmt.Errorf("token %s is empty", name)
+		}
+		users = append(users, serveUser{name: name, token: token})
+	}
+	for _, v := range userCreds {
+		name, password, found := strings.Cut(v, ":")
+		if !found || name == "" || password == "" {
+			return nil, fmt.Errorf("invalid user %s: users must be in the form name:password", name)
+		}
+		users = append(users, serveUser{name: name, password: password})
+	}
+	return users, nil
+}
+
+// serveAuth requires requests to the wrapped handler to be authenticated (if any users are set), and logs requests
+type serveAuth struct {
+	next  http.Handler
+	users []serveUser
+	// log is nil if requests aren't logged
+	log *log.Logger
+}
+
+// newServeAuth wraps a handler, logging requests to the serve.access-log file, or to stdout if authentication is
+// enabled. The returned function closes the log file.
+func newServeAuth(next http.Handler, flags *pflag.FlagSet) (*serveAuth, func(), error) {
+	users, err := loadServeUsers(flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	auth := &serveAuth{next: next, users: users}
+	closeLog := func() {}
+	if logPath := viper.GetString("serve.access-log"); logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		auth.log = log.New(f, "", 0)
+		closeLog = func() { _ = f.Close() }
+	} else if len(users) > 0 {
+		auth.log = log.New(os.Stdout, "", 0)
+	}
+	return auth, closeLog, nil
+}
+
+// warnServeOptionCredentials warns if tokens or users are set in the pack options; they are ignored, as pack.toml is
+// served to every client
+func warnServeOptionCredentials(pack core.Pack) {
+	serveOptions, ok := pack.Options["serve"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	_, hasTokens := serveOptions["tokens"]
+	_, hasUsers := serveOptions["users"]
+	if hasTokens || hasUsers {
+		fmt.Printf("Warning: tokens and users in the pack options of %s are ignored, as pack.toml is served to every client; "+
+			"remove them, and use --token, --user or --auth-file instead\n", pack.Name)
+	}
+}
+
+func secretEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// authenticate returns the user that a request is from, or false if it doesn't have valid credentials
+func (a *serveAuth) authenticate(req *http.Request) (serveUser, bool) {
+	if token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); found {
+		for _, u := range a.users {
+			if u.token != "" && secretEqual(u.token, strings.TrimSpace(token)) {
+				return u, true
+			}
+		}
+		return serveUser{}, false
+	}
+	name, password, ok := req.BasicAuth()
+	if !ok {
+		return serveUser{}, false
+	}
+	for _, u := range a.users {
+		if u.token != "" && secretEqual(u.token, password) {
+			return u, true
+		}
+		if u.password != "" && u.name == name && secretEqual(u.password, password) {
+			return u, true
+		}
+	}
+	return serveUser{}, false
+}
+
+func (a *serveAuth) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	name := "-"
+	if len(a.users) > 0 {
+		user, ok := a.authenticate(req)
+		if !ok {
+			rec.Header().Set("WWW-Authenticate", `Basic realm="packwiz", charset="UTF-8"`)
+			http.Error(rec, "Unauthorized", http.StatusUnauthorized)
+			a.logRequest(req, name, rec, start)
+			return
+		}
+		name = user.name
+	}
+	a.next.ServeHTTP(rec, req)
+	a.logRequest(req, name, rec, start)
+}
+
+// logRequest writes a line to the access log in a format similar to the Common Log Format, with the time taken
+func (a *serveAuth) logRequest(req *http.Request, name string, rec *responseRecorder, start time.Time) {
+	if a.log == nil {
+		return
+	}
+	if strings.ContainsAny(name, " \"") {
+		name = strconv.Quote(name)
+	}
+	a.log.Printf("%s %s [%s] \"%s %s %s\" %d %d %s", req.RemoteAddr, name, start.Format("02/Jan/2006:15:04:05 -0700"),
+		req.Method, req.URL.RequestURI(), req.Proto, rec.status, rec.written, time.Since(start).Round(time.Millisecond))
+}
+
+// responseRecorder records the status code and length of a response, for the access log
+type responseRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.written += int64(n)
+	return n, err
+}
+
+// ReadFrom allows files to be copied to the connection efficiently
+func (r *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	n, err := io.Copy(r.ResponseWriter, src)
+	r.written += n
+	return n, err
+}
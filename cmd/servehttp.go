@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serveContentTypes are the MIME types of files commonly found in modpacks, which aren't always known by the OS
+var serveContentTypes = map[string]string{
+	".toml": "application/toml",
+	".jar":  "application/java-archive",
+	".zip":  "application/zip",
+	".json": "application/json",
+}
+
+func contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := serveContentTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// dataETag returns an ETag for data served from memory
+func dataETag(data []byte) string {
+	return "sha256-" + sha256Hex(data)
+}
+
+// serveContent writes a file with caching headers, handling conditional (If-None-Match/If-Modified-Since) and Range
+// requests. The ETag is omitted if it is empty.
+func serveContent(w http.ResponseWriter, req *http.Request, name string, modTime time.Time, etag string, content io.ReadSeeker) {
+	if etag != "" {
+		w.Header().Set("ETag", `"`+etag+`"`)
+	}
+	if t := contentType(name); t != "" {
+		w.Header().Set("Content-Type", t)
+	}
+	// Files can change at any time, so clients must check they are up to date (using the ETag) before using them
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, req, name, modTime, content)
+}
+
+func (s *packServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/" {
+		_, _ = w.Write(s.indexPage)
+		return
+	}
+
+	// Relative to pack.toml
+	urlPath := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(req.URL.Path, "/")), "/")
+	if endpoint, found := strings.CutPrefix(urlPath, apiPath+"/"); found {
+		s.serveAPI(w, req, endpoint)
+		return
+	}
+	if s.proxy {
+		if rest, found := strings.CutPrefix(urlPath, proxyFilesPath+"/"); found {
+			s.serveProxyDownload(w, req, rest)
+			return
+		}
+		if rest, found := strings.CutPrefix(urlPath, proxyPackPath+"/"); found {
+			s.serveProxyFile(w, req, rest)
+			return
+		}
+	}
+	if urlPath == s.packFileName { // Only need to compare name - already relative to pack.toml
+		if s.lazyRefresh {
+			err := s.refreshAll()
+			if err != nil {
+				s.printf("Failed to refresh pack: %v\n", err)
+			}
+		}
+		snapshot := s.snapshot.Load()
+		serveContent(w, req, urlPath, snapshot.modTime, snapshot.packETag, bytes.NewReader(snapshot.packData))
+		return
+	}
+	snapshot := s.snapshot.Load()
+	if urlPath == snapshot.indexPath {
+		serveContent(w, req, urlPath, snapshot.modTime, snapshot.indexETag, bytes.NewReader(snapshot.indexData))
+		return
+	}
+	s.serveFile(w, req, snapshot, urlPath)
+}
+
+// serveFile serves a file listed in the index, given its path relative to pack.toml
+func (s *packServer) serveFile(w http.ResponseWriter, req *http.Request, snapshot *serveSnapshot, urlPath string) {
+	// Convert to absolute
+	destPath := filepath.Join(s.packServeDir, filepath.FromSlash(urlPath))
+	// Relativisation needs to be done using filepath, as path doesn't have Rel!
+	// (now using index util function)
+	// Relative to index.toml ("pack root")
+	indexRelPath, err := snapshot.index.RelIndexPath(destPath)
+	if err != nil {
+		fmt.Println("Failed to parse path", err)
+		return
+	}
+	// Only allow indexed files, other than the file listing credentials
+	hash, hashFormat, found := snapshot.index.GetHash(indexRelPath)
+	if absPath, err := filepath.Abs(destPath); err == nil && serveAuthFilePath != "" && absPath == serveAuthFilePath {
+		found = false
+	}
+	if !found {
+		fmt.Printf("File not found: %s\n", destPath)
+		w.WriteHeader(404)
+		_, _ = w.Write([]byte("File not found"))
+		return
+	}
+
+	f, err := os.Open(destPath)
+	if err != nil {
+		fmt.Printf("Error reading file \"%s\": %s\n", destPath, err)
+		w.WriteHeader(404)
+		_, _ = w.Write([]byte("File not found"))
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		fmt.Printf("Error reading file \"%s\": %s\n", destPath, err)
+		w.WriteHeader(404)
+		_, _ = w.Write([]byte("File not found"))
+		return
+	}
+
+	// The hash in the index is only used if the file hasn't changed since the index was refreshed
+	etag := ""
+	if hash != "" && !info.ModTime().After(snapshot.modTime) {
+		etag = hashFormat + "-" + strings.ToLower(hash)
+	}
+	serveContent(w, req, info.Name(), info.ModTime(), etag, f)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed serve-templates/packs.html
+var packsPage string
+
+// servePack is a modpack served under /name/
+type servePack struct {
+	name     string
+	packFile string
+}
+
+// servePacksConfig is the configuration file format for serve --packs
+type servePacksConfig struct {
+	// Packs maps the path each pack is served under to its pack file, relative to the configuration file
+	Packs map[string]string `toml:"packs"`
+}
+
+// findServePacks reads the packs to serve from a configuration file, or finds them in the subfolders of a folder
+func findServePacks(packsPath string) ([]servePack, error) {
+	info, err := os.Stat(packsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []servePack
+	if info.IsDir() {
+		entries, err := os.ReadDir(packsPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			packFile := filepath.Join(packsPath, entry.Name(), "pack.toml")
+			if _, err := os.Stat(packFile); entry.IsDir() && err == nil {
+				packs = append(packs, servePack{name: entry.Name(), packFile: packFile})
+			}
+		}
+		if len(packs) == 0 {
+			return nil, fmt.Errorf("no folders containing a pack.toml file found in %s", packsPath)
+		}
+	} else {
+		var config servePacksConfig
+		if _, err := toml.DecodeFile(packsPath, &config); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", packsPath, err)
+		}
+		if len(config.Packs) == 0 {
+			return nil, fmt.Errorf("no packs listed in %s", packsPath)
+		}
+		for name, packFile := range config.Packs {
+			if !filepath.IsAbs(packFile) {
+				packFile = filepath.Join(filepath.Dir(packsPath), filepath.FromSlash(packFile))
+			}
+			packs = append(packs, servePack{name: name, packFile: packFile})
+		}
+	}
+
+	for _, p := range packs {
+		if p.name == "" || p.name == "." || p.name == ".." || url.PathEscape(p.name) != p.name {
+			return nil, fmt.Errorf("invalid pack name %q: names must be usable in a URL path", p.name)
+		}
+	}
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].name < packs[j].name
+	})
+	return packs, nil
+}
+
+// newMultiPackHandler serves each pack under /name/, with a page listing every pack at the root
+func newMultiPackHandler(packs []servePack, port string, scheme string) (http.Handler, error) {
+	t, err := template.New("packs-page").Parse(packsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	servers := make([]*packServer, len(packs))
+	for i, p := range packs {
+		fmt.Printf("Loading modpack %s...\n", p.name)
+		servers[i], err = newPackServer(p.name, p.packFile, "/"+p.name, port, scheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", p.name, err)
+		}
+		// Each pack has its own snapshot and refresh lock, so packs are refreshed independently
+		mux.Handle("/"+p.name+"/", http.StripPrefix("/"+p.name, servers[i]))
+	}
+
+	mux.HandleFunc("/"+apiPath+"/packs", func(w http.ResponseWriter, req *http.Request) {
+		list := make([]apiPack, len(servers))
+		for i, s := range servers {
+			list[i] = s.apiPack(s.snapshot.Load())
+		}
+		writeJSON(w, list)
+	})
+
+	type packInfo struct {
+		Name, Version, Path string
+	}
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, req *http.Request) {
+		data := struct {
+			Port, Scheme string
+			Packs        []packInfo
+		}{Port: port, Scheme: scheme}
+		for _, s := range servers {
+			pack := s.snapshot.Load().pack
+			name := pack.Name
+			if name == "" {
+				name = s.name
+			}
+			data.Packs = append(data.Packs, packInfo{
+				Name:    name,
+				Version: pack.Version,
+				Path:    path.Join("/", s.name, s.packFileName),
+			})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := t.Execute(w, data); err != nil {
+			fmt.Println("Failed to render pack list", err)
+		}
+	})
+	return mux, nil
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/viper"
+)
+
+const (
+	// proxyPackPath is the path the proxied copy of the pack is served under
+	proxyPackPath = "proxy"
+	// proxyFilesPath is the path external files are served under, as proxyFilesPath/<hash format>/<hash>/<file name>
+	proxyFilesPath = "proxy-files"
+	// maxProxyTrees is the number of proxied copies of the pack kept for different base URLs; base URLs come from the
+	// Host header of requests (unless --proxy-url is set), so this stops clients from using unlimited memory
+	maxProxyTrees = 8
+)
+
+// proxyTree is a copy of a pack where the download URLs in metadata files point to the server, so clients download
+// external files through the server rather than from the internet
+type proxyTree struct {
+	packData  []byte
+	indexData []byte
+	// metaFiles contains the modified metadata files, by path in the index
+	metaFiles map[string][]byte
+	// downloads contains the mods that can be downloaded, by hash format and hash as used in download URLs
+	downloads map[string]*core.Mod
+}
+
+// getProxyTree returns the proxied copy of the pack for a base URL, creating it if it hasn't been requested before
+func (snapshot *serveSnapshot) getProxyTree(baseURL string) (*proxyTree, error) {
+	snapshot.proxyMu.Lock()
+	defer snapshot.proxyMu.Unlock()
+	if tree, ok := snapshot.proxyTrees[baseURL]; ok {
+		return tree, nil
+	}
+	tree, err := newProxyTree(snapshot, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.proxyTrees == nil {
+		snapshot.proxyTrees = make(map[string]*proxyTree)
+	}
+	if len(snapshot.proxyTrees) >= maxProxyTrees {
+		// Evict any copy; it is recreated if it is requested again
+		for k := range snapshot.proxyTrees {
+			delete(snapshot.proxyTrees, k)
+			break
+		}
+	}
+	snapshot.proxyTrees[baseURL] = tree
+	return tree, nil
+}
+
+func proxyDownloadKey(hashFormat string, hash string) string {
+	return hashFormat + "/" + strings.ToLower(hash)
+}
+
+// newProxyTree rewrites the download URLs of every metadata file in a snapshot, updating the index and pack to match
+func newProxyTree(snapshot *serveSnapshot, baseURL string) (*proxyTree, error) {
+	mods, err := snapshot.getMods()
+	if err != nil {
+		return nil, err
+	}
+	tree := &proxyTree{
+		metaFiles: make(map[string][]byte, len(mods)),
+		downloads: make(map[string]*core.Mod, len(mods)),
+	}
+	index := snapshot.index.Clone()
+	for _, mod := range mods {
+		relPath, err := index.RelIndexPath(mod.GetFilePath())
+		if err != nil {
+			return nil, err
+		}
+		key := proxyDownloadKey(mod.Download.HashFormat, mod.Download.Hash)
+		tree.downloads[key] = mod
+
+		proxied := *mod
+		proxied.Download.Mode = ""
+		proxied.Download.URL = baseURL + "/" + proxyFilesPath + "/" + key + "/" + url.PathEscape(mod.FileName)
+		data, err := proxied.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write metadata file %s: %w", relPath, err)
+		}
+		tree.metaFiles[relPath] = data
+		err = index.RefreshFileWithHash(mod.GetFilePath(), "sha256", sha256Hex(data), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tree.indexData, err = index.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	pack := snapshot.pack
+	pack.Index.HashFormat = "sha256"
+	pack.Index.Hash = sha256Hex(tree.indexData)
+	tree.packData, err = pack.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pack file: %w", err)
+	}
+	return tree, nil
+}
+
+// proxyBaseURL returns the URL of the pack, as used by the client that made the request
+func (s *packServer) proxyBaseURL(req *http.Request) string {
+	if proxyURL := viper.GetString("serve.proxy-url"); proxyURL != "" {
+		return strings.TrimSuffix(proxyURL, "/") + strings.TrimSuffix(s.urlPath, "/")
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + strings.TrimSuffix(s.urlPath, "/")
+}
+
+// serveProxyFile serves a file from the proxied copy of the pack, given its path relative to pack.toml. Files that
+// aren't metadata files are the same as in the pack.
+func (s *packServer) serveProxyFile(w http.ResponseWriter, req *http.Request, urlPath string) {
+	if urlPath == s.packFileName && s.lazyRefresh {
+		err := s.refreshAll()
+		if err != nil {
+			s.printf("Failed to refresh pack: %v\n", err)
+		}
+	}
+	snapshot := s.snapshot.Load()
+	tree, err := snapshot.getProxyTree(s.proxyBaseURL(req))
+	if err != nil {
+		s.printf("Failed to create proxied pack: %v\n", err)
+		http.Error(w, "Failed to create proxied pack", http.StatusInternalServerError)
+		return
+	}
+
+	var data []byte
+	if urlPath == s.packFileName {
+		data = tree.packData
+	} else if urlPath == snapshot.indexPath {
+		data = tree.indexData
+	} else {
+		indexRelPath, err := snapshot.index.RelIndexPath(filepath.Join(s.packServeDir, filepath.FromSlash(urlPath)))
+		if err == nil {
+			data = tree.metaFiles[indexRelPath]
+		}
+	}
+	if data == nil {
+		s.serveFile(w, req, snapshot, urlPath)
+		return
+	}
+	serveContent(w, req, urlPath, snapshot.modTime, dataETag(data), bytes.NewReader(data))
+}
+
+// proxyCacheMu guards the cache index (which is shared between every pack) while download sessions are created and
+// their downloads are saved; files are downloaded without holding it
+var proxyCacheMu sync.Mutex
+
+// proxyDownloads stores the downloads in progress by proxyDownloadKey, so that concurrent requests for the same file
+// share a single download
+var (
+	proxyDownloads   = make(map[string]*proxyDownload)
+	proxyDownloadsMu sync.Mutex
+)
+
+// proxyDownload is a download in progress; path and err are set when done is closed
+type proxyDownload struct {
+	done chan struct{}
+	path string
+	err  error
+}
+
+// serveProxyDownload serves an external file from the download cache, downloading it first if it isn't cached. Only
+// files used by the pack can be downloaded.
+func (s *packServer) serveProxyDownload(w http.ResponseWriter, req *http.Request, urlPath string) {
+	parts := strings.SplitN(urlPath, "/", 3)
+	if len(parts) != 3 {
+		http.NotFound(w, req)
+		return
+	}
+	tree, err := s.snapshot.Load().getProxyTree(s.proxyBaseURL(req))
+	if err != nil {
+		s.printf("Failed to create proxied pack: %v\n", err)
+		http.Error(w, "Failed to create proxied pack", http.StatusInternalServerError)
+		return
+	}
+	mod, ok := tree.downloads[proxyDownloadKey(parts[0], parts[1])]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	file, err := fetchToCache(mod)
+	if err != nil {
+		s.printf("Failed to download %s: %v\n", mod.FileName, err)
+		http.Error(w, "Failed to download file: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	serveContent(w, req, mod.FileName, info.ModTime(), mod.Download.HashFormat+"-"+strings.ToLower(mod.Download.Hash), file)
+}
+
+// fetchToCache opens a file from the download cache, downloading it first if it isn't cached
+func fetchToCache(mod *core.Mod) (*os.File, error) {
+	key := proxyDownloadKey(mod.Download.HashFormat, mod.Download.Hash)
+	proxyDownloadsMu.Lock()
+	dl, inProgress := proxyDownloads[key]
+	if !inProgress {
+		dl = &proxyDownload{done: make(chan struct{})}
+		proxyDownloads[key] = dl
+	}
+	proxyDownloadsMu.Unlock()
+
+	if !inProgress {
+		dl.path, dl.err = downloadToCache(mod)
+		proxyDownloadsMu.Lock()
+		delete(proxyDownloads, key)
+		proxyDownloadsMu.Unlock()
+		close(dl.done)
+	}
+	<-dl.done
+	if dl.err != nil {
+		return nil, dl.err
+	}
+	return os.Open(dl.path)
+}
+
+// downloadToCache downloads a file into the download cache (if it isn't already cached), returning its path
+func downloadToCache(mod *core.Mod) (string, error) {
+	proxyCacheMu.Lock()
+	session, err := core.CreateDownloadSession([]*core.Mod{mod}, []string{})
+	proxyCacheMu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if len(session.GetManualDownloads()) > 0 {
+		return "", fmt.Errorf("%s must be downloaded manually; add it to the cache with packwiz cache import", mod.FileName)
+	}
+	var path string
+	var hashes map[string]string
+	for dl := range session.StartDownloads() {
+		if dl.Error != nil {
+			err = dl.Error
+			continue
+		}
+		path, hashes = dl.File.Name(), dl.Hashes
+		_ = dl.File.Close()
+	}
+	if path == "" {
+		return "", err
+	}
+
+	// Other sessions may have saved the index since this session loaded it, so the file is added to the current index
+	// rather than saving the session's copy
+	proxyCacheMu.Lock()
+	defer proxyCacheMu.Unlock()
+	index, err := core.LoadCacheIndex()
+	if err == nil {
+		_, err = index.AddCachedFile(hashes)
+	}
+	if err == nil {
+		err = index.Save()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save cache index: %w", err)
+	}
+	return path, nil
+}
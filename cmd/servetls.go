@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+// selfSignedValidity is how long generated certificates are valid for; they are regenerated when they expire
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// selfSignedHosts returns the hostnames and IP addresses that a self-signed certificate should be valid for
+func selfSignedHosts(bind string) ([]string, []net.IP) {
+	names := []string{"localhost"}
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		names = append(names, hostname)
+	}
+	if ip := net.ParseIP(bind); ip != nil {
+		if !ip.IsUnspecified() {
+			ips = append(ips, ip)
+		}
+	} else if bind != "" {
+		names = append(names, bind)
+	}
+	// Clients on the network connect using the address of one of the interfaces
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+	return names, ips
+}
+
+// selfSignedCert returns the paths to a self-signed certificate and key stored in the packwiz data folder, generating
+// them if they don't exist, have expired, or aren't valid for every address of this machine
+func selfSignedCert(bind string) (string, string, error) {
+	store, err := core.GetPackwizLocalStore()
+	if err != nil {
+		return "", "", err
+	}
+	certFile := filepath.Join(store, "serve", "cert.pem")
+	keyFile := filepath.Join(store, "serve", "key.pem")
+	names, ips := selfSignedHosts(bind)
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && cert.Leaf != nil {
+		valid := time.Now().Add(24 * time.Hour).Before(cert.Leaf.NotAfter)
+		for _, name := range names {
+			valid = valid && cert.Leaf.VerifyHostname(name) == nil
+		}
+		for _, ip := range ips {
+			valid = valid && cert.Leaf.VerifyHostname(ip.String()) == nil
+		}
+		if valid {
+			printCertFingerprint(cert.Leaf.Raw)
+			return certFile, keyFile, nil
+		}
+	}
+
+	fmt.Println("Generating self-signed certificate...")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate certificate: %w", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"packwiz"}, CommonName: "packwiz serve"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              names,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate certificate: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(certFile), 0700)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save key: %w", err)
+	}
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save certificate: %w", err)
+	}
+	fmt.Printf("Certificate saved to %s\n", certFile)
+	printCertFingerprint(der)
+	return certFile, keyFile, nil
+}
+
+// printCertFingerprint shows the fingerprint of a certificate, so clients can check they are connecting to this server
+func printCertFingerprint(der []byte) {
+	sum := sha256.Sum256(der)
+	fmt.Printf("Certificate SHA-256 fingerprint: %s\n", hex.EncodeToString(sum[:]))
+}
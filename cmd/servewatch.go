@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/fsnotify/fsnotify"
+)
+
+// serveRefreshDelay is how long to wait for more changes after a file changes, before refreshing the index
+const serveRefreshDelay = 300 * time.Millisecond
+
+// packServer serves the files in a modpack, refreshing the index in the background as files change
+type packServer struct {
+	// name is shown in messages when serving multiple packs
+	name     string
+	packFile string
+	// urlPath is the path that the pack is served under
+	urlPath      string
+	packServeDir string
+	packFileName string
+	indexPage    []byte
+	// proxy is set to serve a copy of the pack that downloads external files through the server
+	proxy bool
+	// lazyRefresh is set when files can't be watched, to refresh the index whenever pack.toml is requested instead
+	lazyRefresh bool
+	watching    bool
+
+	// snapshot is the last consistent state of the modpack; it is replaced as a whole after each refresh, so requests
+	// never wait for a refresh or see a partially refreshed index
+	snapshot atomic.Pointer[serveSnapshot]
+	// refreshMu ensures only one refresh runs at a time
+	refreshMu sync.Mutex
+
+	// lastError is the error from the last refresh if it failed, shown in the status API
+	lastError     error
+	lastErrorTime time.Time
+	statusMu      sync.Mutex
+}
+
+// serveSnapshot is the pack file and index as they were written by a refresh
+type serveSnapshot struct {
+	pack      core.Pack
+	index     core.Index
+	indexPath string // Relative to pack.toml
+	packData  []byte
+	indexData []byte
+	packETag  string
+	indexETag string
+	// modTime is when the snapshot was created; files changed after this might not match their hashes in the index
+	modTime time.Time
+
+	// mods are the metadata files in the index, loaded when first requested
+	mods     []*core.Mod
+	modsErr  error
+	modsOnce sync.Once
+
+	// proxyTrees are the proxied copies of the pack, created when first requested, by base URL
+	proxyTrees map[string]*proxyTree
+	proxyMu    sync.Mutex
+}
+
+// printf prints a message about the pack, prefixed with its name when serving multiple packs
+func (s *packServer) printf(format string, a ...any) {
+	if s.name != "" {
+		format = "[" + s.name + "] " + format
+	}
+	fmt.Printf(format, a...)
+}
+
+// load reads the pack and index files as they are on disk, and makes them the current snapshot
+func (s *packServer) load(pack core.Pack, index core.Index) error {
+	packData, err := os.ReadFile(filepath.Join(s.packServeDir, s.packFileName))
+	if err != nil {
+		return err
+	}
+	snapshot := &serveSnapshot{
+		pack:      pack,
+		index:     index,
+		indexPath: path.Clean(pack.Index.File),
+		packData:  packData,
+	}
+	snapshot.indexData, err = os.ReadFile(snapshot.indexFile())
+	if err != nil {
+		return err
+	}
+	snapshot.packETag = dataETag(snapshot.packData)
+	snapshot.indexETag = dataETag(snapshot.indexData)
+	snapshot.modTime = time.Now()
+	s.snapshot.Store(snapshot)
+	return nil
+}
+
+// indexFile returns the path to the index file on disk
+func (s *serveSnapshot) indexFile() string {
+	return s.index.ResolveIndexPath(path.Base(s.pack.Index.File))
+}
+
+// refreshAll reloads the pack and index from disk and rehashes every file
+func (s *packServer) refreshAll() (err error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	defer func() { s.setRefreshError(err) }()
+	return s.refreshAllLocked()
+}
+
+// setRefreshError records the result of a refresh
+func (s *packServer) setRefreshError(err error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.lastError = err
+	if err != nil {
+		s.lastErrorTime = time.Now()
+	}
+}
+
+func (s *packServer) refreshAllLocked() error {
+	pack, err := core.LoadPackFile(s.packFile)
+	if err != nil {
+		return err
+	}
+	index, err := pack.LoadIndex()
+	if err != nil {
+		return err
+	}
+	err = index.Refresh()
+	if err != nil {
+		return err
+	}
+	err = s.write(pack, index)
+	if err != nil {
+		return err
+	}
+	s.printf("Index refreshed!\n")
+	return nil
+}
+
+// refreshPaths updates the index for files that have changed. If the pack file or index file might have been changed
+// by something else, or force is set because .packwizignore has changed or changes have been missed, everything is
+// reloaded instead.
+func (s *packServer) refreshPaths(paths []string, packChanged bool, force bool) (err error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	defer func() { s.setRefreshError(err) }()
+
+	current := s.snapshot.Load()
+	// Changes to the pack file and index file are usually from the server's own writes, which don't need a refresh
+	if force || (packChanged && !s.unchangedOnDisk(current)) {
+		return s.refreshAllLocked()
+	}
+	index := current.index.Clone()
+	changed, err := index.RefreshPaths(paths)
+	if err != nil || !changed {
+		return err
+	}
+	err = s.write(current.pack, index)
+	if err != nil {
+		return err
+	}
+	s.printf("Index refreshed!\n")
+	return nil
+}
+
+// unchangedOnDisk checks if the pack file and index file are the same as the files in a snapshot, i.e. they have only
+// been changed by the server itself
+func (s *packServer) unchangedOnDisk(snapshot *serveSnapshot) bool {
+	packData, err := os.ReadFile(filepath.Join(s.packServeDir, s.packFileName))
+	if err != nil || string(packData) != string(snapshot.packData) {
+		return false
+	}
+	indexData, err := os.ReadFile(snapshot.indexFile())
+	return err == nil && string(indexData) == string(snapshot.indexData)
+}
+
+// write saves the index and pack file, and makes them the current snapshot
+func (s *packServer) write(pack core.Pack, index core.Index) error {
+	tx := core.NewTransaction()
+	defer tx.Rollback()
+	err := tx.WriteIndexAndPack(index, &pack)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	return s.load(pack, index)
+}
+
+// watch starts watching every folder in the pack root that isn't ignored, refreshing the index when files change
+func (s *packServer) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	index := s.snapshot.Load().index
+	filter := index.Filter()
+	if err := addWatches(watcher, index.PackRoot(), filter); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	abs := func(p string) string {
+		p, _ = filepath.Abs(p)
+		return p
+	}
+	packFile := abs(filepath.Join(s.packServeDir, s.packFileName))
+	ignoreFile := abs(filepath.Join(index.PackRoot(), ".packwizignore"))
+
+	go func() {
+		pending := make(map[string]bool)
+		packChanged := false
+		force := false
+		timer := time.NewTimer(serveRefreshDelay)
+		timer.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				// Paths must be in the same form as when walking the pack root, for ignore rules to match
+				name := filepath.Clean(event.Name)
+				indexFile := abs(s.snapshot.Load().indexFile())
+				switch abs(name) {
+				case ignoreFile:
+					// Rules have changed; watch folders that are no longer ignored
+					filter = s.snapshot.Load().index.Filter()
+					if err := addWatches(watcher, index.PackRoot(), filter); err != nil {
+						s.printf("Error watching files: %v\n", err)
+					}
+					force = true
+				case packFile, indexFile:
+					packChanged = true
+				default:
+					info, err := os.Stat(name)
+					if err == nil && info.IsDir() {
+						if !filter.Includes(name, true) {
+							continue
+						}
+						if err := addWatches(watcher, name, filter); err != nil {
+							s.printf("Error watching files: %v\n", err)
+						}
+					} else if err == nil && !filter.Includes(name, false) {
+						continue
+					}
+					pending[name] = true
+				}
+				timer.Reset(serveRefreshDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				if errors.Is(err, fsnotify.ErrEventOverflow) {
+					// Some changes have been missed; refresh everything
+					force = true
+					timer.Reset(serveRefreshDelay)
+					continue
+				}
+				s.printf("Error watching files: %v\n", err)
+			case <-timer.C:
+				paths := make([]string, 0, len(pending))
+				for p := range pending {
+					paths = append(paths, p)
+				}
+				err := s.refreshPaths(paths, packChanged, force)
+				if err != nil {
+					s.printf("Failed to refresh pack: %v\n", err)
+				}
+				pending = make(map[string]bool)
+				packChanged = false
+				force = false
+			}
+		}
+	}()
+	s.watching = true
+	return nil
+}
+
+// addWatches watches a folder and every folder inside it that isn't ignored
+func addWatches(watcher *fsnotify.Watcher, root string, filter core.IndexFilter) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Folders might be removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if !filter.Includes(path, true) {
+			return fs.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/codecraft3r/packwiz/cmdshared"
 	"github.com/codecraft3r/packwiz/core"
@@ -12,15 +18,24 @@ import (
 
 // UpdateCmd represents the update command
 var UpdateCmd = &cobra.Command{
-	Use:     "update [name]",
+	Use:     "update [name]...",
 	Short:   "Update an external file (or all external files) in the modpack",
 	Aliases: []string{"upgrade"},
-	Args:    cobra.MaximumNArgs(1),
+	Args:    cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		// TODO: --check flag?
-		// TODO: specify multiple files to update at once?
+		checkOnly := viper.GetBool("update.check")
+		jsonOutput := checkOnly && viper.GetString("update.format") == "json"
+		if checkOnly {
+			format := viper.GetString("update.format")
+			if format != "text" && format != "json" {
+				fmt.Printf("Unknown report format %s; must be text or json\n", format)
+				os.Exit(1)
+			}
+		}
 
-		fmt.Println("Loading modpack...")
+		if !jsonOutput {
+			fmt.Println("Loading modpack...")
+		}
 		pack, err := core.LoadPack()
 		if err != nil {
 			fmt.Println(err)
@@ -32,38 +47,48 @@ var UpdateCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if viper.GetBool("update.all") && len(args) > 0 {
+			fmt.Println("Cannot specify files to update when using the --all flag; use --exclude to skip files instead")
+			os.Exit(1)
+		}
+		filters, err := getUpdateFilters()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if checkOnly {
+			os.Exit(checkUpdates(pack, index, args, filters, jsonOutput))
+		}
+
+		// Multiple files, glob patterns and filters all use the same batched update as --all
+		batch := viper.GetBool("update.all") || len(args) > 1 || filters.isSet() || (len(args) == 1 && isGlobPattern(args[0]))
+
+		// All changes are staged, and only written once every file has been updated successfully
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+
 		var singleUpdatedName string
-		if viper.GetBool("update.all") {
-			filesWithUpdater := make(map[string][]*core.Mod)
+		if batch {
 			fmt.Println("Reading metadata files...")
-			mods, err := index.LoadAllMods()
+			mods, warnings, err := selectMods(index, args, filters)
 			if err != nil {
-				fmt.Printf("Failed to update all files: %v\n", err)
+				fmt.Printf("Failed to update files: %v\n", err)
 				os.Exit(1)
 			}
-			for _, modData := range mods {
-				updaterFound := false
-				for k := range modData.Update {
-					slice, ok := filesWithUpdater[k]
-					if !ok {
-						_, ok = core.Updaters[k]
-						if !ok {
-							continue
-						}
-						slice = []*core.Mod{}
-					}
-					updaterFound = true
-					filesWithUpdater[k] = append(slice, modData)
-				}
-				if !updaterFound {
-					fmt.Printf("A supported update system for \"%s\" cannot be found.\n", modData.Name)
+			for _, warning := range warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
+			filesWithUpdater, unsupported := groupByUpdater(mods, filters.sources)
+			for _, modData := range unsupported {
+				if len(filters.sources) > 0 {
+					continue
 				}
+				fmt.Printf("A supported update system for \"%s\" cannot be found.\n", modData.Name)
 			}
 
 			fmt.Println("Checking for updates...")
-			updatesFound := false
-			updatableFiles := make(map[string][]*core.Mod)
-			updaterCachedStateMap := make(map[string][]interface{})
+			var pending []pendingUpdate
 			for k, v := range filesWithUpdater {
 				checks, err := core.Updaters[k].CheckUpdate(v, pack)
 				if err != nil {
@@ -83,44 +108,73 @@ var UpdateCmd = &cobra.Command{
 							continue
 						}
 
-						if !updatesFound {
-							fmt.Println("Updates found:")
-							updatesFound = true
-						}
-						fmt.Printf("%s: %s\n", v[i].Name, check.UpdateString)
-						updatableFiles[k] = append(updatableFiles[k], v[i])
-						updaterCachedStateMap[k] = append(updaterCachedStateMap[k], check.CachedState)
+						pending = append(pending, pendingUpdate{k, v[i], check})
 					}
 				}
 			}
 
-			if !updatesFound {
+			if len(pending) == 0 {
 				fmt.Println("All files are up to date!")
 				return
 			}
+			sort.SliceStable(pending, func(i, j int) bool {
+				return pending[i].mod.GetFilePath() < pending[j].mod.GetFilePath()
+			})
 
-			if !cmdshared.PromptYesNo("Do you want to update? [Y/n]: ") {
-				fmt.Println("Cancelled!")
-				return
+			updatableFiles := make(map[string][]*core.Mod)
+			updaterCachedStateMap := make(map[string][]interface{})
+			fmt.Println("Updates found:")
+			if viper.GetBool("update.interactive") {
+				accepted := 0
+				for _, u := range pending {
+					fmt.Printf("%s: %s\n", u.mod.Name, u.check.UpdateString)
+					printUpdateDetails(u.check)
+					if !cmdshared.PromptYesNo(fmt.Sprintf("Update %s? [Y/n]: ", u.mod.Name)) {
+						continue
+					}
+					updatableFiles[u.updater] = append(updatableFiles[u.updater], u.mod)
+					updaterCachedStateMap[u.updater] = append(updaterCachedStateMap[u.updater], u.check.CachedState)
+					accepted++
+				}
+				if accepted == 0 {
+					fmt.Println("No updates selected!")
+					return
+				}
+				fmt.Printf("Updating %d/%d files...\n", accepted, len(pending))
+			} else {
+				for _, u := range pending {
+					fmt.Printf("%s: %s\n", u.mod.Name, u.check.UpdateString)
+					if viper.GetBool("update.changelog") {
+						printUpdateDetails(u.check)
+					}
+					updatableFiles[u.updater] = append(updatableFiles[u.updater], u.mod)
+					updaterCachedStateMap[u.updater] = append(updaterCachedStateMap[u.updater], u.check.CachedState)
+				}
+				if !cmdshared.PromptYesNo("Do you want to update? [Y/n]: ") {
+					fmt.Println("Cancelled!")
+					return
+				}
 			}
 
 			for k, v := range updatableFiles {
 				err := core.Updaters[k].DoUpdate(v, updaterCachedStateMap[k])
 				if err != nil {
-					// TODO: do we return err code 1?
-					fmt.Println(err.Error())
-					continue
+					tx.Rollback()
+					fmt.Printf("Failed to update files using %s: %v\nNo files have been changed.\n", k, err)
+					os.Exit(1)
 				}
 				for _, modData := range v {
-					format, hash, err := modData.Write()
+					format, hash, err := tx.WriteMod(modData)
 					if err != nil {
-						fmt.Println(err.Error())
-						continue
+						tx.Rollback()
+						fmt.Printf("Failed to write %s: %v\nNo files have been changed.\n", modData.Name, err)
+						os.Exit(1)
 					}
 					err = index.RefreshFileWithHash(modData.GetFilePath(), format, hash, true)
 					if err != nil {
+						tx.Rollback()
 						fmt.Println(err.Error())
-						continue
+						os.Exit(1)
 					}
 				}
 			}
@@ -164,6 +218,13 @@ var UpdateCmd = &cobra.Command{
 
 				if check[0].UpdateAvailable {
 					fmt.Printf("Update available: %s\n", check[0].UpdateString)
+					if viper.GetBool("update.changelog") || viper.GetBool("update.interactive") {
+						printUpdateDetails(check[0])
+					}
+					if viper.GetBool("update.interactive") && !cmdshared.PromptYesNo("Do you want to update? [Y/n]: ") {
+						fmt.Println("Cancelled!")
+						return
+					}
 
 					err = updater.DoUpdate([]*core.Mod{&modData}, []interface{}{check[0].CachedState})
 					if err != nil {
@@ -171,13 +232,15 @@ var UpdateCmd = &cobra.Command{
 						os.Exit(1)
 					}
 
-					format, hash, err := modData.Write()
+					format, hash, err := tx.WriteMod(&modData)
 					if err != nil {
+						tx.Rollback()
 						fmt.Println(err)
 						os.Exit(1)
 					}
 					err = index.RefreshFileWithHash(modPath, format, hash, true)
 					if err != nil {
+						tx.Rollback()
 						fmt.Println(err)
 						os.Exit(1)
 					}
@@ -195,22 +258,18 @@ var UpdateCmd = &cobra.Command{
 			}
 		}
 
-		err = index.Write()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = pack.UpdateIndexHash()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		if viper.GetBool("update.all") {
+		if batch {
 			fmt.Println("Files updated!")
 		} else {
 			fmt.Printf("\"%s\" updated!\n", singleUpdatedName)
@@ -218,9 +277,384 @@ var UpdateCmd = &cobra.Command{
 	},
 }
 
+// pendingUpdate is an update found by an updater, which hasn't been applied yet
+type pendingUpdate struct {
+	updater string
+	mod     *core.Mod
+	check   core.UpdateCheck
+}
+
+// updateChangelogLines is the maximum number of lines of a changelog printed for each update
+const updateChangelogLines = 15
+
+// loadChangelog retrieves the body of the changelog of an update, if the updater didn't include it in the check.
+// Failing to retrieve it isn't treated as an error, as it isn't needed for the update.
+func loadChangelog(check *core.UpdateCheck) {
+	if check.NewVersion.Body != "" || check.LoadChangelog == nil {
+		return
+	}
+	if body, err := check.LoadChangelog(); err == nil {
+		check.NewVersion.Body = body
+	}
+}
+
+// printUpdateDetails prints the new version and changelog given by an updater for an update
+func printUpdateDetails(check core.UpdateCheck) {
+	loadChangelog(&check)
+	var details []string
+	if check.NewVersion.Version != "" {
+		details = append(details, check.NewVersion.Version)
+	}
+	if !check.NewVersion.Date.IsZero() {
+		details = append(details, "released "+check.NewVersion.Date.Format("2006-01-02"))
+	}
+	if check.ReleaseChannel != "" && check.ReleaseChannel != core.ReleaseChannelRelease {
+		details = append(details, check.ReleaseChannel)
+	}
+	if len(details) > 0 {
+		fmt.Printf("  New version: %s\n", strings.Join(details, ", "))
+	}
+	body := strings.TrimSpace(check.NewVersion.Body)
+	if body == "" {
+		fmt.Println("  No changelog available")
+	} else {
+		lines := strings.Split(body, "\n")
+		truncated := len(lines) > updateChangelogLines
+		if truncated {
+			lines = lines[:updateChangelogLines]
+		}
+		for _, l := range lines {
+			fmt.Println(strings.TrimRight("    "+l, " \r"))
+		}
+		if truncated {
+			fmt.Println("    ...")
+		}
+	}
+	if check.NewVersion.URL != "" {
+		fmt.Printf("  %s\n", check.NewVersion.URL)
+	}
+}
+
+// updateFilters stores the filters given to the update command to limit which files are updated
+type updateFilters struct {
+	sources  []string
+	side     string
+	excludes []string
+}
+
+func (f updateFilters) isSet() bool {
+	return len(f.sources) > 0 || len(f.side) > 0 || len(f.excludes) > 0
+}
+
+// getUpdateFilters reads and validates the update filter flags
+func getUpdateFilters() (updateFilters, error) {
+	filters := updateFilters{
+		sources:  viper.GetStringSlice("update.source"),
+		side:     viper.GetString("update.side"),
+		excludes: viper.GetStringSlice("update.exclude"),
+	}
+	for _, source := range filters.sources {
+		if _, ok := core.Updaters[source]; !ok {
+			var known []string
+			for k := range core.Updaters {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return filters, fmt.Errorf("unknown source %s; must be one of %s", source, strings.Join(known, ", "))
+		}
+	}
+	if len(filters.side) > 0 && filters.side != core.UniversalSide && filters.side != core.ServerSide && filters.side != core.ClientSide {
+		return filters, fmt.Errorf("invalid side %q, must be one of client, server, or both", filters.side)
+	}
+	for _, pattern := range filters.excludes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return filters, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+	}
+	return filters, nil
+}
+
+func isGlobPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// metaFileName returns the name of a mod's metadata file without the extension, as used to select files to update
+func metaFileName(modData *core.Mod) string {
+	fileName := filepath.Base(modData.GetFilePath())
+	return strings.TrimSuffix(strings.TrimSuffix(fileName, core.MetaExtension), core.MetaExtensionOld)
+}
+
+// selectMods loads the mods matching the given names or glob patterns (or all mods, if none are given),
+// then applies the side and exclude filters; it also returns warnings for glob patterns that matched nothing
+func selectMods(index core.Index, names []string, filters updateFilters) ([]*core.Mod, []string, error) {
+	for _, name := range names {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern %s: %w", name, err)
+		}
+	}
+
+	allMods, err := index.LoadAllMods()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched := make([]bool, len(names))
+	var mods []*core.Mod
+	for _, modData := range allMods {
+		modName := metaFileName(modData)
+		selected := len(names) == 0
+		for i, name := range names {
+			if ok, _ := path.Match(name, modName); ok {
+				matched[i] = true
+				selected = true
+			}
+		}
+		if !selected {
+			continue
+		}
+		excluded := false
+		for _, pattern := range filters.excludes {
+			if ok, _ := path.Match(pattern, modName); ok {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		if len(filters.side) > 0 && filters.side != core.UniversalSide &&
+			modData.Side != filters.side && modData.Side != core.EmptySide && modData.Side != core.UniversalSide {
+			continue
+		}
+		mods = append(mods, modData)
+	}
+
+	var warnings []string
+	for i, name := range names {
+		if matched[i] {
+			continue
+		}
+		if isGlobPattern(name) {
+			warnings = append(warnings, "no files match the pattern "+name)
+		} else {
+			return nil, nil, fmt.Errorf("can't find %s; please ensure you have run packwiz refresh and use the name of the .pw.toml file (defaults to the project slug)", name)
+		}
+	}
+	sort.SliceStable(mods, func(i, j int) bool {
+		return mods[i].GetFilePath() < mods[j].GetFilePath()
+	})
+	return mods, warnings, nil
+}
+
+// groupByUpdater sorts mods by the updaters that can handle them, returning mods with no known updater separately
+// If sources is not empty, only the given updaters are used
+func groupByUpdater(mods []*core.Mod, sources []string) (map[string][]*core.Mod, []*core.Mod) {
+	filesWithUpdater := make(map[string][]*core.Mod)
+	var unsupported []*core.Mod
+	for _, modData := range mods {
+		updaterFound := false
+		for k := range modData.Update {
+			if _, ok := core.Updaters[k]; !ok {
+				continue
+			}
+			if len(sources) > 0 && !slices.Contains(sources, k) {
+				continue
+			}
+			updaterFound = true
+			filesWithUpdater[k] = append(filesWithUpdater[k], modData)
+		}
+		if !updaterFound {
+			unsupported = append(unsupported, modData)
+		}
+	}
+	return filesWithUpdater, unsupported
+}
+
+// updateCheckResult is a single entry in the report printed by update --check
+type updateCheckResult struct {
+	Name            string `json:"name"`
+	MetaFile        string `json:"metafile"`
+	Updater         string `json:"updater,omitempty"`
+	CurrentFile     string `json:"current-file"`
+	CandidateFile   string `json:"candidate-file,omitempty"`
+	UpdateAvailable bool   `json:"update-available"`
+	NewVersion      string `json:"new-version,omitempty"`
+	ReleaseChannel  string `json:"release-channel,omitempty"`
+	Changelog       string `json:"changelog,omitempty"`
+	ChangelogURL    string `json:"changelog-url,omitempty"`
+	Pinned          bool   `json:"pinned"`
+	Error           string `json:"error,omitempty"`
+
+	check core.UpdateCheck
+}
+
+// updateCheckReport is the report printed by update --check --format json
+type updateCheckReport struct {
+	UpdatesAvailable bool                `json:"updates-available"`
+	Mods             []updateCheckResult `json:"mods"`
+	Warnings         []string            `json:"warnings,omitempty"`
+	// Error is set if the files to check couldn't be selected, in which case no files are checked
+	Error string `json:"error,omitempty"`
+}
+
+func printCheckReport(report updateCheckReport) error {
+	if report.Mods == nil {
+		report.Mods = []updateCheckResult{}
+	}
+	out, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// checkUpdates runs every updater over the selected mods (or all mods, if none are given) without modifying anything,
+// prints a report and returns the exit code to use
+func checkUpdates(pack core.Pack, index core.Index, args []string, filters updateFilters, jsonOutput bool) int {
+	mods, warnings, err := selectMods(index, args, filters)
+	if err != nil {
+		if jsonOutput {
+			err = printCheckReport(updateCheckReport{Error: err.Error()})
+			if err != nil {
+				fmt.Println(err)
+			}
+		} else {
+			fmt.Printf("Failed to select files to check: %v\n", err)
+		}
+		return 1
+	}
+
+	if !jsonOutput {
+		for _, warning := range warnings {
+			_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		fmt.Println("Checking for updates...")
+	}
+	newResult := func(modData *core.Mod, updater string) updateCheckResult {
+		metaFile, err := index.RelIndexPath(modData.GetFilePath())
+		if err != nil {
+			metaFile = modData.GetFilePath()
+		}
+		return updateCheckResult{
+			Name:        modData.Name,
+			MetaFile:    filepath.ToSlash(metaFile),
+			Updater:     updater,
+			CurrentFile: modData.FileName,
+			Pinned:      modData.Pin,
+		}
+	}
+
+	var results []updateCheckResult
+	filesWithUpdater, unsupported := groupByUpdater(mods, filters.sources)
+	for _, modData := range unsupported {
+		if len(filters.sources) > 0 {
+			// Not handled by the selected sources
+			continue
+		}
+		res := newResult(modData, "")
+		res.Error = "a supported update system cannot be found"
+		results = append(results, res)
+	}
+	for k, v := range filesWithUpdater {
+		checks, err := core.Updaters[k].CheckUpdate(v, pack)
+		if err == nil && len(checks) != len(v) {
+			err = fmt.Errorf("invalid update check response")
+		}
+		for i, modData := range v {
+			res := newResult(modData, k)
+			if err != nil {
+				res.Error = err.Error()
+			} else if checks[i].Error != nil {
+				res.Error = checks[i].Error.Error()
+			} else if checks[i].UpdateAvailable {
+				res.UpdateAvailable = true
+				res.CandidateFile = checks[i].NewFileName
+				if viper.GetBool("update.changelog") {
+					loadChangelog(&checks[i])
+				}
+				res.NewVersion = checks[i].NewVersion.Version
+				res.ReleaseChannel = checks[i].ReleaseChannel
+				res.Changelog = checks[i].NewVersion.Body
+				res.ChangelogURL = checks[i].NewVersion.URL
+				res.check = checks[i]
+			}
+			results = append(results, res)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].MetaFile != results[j].MetaFile {
+			return results[i].MetaFile < results[j].MetaFile
+		}
+		return results[i].Updater < results[j].Updater
+	})
+
+	updatesFound := false
+	errorsFound := false
+	for _, res := range results {
+		if res.Error != "" {
+			errorsFound = true
+		} else if res.UpdateAvailable && !res.Pinned {
+			updatesFound = true
+		}
+	}
+
+	if jsonOutput {
+		err := printCheckReport(updateCheckReport{UpdatesAvailable: updatesFound, Mods: results, Warnings: warnings})
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	} else {
+		for _, res := range results {
+			if res.Error != "" {
+				fmt.Printf("%s: failed to check for updates: %s\n", res.Name, res.Error)
+			} else if res.UpdateAvailable {
+				candidate := res.CandidateFile
+				if candidate == "" {
+					candidate = "(unknown file)"
+				}
+				if res.Pinned {
+					fmt.Printf("%s: %s -> %s (%s, pinned)\n", res.Name, res.CurrentFile, candidate, res.Updater)
+				} else {
+					fmt.Printf("%s: %s -> %s (%s)\n", res.Name, res.CurrentFile, candidate, res.Updater)
+				}
+				if viper.GetBool("update.changelog") {
+					printUpdateDetails(res.check)
+				}
+			}
+		}
+		if !updatesFound {
+			fmt.Println("All files are up to date!")
+		}
+	}
+
+	if updatesFound {
+		return 2
+	}
+	if errorsFound {
+		return 1
+	}
+	return 0
+}
+
 func init() {
 	rootCmd.AddCommand(UpdateCmd)
 
 	UpdateCmd.Flags().BoolP("all", "a", false, "Update all external files")
 	_ = viper.BindPFlag("update.all", UpdateCmd.Flags().Lookup("all"))
+	UpdateCmd.Flags().Bool("check", false, "Only check for updates without changing any files; exits with code 2 if updates are available")
+	_ = viper.BindPFlag("update.check", UpdateCmd.Flags().Lookup("check"))
+	UpdateCmd.Flags().String("format", "text", "The format of the report printed by --check (text or json)")
+	_ = viper.BindPFlag("update.format", UpdateCmd.Flags().Lookup("format"))
+	UpdateCmd.Flags().StringSlice("source", nil, "Only use the given update sources (e.g. modrinth, curseforge, github)")
+	_ = viper.BindPFlag("update.source", UpdateCmd.Flags().Lookup("source"))
+	UpdateCmd.Flags().String("side", "", "Only update files installed on the given side (client, server or both)")
+	_ = viper.BindPFlag("update.side", UpdateCmd.Flags().Lookup("side"))
+	UpdateCmd.Flags().StringSlice("exclude", nil, "Names or glob patterns of files to skip when updating")
+	_ = viper.BindPFlag("update.exclude", UpdateCmd.Flags().Lookup("exclude"))
+	UpdateCmd.Flags().Bool("changelog", false, "Show the changelog of the new version of each file (and include it for every file in the --check JSON report)")
+	_ = viper.BindPFlag("update.changelog", UpdateCmd.Flags().Lookup("changelog"))
+	UpdateCmd.Flags().BoolP("interactive", "i", false, "Show the changelog of each update, and choose which updates to apply")
+	_ = viper.BindPFlag("update.interactive", UpdateCmd.Flags().Lookup("interactive"))
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/codecraft3r/packwiz/core"
@@ -20,7 +21,16 @@ var validateCmd = &cobra.Command{
 - Checking that all files referenced in the index exist
 - Ensuring the index is consistent with actual files
 - Validating pack.toml format
-- Reporting any issues found`,
+- Reporting any issues found
+
+With --online, it also checks:
+- Downloading every external file, and verifying that it matches its declared hash
+- Checking through the Modrinth, CurseForge and GitHub APIs that each mod's file is still available and marked as
+  supporting the pack's Minecraft version and loaders
+- Checking that projects haven't been archived or deleted
+
+External files are downloaded into a temporary folder rather than the cache (so that files already in the cache are
+downloaded again), unless --use-cache is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load pack
 		pack, err := core.LoadPack()
@@ -94,6 +104,7 @@ var validateCmd = &cobra.Command{
 		fmt.Println("✓ Checking mod metadata file formats...")
 		validMods := 0
 		invalidMods := 0
+		var mods []*core.Mod
 
 		for fileName, fileData := range index.Files {
 			if fileData.IsMetaFile() {
@@ -123,8 +134,22 @@ var validateCmd = &cobra.Command{
 					continue
 				}
 
-				if mod.Download.URL == "" {
-					fmt.Printf("  ERROR: Mod file %s has empty download URL\n", fileName)
+				if mod.Download.Mode == "" || mod.Download.Mode == core.ModeURL {
+					if mod.Download.URL == "" {
+						fmt.Printf("  ERROR: Mod file %s has empty download URL\n", fileName)
+						issues++
+						invalidMods++
+						continue
+					}
+				} else if dlID, ok := strings.CutPrefix(mod.Download.Mode, "metadata:"); ok {
+					if _, ok := core.MetaDownloaders[dlID]; !ok {
+						fmt.Printf("  ERROR: Mod file %s has unknown metadata download source '%s'\n", fileName, dlID)
+						issues++
+						invalidMods++
+						continue
+					}
+				} else {
+					fmt.Printf("  ERROR: Mod file %s has unknown download mode '%s'\n", fileName, mod.Download.Mode)
 					issues++
 					invalidMods++
 					continue
@@ -149,6 +174,7 @@ var validateCmd = &cobra.Command{
 				}
 
 				validMods++
+				mods = append(mods, &mod)
 			}
 		}
 
@@ -239,6 +265,17 @@ var validateCmd = &cobra.Command{
 			}
 		}
 
+		// 7. Check external files and sources online
+		if viper.GetBool("validate.online") {
+			sort.Slice(mods, func(i, j int) bool {
+				return mods[i].GetDestFilePath() < mods[j].GetDestFilePath()
+			})
+			dlIssues, dlWarnings := validateDownloads(mods)
+			srcIssues, srcWarnings := validateSources(pack, mods)
+			issues += dlIssues + srcIssues
+			warnings += dlWarnings + srcWarnings
+		}
+
 		// Summary
 		fmt.Println()
 		fmt.Println("=== Validation Summary ===")
@@ -266,6 +303,108 @@ var validateCmd = &cobra.Command{
 	},
 }
 
+// validateDownloads downloads every external file of the given mods, checking that it matches its declared hash, and
+// returns the number of errors and warnings found
+func validateDownloads(mods []*core.Mod) (int, int) {
+	issues, warnings := 0, 0
+	fmt.Println("✓ Checking external files can be downloaded...")
+	if !viper.GetBool("validate.use-cache") {
+		// Use an empty cache, so that every file is downloaded (and its hash checked) again
+		tempCache, err := os.MkdirTemp("", "packwiz-validate-")
+		if err != nil {
+			fmt.Printf("  ERROR: Failed to create temporary cache: %v\n", err)
+			return 1, 0
+		}
+		defer func() {
+			_ = os.RemoveAll(tempCache)
+		}()
+		oldCache := viper.GetString("cache.directory")
+		viper.Set("cache.directory", tempCache)
+		defer viper.Set("cache.directory", oldCache)
+	}
+	session, err := core.CreateDownloadSession(mods, []string{})
+	if err != nil {
+		fmt.Printf("  ERROR: Failed to retrieve external files: %v\n", err)
+		return 1, 0
+	}
+	for _, dl := range session.GetManualDownloads() {
+		fmt.Printf("     WARNING: %s (%s) must be downloaded manually from %s\n", dl.Name, dl.FileName, dl.URL)
+		warnings++
+	}
+	failed := 0
+	for dl := range session.StartDownloads() {
+		if dl.Error != nil {
+			fmt.Printf("  ERROR: Download of %s (%s) failed: %v\n", dl.Mod.Name, dl.Mod.FileName, dl.Error)
+			issues++
+			failed++
+			continue
+		}
+		for _, warning := range dl.Warnings {
+			fmt.Printf("     WARNING: %s (%s): %v\n", dl.Mod.Name, dl.Mod.FileName, warning)
+			warnings++
+		}
+		_ = dl.File.Close()
+	}
+	if viper.GetBool("validate.use-cache") {
+		err = session.SaveIndex()
+		if err != nil {
+			fmt.Printf("     WARNING: Failed to save cache index: %v\n", err)
+			warnings++
+		}
+	}
+	if failed == 0 {
+		fmt.Printf("  All %d external files can be downloaded\n", len(mods)-len(session.GetManualDownloads()))
+	}
+	return issues, warnings
+}
+
+// validateSources checks the given mods against the APIs of their sources, and returns the number of errors and
+// warnings found
+func validateSources(pack core.Pack, mods []*core.Mod) (int, int) {
+	issues, warnings := 0, 0
+	fmt.Println("✓ Checking mods against their sources...")
+	sourceMods := make(map[string][]*core.Mod)
+	var sources []string
+	for _, mod := range mods {
+		name, _, ok := core.GetModValidator(mod)
+		if !ok {
+			continue
+		}
+		if _, ok := sourceMods[name]; !ok {
+			sources = append(sources, name)
+		}
+		sourceMods[name] = append(sourceMods[name], mod)
+	}
+	sort.Strings(sources)
+	sourceIssues := 0
+	for _, name := range sources {
+		results, err := core.ModValidators[name].ValidateMods(sourceMods[name], pack)
+		if err != nil {
+			fmt.Printf("  ERROR: Failed to check mods from %s: %v\n", name, err)
+			issues++
+			sourceIssues++
+			continue
+		}
+		for i, result := range results {
+			mod := sourceMods[name][i]
+			for _, v := range result.Errors {
+				fmt.Printf("  ERROR: %s (%s): %s\n", mod.Name, name, v)
+				issues++
+				sourceIssues++
+			}
+			for _, v := range result.Warnings {
+				fmt.Printf("     WARNING: %s (%s): %s\n", mod.Name, name, v)
+				warnings++
+				sourceIssues++
+			}
+		}
+	}
+	if sourceIssues == 0 {
+		fmt.Println("  All mods are available and compatible with the pack")
+	}
+	return issues, warnings
+}
+
 // calculateIndexHash calculates the hash of the index file
 func calculateIndexHash(pack core.Pack) (string, error) {
 	packFilePath := "pack.toml" // Default
@@ -305,4 +444,9 @@ func calculateIndexHash(pack core.Pack) (string, error) {
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().Bool("online", false, "Also download external files and check mods against the APIs of their sources")
+	_ = viper.BindPFlag("validate.online", validateCmd.Flags().Lookup("online"))
+	validateCmd.Flags().Bool("use-cache", false, "Use files in the download cache instead of downloading every file again (with --online)")
+	_ = viper.BindPFlag("validate.use-cache", validateCmd.Flags().Lookup("use-cache"))
 }
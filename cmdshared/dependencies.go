@@ -0,0 +1,108 @@
+package cmdshared
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+// LoadInstalledMods loads every metadata file in the index, skipping (and warning about) any that can't be read
+func LoadInstalledMods(index *core.Index) []*core.Mod {
+	mods, err := index.LoadAllMods()
+	if err == nil {
+		return mods
+	}
+	fmt.Printf("Warning: Some mod files may be missing or corrupted: %v\n", err)
+	mods = mods[:0]
+	for fileName, fileData := range index.Files {
+		if !fileData.IsMetaFile() {
+			continue
+		}
+		mod, err := core.LoadMod(index.ResolveIndexPath(fileName))
+		if err != nil {
+			fmt.Printf("Warning: Skipping mod file %s: %v\n", fileName, err)
+			continue
+		}
+		mods = append(mods, &mod)
+	}
+	return mods
+}
+
+// AddDependencies finds the required dependencies of newly added mods that aren't installed yet, asks whether they
+// should be added, and stages them in the transaction. Optional dependencies are suggested, and incompatibilities
+// with installed mods are reported as warnings.
+// This should be called before the new mods are added to the index.
+func AddDependencies(newMods []*core.Mod, pack core.Pack, index *core.Index, tx *core.Transaction) error {
+	newNames := make(map[string]bool)
+	hasRequired := false
+	for _, m := range newMods {
+		newNames[m.MetaName()] = true
+		for _, dep := range m.Dependencies {
+			if dep.Type == core.DependencyRequired {
+				hasRequired = true
+			}
+		}
+	}
+	// Mods that are being replaced by the new mods aren't considered to be installed
+	var installed []*core.Mod
+	for _, m := range LoadInstalledMods(index) {
+		if !newNames[m.MetaName()] {
+			installed = append(installed, m)
+		}
+	}
+
+	var added []*core.Mod
+	if hasRequired {
+		fmt.Println("Finding dependencies...")
+		deps, err := core.ResolveDependencies(newMods, installed, pack)
+		if err != nil {
+			fmt.Printf("Error retrieving dependency data: %v\n", err)
+		}
+		if len(deps) > 0 {
+			fmt.Println("Dependencies found:")
+			for _, v := range deps {
+				fmt.Println(v.Name)
+			}
+
+			if PromptYesNo("Would you like to add them? [Y/n]: ") {
+				for _, v := range deps {
+					format, hash, err := tx.WriteMod(v)
+					if err != nil {
+						return err
+					}
+					err = index.RefreshFileWithHash(v.GetFilePath(), format, hash, true)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Dependency \"%s\" successfully added! (%s)\n", v.Name, v.FileName)
+					newNames[v.MetaName()] = true
+				}
+				added = deps
+			}
+		} else if err == nil {
+			fmt.Println("All dependencies are already added!")
+		}
+	}
+
+	all := append(append(append([]*core.Mod(nil), installed...), newMods...), added...)
+	graph := core.NewDependencyGraph(all)
+	for _, v := range graph.Incompatibilities() {
+		if newNames[v.Mod.MetaName()] || newNames[v.With.MetaName()] {
+			fmt.Printf("Warning: %s is incompatible with %s\n", v.Mod.Name, v.With.Name)
+		}
+	}
+
+	var suggestions []string
+	for _, m := range newMods {
+		for _, e := range graph.Dependencies(m) {
+			if e.Mod == nil && e.Dependency.Type == core.DependencyOptional {
+				suggestions = append(suggestions, e.Dependency.String())
+			}
+		}
+	}
+	if len(suggestions) > 0 {
+		fmt.Println("Optional dependencies (not added): " + strings.Join(suggestions, ", "))
+	}
+	return nil
+}
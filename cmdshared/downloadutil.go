@@ -30,11 +30,46 @@ func ListManualDownloads(session core.DownloadSession) {
 	}
 }
 
+// ExportWriter creates files in an export, such as a zip file (*zip.Writer) or a folder. If a returned writer is also
+// an io.Closer, it is closed once the file has been written.
+type ExportWriter interface {
+	Create(name string) (io.Writer, error)
+}
+
+// WriteToExport writes the contents of src to a file in an export
+func WriteToExport(exp ExportWriter, name string, src io.Reader) error {
+	dest, err := exp.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	_, err = io.Copy(dest, src)
+	if closer, ok := dest.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddToZip saves a downloaded file into the given folder in the zip
 func AddToZip(dl core.CompletedDownload, exp *zip.Writer, dir string, index *core.Index) bool {
+	if !AddToExport(dl, exp, dir, index) {
+		return false
+	}
+	fmt.Printf("%s (%s) added to zip\n", dl.Mod.Name, dl.Mod.FileName)
+	return true
+}
+
+// AddToExport saves a downloaded file into the given folder in an export, printing any errors and warnings
+func AddToExport(dl core.CompletedDownload, exp ExportWriter, dir string, index *core.Index) bool {
 	if dl.Error != nil {
 		fmt.Printf("Download of %s (%s) failed: %v\n", dl.Mod.Name, dl.Mod.FileName, dl.Error)
 		return false
 	}
+	defer dl.File.Close()
 	for _, warning := range dl.Warnings {
 		fmt.Printf("Warning for %s (%s): %v\n", dl.Mod.Name, dl.Mod.FileName, warning)
 	}
@@ -44,55 +79,42 @@ func AddToZip(dl core.CompletedDownload, exp *zip.Writer, dir string, index *cor
 		fmt.Printf("Error resolving external file: %v\n", err)
 		return false
 	}
-	modFile, err := exp.Create(path.Join(dir, p))
+	err = WriteToExport(exp, path.Join(dir, p), dl.File)
 	if err != nil {
-		fmt.Printf("Error creating metadata file %s: %v\n", p, err)
+		fmt.Printf("Error exporting %s (%s): %v\n", dl.Mod.Name, dl.Mod.FileName, err)
 		return false
 	}
-	_, err = io.Copy(modFile, dl.File)
-	if err != nil {
-		fmt.Printf("Error copying file %s: %v\n", p, err)
-		return false
-	}
-	err = dl.File.Close()
-	if err != nil {
-		fmt.Printf("Error closing file %s: %v\n", p, err)
-		return false
-	}
-
-	fmt.Printf("%s (%s) added to zip\n", dl.Mod.Name, dl.Mod.FileName)
 	return true
 }
 
 // AddNonMetafileOverrides saves all non-metadata files into an overrides folder in the zip
 func AddNonMetafileOverrides(index *core.Index, exp *zip.Writer) {
+	AddNonMetafileFiles(index, exp, "overrides")
+}
+
+// AddNonMetafileFiles saves all non-metadata files into the given folder in an export, returning false if any of them
+// couldn't be saved
+func AddNonMetafileFiles(index *core.Index, exp ExportWriter, dir string) bool {
+	ok := true
 	for p, v := range index.Files {
 		if !v.IsMetaFile() {
-			file, err := exp.Create(path.Join("overrides", p))
-			if err != nil {
-				fmt.Printf("Error creating file: %s\n", err.Error())
-				// TODO: exit(1)?
-				continue
-			}
 			// Attempt to read the file from disk, without checking hashes (assumed to have no errors)
 			src, err := os.Open(index.ResolveIndexPath(p))
 			if err != nil {
-				_ = src.Close()
 				fmt.Printf("Error reading file: %s\n", err.Error())
-				// TODO: exit(1)?
+				ok = false
 				continue
 			}
-			_, err = io.Copy(file, src)
+			err = WriteToExport(exp, path.Join(dir, p), src)
+			_ = src.Close()
 			if err != nil {
-				_ = src.Close()
 				fmt.Printf("Error copying file: %s\n", err.Error())
-				// TODO: exit(1)?
+				ok = false
 				continue
 			}
-
-			_ = src.Close()
 		}
 	}
+	return ok
 }
 
 func PrintDisclaimer(isCf bool) {
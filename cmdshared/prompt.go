@@ -8,13 +8,16 @@ import (
 	"strings"
 )
 
+// stdinReader is shared between prompts, so that input buffered by one prompt isn't lost to the next
+var stdinReader = bufio.NewReader(os.Stdin)
+
 func PromptYesNo(prompt string) bool {
 	fmt.Print(prompt)
 	if viper.GetBool("non-interactive") {
 		fmt.Println("Y (non-interactive mode)")
 		return true
 	}
-	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer, err := stdinReader.ReadString('\n')
 	if err != nil {
 		fmt.Printf("Failed to prompt user: %v\n", err)
 		os.Exit(1)
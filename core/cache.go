@@ -0,0 +1,222 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheEntry is a file stored in the download cache
+type CacheEntry struct {
+	// Hashes contains every hash of the file stored in the cache index
+	Hashes   map[string]string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	// Missing is set if the file has been deleted from the cache folder
+	Missing bool
+	idx     int
+}
+
+// Path returns the folder the cache is stored in
+func (c *CacheIndex) Path() string {
+	return c.cachePath
+}
+
+// Entries returns every file in the cache index, reading the size and last used time of each file
+func (c *CacheIndex) Entries() []CacheEntry {
+	hashList := c.Hashes[cacheHashFormat]
+	entries := make([]CacheEntry, 0, len(hashList))
+	for i, hash := range hashList {
+		if hash == "" {
+			continue
+		}
+		entry := CacheEntry{
+			Hashes: c.getHashesMap(i),
+			Path:   filepath.Join(c.cachePath, hash[:2], hash[2:]),
+			idx:    i,
+		}
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			entry.Missing = true
+		} else {
+			entry.Size = info.Size()
+			entry.LastUsed = info.ModTime()
+		}
+		if lastUsed, ok := c.LastUsed[hash]; ok {
+			entry.LastUsed = time.Unix(lastUsed, 0)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Verify rehashes a cached file with every hash format stored for it, returning an error if any hash doesn't match
+func (c *CacheIndex) Verify(entry CacheEntry) error {
+	file, err := os.Open(entry.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	formats := make([]string, 0, len(entry.Hashes))
+	for format := range entry.Hashes {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	hashers := make([]HashStringer, len(formats))
+	writers := make([]io.Writer, len(formats))
+	for i, format := range formats {
+		hashers[i], err = GetHashImpl(format)
+		if err != nil {
+			return err
+		}
+		writers[i] = hashers[i]
+	}
+	_, err = io.Copy(io.MultiWriter(writers...), file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var mismatched []string
+	for i, format := range formats {
+		if !strings.EqualFold(hashers[i].HashToString(hashers[i].Sum(nil)), entry.Hashes[format]) {
+			mismatched = append(mismatched, format)
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("%s hash does not match the cache index", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// Remove deletes files from the cache and removes them from the index
+func (c *CacheIndex) Remove(entries []CacheEntry) error {
+	// Remove the highest indexes first, so the remaining indexes stay valid
+	sorted := slices.Clone(entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].idx > sorted[j].idx
+	})
+	for _, entry := range sorted {
+		err := os.Remove(entry.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		c.removeEntry(entry.idx)
+	}
+	return nil
+}
+
+// removeEntry removes the file at the given index from every hash list
+func (c *CacheIndex) removeEntry(idx int) {
+	hashList := c.Hashes[cacheHashFormat]
+	if idx < len(hashList) {
+		delete(c.LastUsed, hashList[idx])
+	}
+	for hashFormat, hashList := range c.Hashes {
+		if idx < len(hashList) {
+			c.Hashes[hashFormat] = slices.Delete(hashList, idx, idx+1)
+		}
+	}
+	if c.nextHashIdx > idx {
+		c.nextHashIdx--
+	}
+}
+
+// FindCacheHash looks up a file by any hash, returning its cache hash (as stored in CacheEntry.Hashes), or an empty
+// string if it isn't in the cache. Files without a hash of the given format are rehashed to check them, so this can
+// be slow when using a hash format that isn't stored in the index.
+func (c *CacheIndex) FindCacheHash(hashFormat string, hash string) (string, error) {
+	handle, err := c.GetHandleFromHashForce(hashFormat, hash)
+	if err != nil || handle == nil {
+		return "", err
+	}
+	return handle.Hashes[cacheHashFormat], nil
+}
+
+// GetCacheHash returns the hash of a cache entry that identifies it
+func (e CacheEntry) GetCacheHash() string {
+	return e.Hashes[cacheHashFormat]
+}
+
+// CacheImportHashFormats are the hash formats calculated for imported files; this includes every format used by
+// metadata files and exports, so imported files never need to be rehashed
+var CacheImportHashFormats = []string{cacheHashFormat, "sha1", "sha512", "md5", "murmur2", "length-bytes"}
+
+// Import adds a file to the cache, calculating every hash in CacheImportHashFormats. It returns the hashes of the file
+// and whether it was already in the cache (in which case any missing hashes are added to the index).
+func (c *CacheIndex) Import(src io.Reader) (map[string]string, bool, error) {
+	tempFile, err := os.CreateTemp(filepath.Join(c.cachePath, "temp"), "import-tmp")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create temporary file for import: %w", err)
+	}
+
+	hashers := make([]HashStringer, len(CacheImportHashFormats))
+	writers := make([]io.Writer, len(CacheImportHashFormats)+1)
+	for i, format := range CacheImportHashFormats {
+		hashers[i], err = GetHashImpl(format)
+		if err != nil {
+			_ = tempFile.Close()
+			_ = os.Remove(tempFile.Name())
+			return nil, false, err
+		}
+		writers[i] = hashers[i]
+	}
+	writers[len(hashers)] = tempFile
+	_, err = io.Copy(io.MultiWriter(writers...), src)
+	if err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	hashes := make(map[string]string, len(hashers))
+	for i, format := range CacheImportHashFormats {
+		hashes[format] = hashers[i].HashToString(hashers[i].Sum(nil))
+	}
+	handle, exists := c.NewHandleFromHashes(hashes)
+	if exists {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	} else {
+		file, err := handle.CreateFromTemp(tempFile)
+		if err != nil {
+			_ = os.Remove(tempFile.Name())
+			return nil, false, fmt.Errorf("failed to move file %s to cache: %w", handle.Path(), err)
+		}
+		_ = file.Close()
+	}
+	_ = handle.UpdateIndex()
+	handle.markUsed()
+	return hashes, exists, nil
+}
+
+// AddCachedFile records the hashes of a file that is already in the cache folder, such as one downloaded by a download
+// session with its own copy of the index, and marks it as used
+func (c *CacheIndex) AddCachedFile(hashes map[string]string) ([]error, error) {
+	if _, ok := hashes[cacheHashFormat]; !ok {
+		return nil, fmt.Errorf("missing %s hash", cacheHashFormat)
+	}
+	handle, _ := c.NewHandleFromHashes(hashes)
+	warnings := handle.UpdateIndex()
+	handle.markUsed()
+	return warnings, nil
+}
+
+// GetHashFormats returns the hash formats stored in the index, with the number of files that have each of them
+func (c *CacheIndex) GetHashFormats() map[string]int {
+	counts := make(map[string]int)
+	for format, hashList := range c.Hashes {
+		for _, hash := range hashList {
+			if hash != "" {
+				counts[format]++
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GetChangelogs returns the changelogs of the versions of a mod between two versions of its metadata (see
+// ChangelogProvider), from the first update source they have in common that has a changelog provider. If there is no
+// such source, no changelogs are returned.
+func GetChangelogs(oldMod *Mod, newMod *Mod) ([]Changelog, error) {
+	keys := make([]string, 0, len(newMod.Update))
+	for k := range newMod.Update {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		provider, ok := ChangelogProviders[k]
+		if !ok {
+			continue
+		}
+		if _, ok := oldMod.Update[k]; !ok {
+			continue
+		}
+		return provider.GetChangelogs(oldMod, newMod)
+	}
+	return nil, nil
+}
+
+var (
+	htmlTagRegex       = regexp.MustCompile(`(?s)<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	htmlHrefRegex      = regexp.MustCompile(`(?i)href\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	htmlSkipRegex      = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style)[^>]*>.*?</(script|style)>`)
+	htmlSpaceRegex     = regexp.MustCompile(`[ \t\r\n]+`)
+	blankLinesRegex    = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+	trailingSpaceRegex = regexp.MustCompile(`[ \t]+\n`)
+	listItemRegex      = regexp.MustCompile(`(?m)^( *- ) +`)
+)
+
+// HTMLToMarkdown converts a changelog in HTML (as used by CurseForge) into Markdown, keeping paragraphs, lists,
+// headings, emphasis and links, and removing any other formatting
+func HTMLToMarkdown(s string) string {
+	s = htmlSkipRegex.ReplaceAllString(s, "")
+	// Whitespace in HTML is insignificant, except in preformatted text which is rare enough in changelogs to be ignored
+	s = htmlSpaceRegex.ReplaceAllString(s, " ")
+
+	var sb strings.Builder
+	var links []string
+	listDepth := 0
+	last := 0
+	for _, m := range htmlTagRegex.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(html.UnescapeString(s[last:m[0]]))
+		last = m[1]
+		closing := s[m[2]:m[3]] == "/"
+		tag := strings.ToLower(s[m[4]:m[5]])
+		attrs := s[m[6]:m[7]]
+		switch tag {
+		case "br":
+			sb.WriteString("\n")
+		case "p", "div", "table", "tr", "blockquote", "pre":
+			sb.WriteString("\n\n")
+		case "hr":
+			sb.WriteString("\n\n---\n\n")
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			sb.WriteString("\n\n")
+			if !closing {
+				sb.WriteString(strings.Repeat("#", int(tag[1]-'0')) + " ")
+			}
+		case "ul", "ol":
+			if closing {
+				listDepth = max(listDepth-1, 0)
+			} else {
+				listDepth++
+			}
+			if listDepth == 0 {
+				sb.WriteString("\n\n")
+			}
+		case "li":
+			if !closing {
+				sb.WriteString("\n" + strings.Repeat("  ", max(listDepth-1, 0)) + "- ")
+			}
+		case "strong", "b":
+			sb.WriteString("**")
+		case "em", "i":
+			sb.WriteString("_")
+		case "code":
+			sb.WriteString("`")
+		case "a":
+			if closing {
+				if len(links) > 0 {
+					if href := links[len(links)-1]; href != "" {
+						sb.WriteString("](" + href + ")")
+					}
+					links = links[:len(links)-1]
+				}
+			} else {
+				href := ""
+				if hm := htmlHrefRegex.FindStringSubmatch(attrs); hm != nil {
+					href = html.UnescapeString(strings.Trim(hm[1], `"'`))
+				}
+				links = append(links, href)
+				if href != "" {
+					sb.WriteString("[")
+				}
+			}
+		}
+	}
+	sb.WriteString(html.UnescapeString(s[last:]))
+
+	out := strings.ReplaceAll(sb.String(), "\u00a0", " ")
+	lines := strings.Split(out, "\n")
+	for i, l := range lines {
+		// Remove the indentation left over from the HTML source, but keep the indentation of nested list items
+		trimmed := strings.TrimLeft(l, " ")
+		if !strings.HasPrefix(trimmed, "- ") {
+			lines[i] = trimmed
+		}
+	}
+	out = strings.Join(lines, "\n")
+	out = listItemRegex.ReplaceAllString(out, "$1")
+	out = trailingSpaceRegex.ReplaceAllString(out, "\n")
+	out = blankLinesRegex.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out)
+}
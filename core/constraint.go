@@ -0,0 +1,306 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/unascribed/FlexVer/go/flexver"
+)
+
+// ModConstraint limits which versions of a mod updaters are allowed to choose
+type ModConstraint struct {
+	// Version is a version range, such as ">=4.0, <5.0", "4.x" or "~1.2"; ranges are checked using semver if the
+	// version number is valid semver, and FlexVer otherwise
+	Version string `toml:"version,omitempty"`
+	// Deny lists version numbers, version IDs, file IDs, release tags or file names that must never be chosen
+	Deny []string `toml:"deny,omitempty"`
+}
+
+// IsEmpty returns true if the constraint doesn't restrict any versions
+func (c *ModConstraint) IsEmpty() bool {
+	return c == nil || (c.Version == "" && len(c.Deny) == 0)
+}
+
+// String returns a short human-readable description of the constraint
+func (c *ModConstraint) String() string {
+	if c.IsEmpty() {
+		return ""
+	}
+	var parts []string
+	if c.Version != "" {
+		parts = append(parts, c.Version)
+	}
+	if len(c.Deny) > 0 {
+		parts = append(parts, "deny "+strings.Join(c.Deny, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Allows checks whether a version can be chosen, given its mod version number (see ModVersion) and any IDs or names
+// that identify it for the deny list
+// A nil constraint allows every version.
+func (c *ModConstraint) Allows(versionNumber string, ids ...string) bool {
+	if c.IsEmpty() {
+		return true
+	}
+	for _, denied := range c.Deny {
+		if denied == versionNumber || slices.Contains(ids, denied) {
+			return false
+		}
+	}
+	if c.Version == "" {
+		return true
+	}
+
+	if constraint, err := semver.NewConstraint(c.Version); err == nil {
+		if version, err := semver.NewVersion(versionNumber); err == nil {
+			return constraint.Check(version)
+		}
+	}
+	ranges, err := parseFlexVerRanges(c.Version)
+	if err != nil {
+		// Invalid constraints are rejected by ValidateVersionConstraint; don't allow anything if one gets through
+		return false
+	}
+	for _, r := range ranges {
+		if r.matches(versionNumber) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateVersionConstraint checks that a version range can be parsed; an empty string is valid (no range)
+func ValidateVersionConstraint(constraint string) error {
+	if constraint == "" {
+		return nil
+	}
+	_, err := parseFlexVerRanges(constraint)
+	return err
+}
+
+var (
+	mcVersionRegex      = regexp.MustCompile(`^1\.\d+(\.\d+)?$`)
+	mcSnapshotRegex     = regexp.MustCompile(`^\d\dw\d\d[a-z]$`)
+	preReleaseRegex     = regexp.MustCompile(`(?i)^(alpha|beta|rc|pre)`)
+	versionSeparatorSet = " \t-_+[](),/"
+)
+
+// ModVersion extracts the version number of a mod from a version number, file display name or release tag given by a
+// source, such as "mc1.20.1-0.5.3", "0.92.2+1.20.1", "[1.20.1] JEI 15.2.0" or "v1.2.3", dropping Minecraft versions,
+// loader names and other text around it. mcVersions are the Minecraft versions the source lists for the version, if
+// any; otherwise Minecraft versions are guessed from the way they are usually written.
+func ModVersion(version string, mcVersions ...string) string {
+	trimmed := strings.TrimSpace(version)
+	for _, ext := range []string{".jar", ".zip"} {
+		trimmed = strings.TrimSuffix(trimmed, ext)
+	}
+
+	type versionToken struct {
+		text string
+		// mc is set if the token is certainly a Minecraft version
+		mc bool
+		// next is the token following this one, if separated by '-'
+		next string
+	}
+	var tokens []versionToken
+	bracketed, afterPlus := false, false
+	// prev is the index of the previous token, if it was a version followed by '-'
+	prev := -1
+	for len(trimmed) > 0 {
+		end := strings.IndexAny(trimmed, versionSeparatorSet)
+		if end == 0 {
+			switch trimmed[0] {
+			case '[', '(':
+				bracketed = true
+			case ']', ')':
+				bracketed = false
+			case '+':
+				afterPlus = true
+			case ' ', '\t':
+				afterPlus = false
+			}
+			if trimmed[0] != '-' {
+				prev = -1
+			}
+			trimmed = trimmed[1:]
+			continue
+		}
+		if end < 0 {
+			end = len(trimmed)
+		}
+		text := trimmed[:end]
+		trimmed = trimmed[end:]
+		if prev >= 0 {
+			tokens[prev].next = text
+		}
+
+		mc := false
+		if len(text) > 2 && strings.EqualFold(text[:2], "mc") {
+			text, mc = text[2:], true
+		} else if len(text) > 1 && (text[0] == 'v' || text[0] == 'V') {
+			text = text[1:]
+		}
+		if text[0] < '0' || text[0] > '9' {
+			prev = -1
+			continue
+		}
+		mc = mc || slices.Contains(mcVersions, text) || mcSnapshotRegex.MatchString(text) ||
+			((bracketed || afterPlus) && mcVersionRegex.MatchString(text))
+		tokens = append(tokens, versionToken{text: text, mc: mc})
+		prev = len(tokens) - 1
+	}
+
+	var candidates []versionToken
+	for _, t := range tokens {
+		if !t.mc {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		if len(tokens) > 0 {
+			// Only Minecraft versions; use the first rather than nothing
+			return tokens[0].text
+		}
+		return trimVersionPrefix(strings.TrimSpace(version))
+	}
+	// Without Minecraft versions from the source, anything that looks like one is assumed to be one, unless it's the
+	// only version; Minecraft versions are usually written before mod versions, so the last is chosen if they all do
+	chosen := candidates[len(candidates)-1]
+	if len(mcVersions) > 0 {
+		chosen = candidates[0]
+	}
+	for _, t := range candidates {
+		if !mcVersionRegex.MatchString(t.text) {
+			chosen = t
+			break
+		}
+	}
+	if preReleaseRegex.MatchString(chosen.next) {
+		return chosen.text + "-" + chosen.next
+	}
+	return chosen.text
+}
+
+// trimVersionPrefix removes any text before the first digit, e.g. "v1.2" or "Sodium 0.5.3"
+func trimVersionPrefix(version string) string {
+	idx := strings.IndexAny(version, "0123456789")
+	if idx < 0 {
+		return version
+	}
+	return version[idx:]
+}
+
+type flexVerTerm struct {
+	op      string
+	version string
+	// prefix is set for wildcard, ~ and ^ terms; versions must start with these components
+	prefix string
+}
+
+// flexVerRange is a set of terms that must all match
+type flexVerRange []flexVerTerm
+
+// parseFlexVerRanges parses a version range using similar syntax to semver constraints (terms separated by commas or
+// spaces, ranges separated by ||), so it can be applied to versions that aren't valid semver
+func parseFlexVerRanges(constraint string) ([]flexVerRange, error) {
+	var ranges []flexVerRange
+	for _, rangeStr := range strings.Split(constraint, "||") {
+		var r flexVerRange
+		fields := strings.FieldsFunc(rangeStr, func(c rune) bool {
+			return c == ',' || c == ' ' || c == '\t'
+		})
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			op := ""
+			for _, candidate := range []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"} {
+				if strings.HasPrefix(field, candidate) {
+					op = candidate
+					break
+				}
+			}
+			version := strings.TrimPrefix(field, op)
+			if version == "" && i+1 < len(fields) {
+				// Operator separated from the version by a space
+				i++
+				version = fields[i]
+			}
+			version = trimVersionPrefix(version)
+			if version == "" {
+				return nil, fmt.Errorf("invalid version constraint '%s': missing version after '%s'", constraint, op)
+			}
+
+			term := flexVerTerm{op: op, version: version}
+			components := strings.Split(version, ".")
+			if wildcardIdx := slices.IndexFunc(components, isWildcard); wildcardIdx >= 0 {
+				if op != "" && op != "=" && op != "==" {
+					return nil, fmt.Errorf("invalid version constraint '%s': wildcards can't be used with '%s'", constraint, op)
+				}
+				term.op = "="
+				term.prefix = strings.Join(components[:wildcardIdx], ".")
+				term.version = ""
+			} else if op == "~" {
+				term.prefix = strings.Join(components[:min(2, len(components))], ".")
+			} else if op == "^" {
+				term.prefix = components[0]
+			}
+			r = append(r, term)
+		}
+		if len(r) == 0 {
+			return nil, errors.New("invalid version constraint '" + constraint + "': empty range")
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func isWildcard(component string) bool {
+	return component == "*" || component == "x" || component == "X"
+}
+
+func (r flexVerRange) matches(version string) bool {
+	for _, term := range r {
+		if !term.matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t flexVerTerm) matches(version string) bool {
+	if t.prefix != "" && !hasVersionPrefix(version, t.prefix) {
+		return false
+	}
+	if t.version == "" {
+		// Wildcard; only the prefix matters
+		return true
+	}
+	cmp := flexver.Compare(version, t.version)
+	switch t.op {
+	case ">=", "~", "^":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// hasVersionPrefix checks if a version starts with the given components, e.g. 1.2.3 starts with 1.2 but 1.20 doesn't
+func hasVersionPrefix(version string, prefix string) bool {
+	if !strings.HasPrefix(version, prefix) {
+		return false
+	}
+	rest := version[len(prefix):]
+	return rest == "" || !strings.ContainsAny(rest[:1], "0123456789")
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		mcVersions []string
+		want       string
+	}{
+		// Modrinth version numbers
+		{"mc1.20.1-0.5.3", []string{"1.20.1"}, "0.5.3"},
+		{"mc1.21-0.5.11", []string{"1.21"}, "0.5.11"},
+		{"mc1.20.1-0.11.2", []string{"1.20.1"}, "0.11.2"},
+		{"0.92.2+1.20.1", []string{"1.20.1"}, "0.92.2"},
+		{"1.6.11+1.20.1", []string{"1.20.1"}, "1.6.11"},
+		{"11.1.118+fabric", []string{"1.20.1"}, "11.1.118"},
+		{"0.5.1-f-build.1417+mc1.20.1", []string{"1.20.1"}, "0.5.1"},
+		{"1.6.2-mc1.20.1", []string{"1.20.1"}, "1.6.2"},
+		{"7.2.2", []string{"1.20.1"}, "7.2.2"},
+		{"1.20.1", []string{"1.20.1"}, "1.20.1"},
+		// CurseForge display names and file names
+		{"[1.20.1] JEI 15.2.0", []string{"1.20.1", "Forge"}, "15.2.0"},
+		{"jei-1.20.1-forge-15.2.0.27.jar", []string{"1.20.1", "Forge"}, "15.2.0.27"},
+		{"create-1.20.1-0.5.1.f.jar", []string{"1.20.1", "Forge"}, "0.5.1.f"},
+		{"Botania 1.20.1-443", []string{"1.20.1", "Forge"}, "443"},
+		{"appleskin-forge-mc1.20.1-2.5.1.jar", []string{"1.20.1", "Forge"}, "2.5.1"},
+		{"Xaero's Minimap 23.9.7 for Forge 1.20", []string{"1.20", "Forge"}, "23.9.7"},
+		{"Sodium 0.5.3", []string{"1.20.1"}, "0.5.3"},
+		// GitHub release tags, without Minecraft versions
+		{"v1.2.3", nil, "1.2.3"},
+		{"mc1.20.1-0.5.3", nil, "0.5.3"},
+		{"1.20.1-4.0.3", nil, "4.0.3"},
+		{"1.20.1-1.2.3", nil, "1.2.3"},
+		{"1.6.11+1.20.1", nil, "1.6.11"},
+		{"v0.5.0-beta.2", nil, "0.5.0-beta.2"},
+		{"release-2.1", nil, "2.1"},
+		{"23w45a-1.0", nil, "1.0"},
+		{"latest", nil, "latest"},
+	}
+	for _, tt := range tests {
+		if got := ModVersion(tt.version, tt.mcVersions...); got != tt.want {
+			t.Errorf("ModVersion(%q, %q) = %q, want %q", tt.version, tt.mcVersions, got, tt.want)
+		}
+	}
+}
+
+func TestModConstraintAllows(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint *ModConstraint
+		version    string
+		mcVersions []string
+		ids        []string
+		want       bool
+	}{
+		{"nil constraint", nil, "mc1.20.1-0.5.3", []string{"1.20.1"}, nil, true},
+		{"modrinth wildcard", &ModConstraint{Version: "0.5.x"}, "mc1.20.1-0.5.3", []string{"1.20.1"}, nil, true},
+		{"modrinth wildcard mismatch", &ModConstraint{Version: "0.4.x"}, "mc1.20.1-0.5.3", []string{"1.20.1"}, nil, false},
+		{"modrinth range", &ModConstraint{Version: ">=0.5, <0.6"}, "mc1.20.1-0.5.11", []string{"1.20.1"}, nil, true},
+		{"modrinth build metadata", &ModConstraint{Version: "<0.93"}, "0.92.2+1.20.1", []string{"1.20.1"}, nil, true},
+		{"curseforge display name", &ModConstraint{Version: "15.x"}, "[1.20.1] JEI 15.2.0", []string{"1.20.1"}, nil, true},
+		{"curseforge display name mismatch", &ModConstraint{Version: "4.x"}, "[1.20.1] JEI 15.2.0", []string{"1.20.1"}, nil, false},
+		{"curseforge flexver", &ModConstraint{Version: ">=0.5.1"}, "create-1.20.1-0.5.1.f.jar", []string{"1.20.1"}, nil, true},
+		{"curseforge flexver upper bound", &ModConstraint{Version: "<0.5.1"}, "create-1.20.1-0.5.1.f.jar", []string{"1.20.1"}, nil, false},
+		{"github tag caret", &ModConstraint{Version: "^1.2"}, "v1.3.0", nil, nil, true},
+		{"github tag tilde", &ModConstraint{Version: "~1.2"}, "v1.2.9", nil, nil, true},
+		{"github tag tilde mismatch", &ModConstraint{Version: "~1.2"}, "v1.3.0", nil, nil, false},
+		{"github forge tag", &ModConstraint{Version: "4.x"}, "1.20.1-4.0.3", nil, nil, true},
+		{"deny version", &ModConstraint{Deny: []string{"0.5.3"}}, "mc1.20.1-0.5.3", []string{"1.20.1"}, nil, false},
+		{"deny id", &ModConstraint{Deny: []string{"4613287"}}, "[1.20.1] JEI 15.2.0", []string{"1.20.1"}, []string{"4613287"}, false},
+		{"deny other id", &ModConstraint{Deny: []string{"4613286"}}, "[1.20.1] JEI 15.2.0", []string{"1.20.1"}, []string{"4613287"}, true},
+		{"range and deny", &ModConstraint{Version: "0.5.x", Deny: []string{"0.5.3"}}, "mc1.20.1-0.5.4", []string{"1.20.1"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.constraint.Allows(ModVersion(tt.version, tt.mcVersions...), tt.ids...); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlexVerRanges(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       []flexVerRange
+		wantErr    bool
+	}{
+		{"4.x", []flexVerRange{{{op: "=", prefix: "4"}}}, false},
+		{"1.20.*", []flexVerRange{{{op: "=", prefix: "1.20"}}}, false},
+		{">=0.5, <0.6", []flexVerRange{{{op: ">=", version: "0.5"}, {op: "<", version: "0.6"}}}, false},
+		{">= 0.5 < 0.6", []flexVerRange{{{op: ">=", version: "0.5"}, {op: "<", version: "0.6"}}}, false},
+		{"~1.2.3", []flexVerRange{{{op: "~", version: "1.2.3", prefix: "1.2"}}}, false},
+		{"^15.2", []flexVerRange{{{op: "^", version: "15.2", prefix: "15"}}}, false},
+		{"v1.2", []flexVerRange{{{op: "", version: "1.2"}}}, false},
+		{"0.5.x || >=1.0", []flexVerRange{{{op: "=", prefix: "0.5"}}, {{op: ">=", version: "1.0"}}}, false},
+		{"", nil, true},
+		{">=", nil, true},
+		{">=1.x", nil, true},
+		{"1.0 ||", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFlexVerRanges(tt.constraint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFlexVerRanges(%q) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFlexVerRanges(%q) = %+v, want %+v", tt.constraint, got, tt.want)
+		}
+	}
+}
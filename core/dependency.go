@@ -0,0 +1,400 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The possible values of ModDependency.Type
+const (
+	DependencyRequired     = "required"
+	DependencyOptional     = "optional"
+	DependencyIncompatible = "incompatible"
+	// DependencyEmbedded is a dependency that is included in the file of the mod, so it doesn't need to be installed
+	DependencyEmbedded = "embedded"
+)
+
+// ModDependency is a dependency declared by the installed version of a mod, as recorded in its metadata file
+type ModDependency struct {
+	// Source is the name of the updater that the project ID belongs to (e.g. modrinth or curseforge)
+	Source    string `toml:"source"`
+	ProjectID string `toml:"project-id"`
+	// Slug is used to match dependencies to mods that were installed from a different source
+	Slug string `toml:"slug,omitempty"`
+	Name string `toml:"name,omitempty"`
+	Type string `toml:"type"`
+}
+
+// String returns the most readable name available for the dependency
+func (d ModDependency) String() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	if d.Slug != "" {
+		return d.Slug
+	}
+	return d.Source + ":" + d.ProjectID
+}
+
+// DependencyResolvers stores the dependency resolvers for each source, keyed by the name of the matching updater
+var DependencyResolvers = make(map[string]DependencyResolver)
+
+// DependencyResolver looks up dependency information from a single source
+type DependencyResolver interface {
+	// GetProjectID returns the project ID of a mod, or false if the mod wasn't installed from this source
+	GetProjectID(mod *Mod) (string, bool)
+	// GetDependencies retrieves the dependencies declared by the installed versions of the given mods, which must all
+	// have been installed from this source. The returned slice has the same length as the given slice.
+	GetDependencies(mods []*Mod, pack Pack) ([][]ModDependency, error)
+	// ResolveDependency finds the newest version of a dependency that is compatible with the pack, returning new
+	// metadata for it (not yet saved) with its own dependencies filled in
+	ResolveDependency(dep ModDependency, pack Pack) (*Mod, error)
+}
+
+// GetModSource returns the name of the source a mod was installed from and its project ID, or false if the mod doesn't
+// have a dependency resolver (e.g. files added from a URL). Sources are checked in order of their names, so mods with
+// update metadata for multiple sources are always identified by the same source.
+func GetModSource(mod *Mod) (string, string, bool) {
+	for _, name := range dependencyResolverNames() {
+		if id, ok := DependencyResolvers[name].GetProjectID(mod); ok {
+			return name, id, true
+		}
+	}
+	return "", "", false
+}
+
+// GetModSources returns the project ID of a mod on every source with a dependency resolver that it has update metadata
+// for, keyed by the name of the source
+func GetModSources(mod *Mod) map[string]string {
+	sources := make(map[string]string)
+	for name, resolver := range DependencyResolvers {
+		if id, ok := resolver.GetProjectID(mod); ok {
+			sources[name] = id
+		}
+	}
+	return sources
+}
+
+// dependencyResolverNames returns the names of the dependency resolvers, sorted
+func dependencyResolverNames() []string {
+	names := make([]string, 0, len(DependencyResolvers))
+	for k := range DependencyResolvers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetaName returns the name of a metadata file without its extension, which is used as the slug of the mod
+func (m Mod) MetaName() string {
+	name := filepath.Base(m.metaFile)
+	return strings.TrimSuffix(strings.TrimSuffix(name, MetaExtension), MetaExtensionOld)
+}
+
+// modMatcher finds the mods that satisfy dependencies, by project ID or by slug
+type modMatcher struct {
+	byID   map[string]*Mod
+	bySlug map[string]*Mod
+}
+
+func newModMatcher() *modMatcher {
+	return &modMatcher{
+		byID:   make(map[string]*Mod),
+		bySlug: make(map[string]*Mod),
+	}
+}
+
+// add indexes a mod under its project ID on every source it has update metadata for, so dependencies from any source
+// match it
+func (mm *modMatcher) add(m *Mod) {
+	for source, id := range GetModSources(m) {
+		mm.byID[source+":"+id] = m
+	}
+	if slug := m.MetaName(); slug != "" {
+		mm.bySlug[slug] = m
+	}
+}
+
+func (mm *modMatcher) find(dep ModDependency) *Mod {
+	if m, ok := mm.byID[dep.Source+":"+dep.ProjectID]; ok {
+		return m
+	}
+	if dep.Slug != "" {
+		if m, ok := mm.bySlug[dep.Slug]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// findMod finds an installed mod that is the same project as a newly resolved mod, on any of its sources or by the slug
+// of the dependency it was resolved from
+func (mm *modMatcher) findMod(m *Mod, dep ModDependency) *Mod {
+	for source, id := range GetModSources(m) {
+		if existing, ok := mm.byID[source+":"+id]; ok {
+			return existing
+		}
+	}
+	dep.Slug = m.MetaName()
+	return mm.find(dep)
+}
+
+// DependencyEdge is a dependency of a mod, along with the installed mod that satisfies it (nil if there isn't one)
+type DependencyEdge struct {
+	Dependency ModDependency
+	Mod        *Mod
+}
+
+// MissingDependency is a required dependency that isn't satisfied by any installed mod
+type MissingDependency struct {
+	Mod        *Mod
+	Dependency ModDependency
+}
+
+// Incompatibility is an installed mod that another installed mod declares itself incompatible with
+type Incompatibility struct {
+	Mod        *Mod
+	Dependency ModDependency
+	With       *Mod
+}
+
+// DependencyGraph links mods to the installed mods they depend on, using the dependencies recorded in their metadata
+type DependencyGraph struct {
+	Mods       []*Mod
+	edges      map[*Mod][]DependencyEdge
+	dependents map[*Mod][]*Mod
+}
+
+// NewDependencyGraph builds a dependency graph from a set of mods (usually every mod in the pack)
+func NewDependencyGraph(mods []*Mod) *DependencyGraph {
+	matcher := newModMatcher()
+	for _, m := range mods {
+		matcher.add(m)
+	}
+
+	g := &DependencyGraph{
+		Mods:       mods,
+		edges:      make(map[*Mod][]DependencyEdge),
+		dependents: make(map[*Mod][]*Mod),
+	}
+	for _, m := range mods {
+		for _, dep := range m.Dependencies {
+			target := matcher.find(dep)
+			if target == m {
+				continue
+			}
+			g.edges[m] = append(g.edges[m], DependencyEdge{Dependency: dep, Mod: target})
+			if target != nil && dep.Type == DependencyRequired {
+				g.dependents[target] = append(g.dependents[target], m)
+			}
+		}
+	}
+	for _, deps := range g.dependents {
+		sortMods(deps)
+	}
+	return g
+}
+
+func sortMods(mods []*Mod) {
+	sort.SliceStable(mods, func(i, j int) bool {
+		return strings.ToLower(mods[i].Name) < strings.ToLower(mods[j].Name)
+	})
+}
+
+// Find returns the mod with the given name or metadata file name (without extension), or nil if there isn't one
+func (g *DependencyGraph) Find(name string) *Mod {
+	for _, m := range g.Mods {
+		if m.MetaName() == name {
+			return m
+		}
+	}
+	for _, m := range g.Mods {
+		if strings.EqualFold(m.Name, name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the dependencies of a mod, of every type
+func (g *DependencyGraph) Dependencies(m *Mod) []DependencyEdge {
+	return g.edges[m]
+}
+
+// Dependents returns the installed mods that require a mod
+func (g *DependencyGraph) Dependents(m *Mod) []*Mod {
+	return g.dependents[m]
+}
+
+// Roots returns the mods that aren't required by any other mod, sorted by name
+func (g *DependencyGraph) Roots() []*Mod {
+	var roots []*Mod
+	for _, m := range g.Mods {
+		if len(g.dependents[m]) == 0 {
+			roots = append(roots, m)
+		}
+	}
+	sortMods(roots)
+	return roots
+}
+
+// Missing returns the required dependencies that aren't satisfied by any installed mod
+func (g *DependencyGraph) Missing() []MissingDependency {
+	var missing []MissingDependency
+	for _, m := range g.Mods {
+		for _, e := range g.edges[m] {
+			if e.Mod == nil && e.Dependency.Type == DependencyRequired {
+				missing = append(missing, MissingDependency{Mod: m, Dependency: e.Dependency})
+			}
+		}
+	}
+	return missing
+}
+
+// Incompatibilities returns the installed mods that are declared to be incompatible with another installed mod
+func (g *DependencyGraph) Incompatibilities() []Incompatibility {
+	var incompatible []Incompatibility
+	for _, m := range g.Mods {
+		for _, e := range g.edges[m] {
+			if e.Mod != nil && e.Dependency.Type == DependencyIncompatible {
+				incompatible = append(incompatible, Incompatibility{Mod: m, Dependency: e.Dependency, With: e.Mod})
+			}
+		}
+	}
+	return incompatible
+}
+
+// Why returns every chain of required dependencies that leads to a mod being installed, starting from a mod that
+// isn't required by anything else and ending with the given mod. It returns nil if nothing requires the mod.
+func (g *DependencyGraph) Why(m *Mod) [][]*Mod {
+	var chains [][]*Mod
+	var walk func(current *Mod, chain []*Mod)
+	walk = func(current *Mod, chain []*Mod) {
+		dependents := g.dependents[current]
+		if len(dependents) == 0 {
+			if len(chain) > 1 {
+				reversed := make([]*Mod, len(chain))
+				for i, v := range chain {
+					reversed[len(chain)-1-i] = v
+				}
+				chains = append(chains, reversed)
+			}
+			return
+		}
+		for _, d := range dependents {
+			cycle := false
+			for _, v := range chain {
+				if v == d {
+					cycle = true
+					break
+				}
+			}
+			if cycle {
+				// Mods that depend on each other; stop here rather than looping forever
+				reversed := make([]*Mod, 0, len(chain)+1)
+				reversed = append(reversed, d)
+				for i := len(chain) - 1; i >= 0; i-- {
+					reversed = append(reversed, chain[i])
+				}
+				chains = append(chains, reversed)
+				continue
+			}
+			walk(d, append(chain[:len(chain):len(chain)], d))
+		}
+	}
+	walk(m, []*Mod{m})
+	return chains
+}
+
+// Unneeded returns the mods that were added as dependencies and aren't required (directly or indirectly) by any mod
+// that was added explicitly, once the given mods have been removed. The removed mods aren't included in the result.
+func (g *DependencyGraph) Unneeded(removed []*Mod) []*Mod {
+	isRemoved := make(map[*Mod]bool)
+	for _, m := range removed {
+		isRemoved[m] = true
+	}
+
+	needed := make(map[*Mod]bool)
+	var mark func(m *Mod)
+	mark = func(m *Mod) {
+		if needed[m] || isRemoved[m] {
+			return
+		}
+		needed[m] = true
+		for _, e := range g.edges[m] {
+			if e.Mod != nil && e.Dependency.Type == DependencyRequired {
+				mark(e.Mod)
+			}
+		}
+	}
+	for _, m := range g.Mods {
+		if !m.AddedAsDependency {
+			mark(m)
+		}
+	}
+
+	var unneeded []*Mod
+	for _, m := range g.Mods {
+		if !needed[m] && !isRemoved[m] {
+			unneeded = append(unneeded, m)
+		}
+	}
+	sortMods(unneeded)
+	return unneeded
+}
+
+// ResolveDependencies finds the required dependencies of the given new mods that aren't satisfied by the installed
+// mods (or by each other), recursively, and returns metadata for each mod that needs to be added (not yet saved).
+// Mods that could be resolved are returned even if resolving some dependencies fails; the failures are returned as a
+// single error.
+func ResolveDependencies(newMods []*Mod, installed []*Mod, pack Pack) ([]*Mod, error) {
+	matcher := newModMatcher()
+	for _, m := range installed {
+		matcher.add(m)
+	}
+	for _, m := range newMods {
+		matcher.add(m)
+	}
+
+	var added []*Mod
+	var errs []error
+	visited := make(map[string]bool)
+	queue := append([]*Mod(nil), newMods...)
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		for _, dep := range m.Dependencies {
+			if dep.Type != DependencyRequired {
+				continue
+			}
+			key := dep.Source + ":" + dep.ProjectID
+			if visited[key] || matcher.find(dep) != nil {
+				continue
+			}
+			visited[key] = true
+
+			resolver, ok := DependencyResolvers[dep.Source]
+			if !ok {
+				errs = append(errs, fmt.Errorf("failed to resolve dependency %s: source %s not found", dep, dep.Source))
+				continue
+			}
+			depMod, err := resolver.ResolveDependency(dep, pack)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to resolve dependency %s of %s: %w", dep, m.Name, err))
+				continue
+			}
+			// The project might have been installed under a different slug or source than the dependency suggested
+			if existing := matcher.findMod(depMod, dep); existing != nil {
+				continue
+			}
+			depMod.AddedAsDependency = true
+			matcher.add(depMod)
+			added = append(added, depMod)
+			queue = append(queue, depMod)
+		}
+	}
+	return added, errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"testing"
+)
+
+// testResolver identifies mods by a "project" value in their update metadata for its source
+type testResolver struct {
+	source string
+}
+
+func (r testResolver) GetProjectID(mod *Mod) (string, bool) {
+	id, ok := mod.Update[r.source]["project"].(string)
+	return id, ok
+}
+
+func (r testResolver) GetDependencies(mods []*Mod, _ Pack) ([][]ModDependency, error) {
+	return make([][]ModDependency, len(mods)), nil
+}
+
+func (r testResolver) ResolveDependency(ModDependency, Pack) (*Mod, error) {
+	return nil, nil
+}
+
+func withTestResolvers(t *testing.T) {
+	old := DependencyResolvers
+	DependencyResolvers = map[string]DependencyResolver{
+		"modrinth":   testResolver{"modrinth"},
+		"curseforge": testResolver{"curseforge"},
+	}
+	t.Cleanup(func() { DependencyResolvers = old })
+}
+
+func TestGetModSourceMultipleSources(t *testing.T) {
+	withTestResolvers(t)
+	mod := &Mod{Update: map[string]map[string]interface{}{
+		"modrinth":   {"project": "AANobbMI"},
+		"curseforge": {"project": "394468"},
+	}}
+	for i := 0; i < 20; i++ {
+		source, id, ok := GetModSource(mod)
+		if !ok || source != "curseforge" || id != "394468" {
+			t.Fatalf("GetModSource() = %q, %q, %v, want curseforge, 394468, true", source, id, ok)
+		}
+	}
+}
+
+func TestDependencyGraphMultipleSources(t *testing.T) {
+	withTestResolvers(t)
+	sodium := &Mod{Name: "Sodium", AddedAsDependency: true, Update: map[string]map[string]interface{}{
+		"modrinth":   {"project": "AANobbMI"},
+		"curseforge": {"project": "394468"},
+	}}
+	fromModrinth := &Mod{Name: "Iris", Dependencies: []ModDependency{
+		{Source: "modrinth", ProjectID: "AANobbMI", Type: DependencyRequired},
+	}}
+	fromCurseForge := &Mod{Name: "Indium", Dependencies: []ModDependency{
+		{Source: "curseforge", ProjectID: "394468", Type: DependencyRequired},
+	}}
+	for i := 0; i < 20; i++ {
+		g := NewDependencyGraph([]*Mod{sodium, fromModrinth, fromCurseForge})
+		if missing := g.Missing(); len(missing) != 0 {
+			t.Fatalf("Missing() = %v, want none", missing)
+		}
+		if dependents := g.Dependents(sodium); len(dependents) != 2 {
+			t.Fatalf("Dependents() has %d mods, want 2", len(dependents))
+		}
+		if unneeded := g.Unneeded([]*Mod{fromModrinth}); len(unneeded) != 0 {
+			t.Fatalf("Unneeded() = %v, want none", unneeded)
+		}
+	}
+}
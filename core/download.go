@@ -9,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"slices"
 )
@@ -33,6 +35,9 @@ type DownloadSession interface {
 	SaveIndex() error
 }
 
+// CompletedDownload is a file that has been downloaded (or found in the cache) by a DownloadSession.
+// They are sent by StartDownloads in a fixed order, regardless of which downloads finish first: manual downloads that
+// were found in the cache, followed by every other file in the order given to CreateDownloadSession.
 type CompletedDownload struct {
 	// File is only populated when the download is successful; points to the opened cache file
 	File *os.File
@@ -52,6 +57,13 @@ type downloadSessionInternal struct {
 	manualDownloads      []ManualDownload
 	downloadTasks        []downloadTask
 	foundManualDownloads []CompletedDownload
+
+	// mu guards cacheIndex and every CacheIndexHandle, as removing a handle changes the indexes of the others
+	mu    sync.Mutex
+	hosts *hostLimiter
+	// Partial download files that are currently being written to
+	partials   map[string]bool
+	partialsMu sync.Mutex
 }
 
 type downloadTask struct {
@@ -66,64 +78,105 @@ func (d *downloadSessionInternal) GetManualDownloads() []ManualDownload {
 	return d.manualDownloads
 }
 
+// StartDownloads downloads every file that isn't already in the cache, running several downloads at once (see the
+// download.concurrency option). Completed downloads are sent in the order documented on CompletedDownload, and the
+// channel is closed once every file has been sent.
 func (d *downloadSessionInternal) StartDownloads() chan CompletedDownload {
 	downloads := make(chan CompletedDownload)
+	concurrency := getDownloadSetting("download.concurrency", DefaultDownloadConcurrency)
+
+	results := make([]chan CompletedDownload, len(d.downloadTasks))
+	for i := range results {
+		results[i] = make(chan CompletedDownload, 1)
+	}
+	// Completed downloads hold open files until they are received, so limit how far ahead of the receiver the
+	// workers can get
+	window := make(chan struct{}, concurrency*2)
+	jobs := make(chan int)
 	go func() {
-		for _, found := range d.foundManualDownloads {
-			downloads <- found
+		for i := range d.downloadTasks {
+			window <- struct{}{}
+			jobs <- i
 		}
-		for _, task := range d.downloadTasks {
-			warnings := make([]error, 0)
-
-			// Get handle for mod
-			cacheHandle := d.cacheIndex.GetHandleFromHash(task.hashFormat, task.hash)
-			if cacheHandle != nil {
-				download, err := reuseExistingFile(cacheHandle, d.hashesToObtain, task.mod)
-				if err != nil {
-					// Remove handle and try again
-					cacheHandle.Remove()
-					cacheHandle = nil
-					warnings = append(warnings, fmt.Errorf("redownloading cached file: %w", err))
-				} else {
-					downloads <- download
-					continue
-				}
+		close(jobs)
+	}()
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] <- d.processTask(&d.downloadTasks[i])
 			}
+		}()
+	}
 
-			download, err := downloadNewFile(&task, d.cacheFolder, d.hashesToObtain, &d.cacheIndex)
-			if err != nil {
-				downloads <- CompletedDownload{
-					Error: err,
-					Mod:   task.mod,
-				}
-			} else {
-				download.Warnings = warnings
-				downloads <- download
-			}
+	go func() {
+		for _, found := range d.foundManualDownloads {
+			downloads <- found
+		}
+		for _, result := range results {
+			downloads <- <-result
+			<-window
 		}
 		close(downloads)
 	}()
 	return downloads
 }
 
-func (d *downloadSessionInternal) SaveIndex() error {
-	data, err := json.Marshal(d.cacheIndex)
-	if err != nil {
-		return fmt.Errorf("failed to serialise index: %w", err)
+// processTask gets a file from the cache, or downloads it if it isn't in the cache
+func (d *downloadSessionInternal) processTask(task *downloadTask) CompletedDownload {
+	warnings := make([]error, 0)
+
+	// Get handle for mod
+	d.mu.Lock()
+	cacheHandle := d.cacheIndex.GetHandleFromHash(task.hashFormat, task.hash)
+	d.mu.Unlock()
+	if cacheHandle != nil {
+		// The file is read without holding the lock, so other downloads can continue while it is hashed
+		download, err := reuseExistingFile(cacheHandle, d.hashesToObtain, task.mod)
+		d.mu.Lock()
+		// Other downloads might have changed the index in the meantime, so the file is looked up again
+		current := d.cacheIndex.GetHandleFromHash(cacheHashFormat, cacheHandle.Hashes[cacheHashFormat])
+		if current != nil {
+			if err != nil {
+				// Remove handle and try again
+				current.Remove()
+			} else {
+				// Add any newly obtained hashes
+				for hashFormat, hash := range cacheHandle.Hashes {
+					current.Hashes[hashFormat] = hash
+				}
+				download.Warnings = current.UpdateIndex()
+				current.markUsed()
+			}
+		}
+		d.mu.Unlock()
+		if err == nil {
+			return download
+		}
+		warnings = append(warnings, fmt.Errorf("redownloading cached file: %w", err))
 	}
-	err = os.WriteFile(filepath.Join(d.cacheFolder, "index.json"), data, 0644)
+
+	download, err := d.downloadNewFile(task)
 	if err != nil {
-		return fmt.Errorf("failed to write index: %w", err)
+		return CompletedDownload{
+			Error: err,
+			Mod:   task.mod,
+		}
 	}
-	return nil
+	download.Warnings = append(warnings, download.Warnings...)
+	return download
 }
 
+func (d *downloadSessionInternal) SaveIndex() error {
+	return d.cacheIndex.Save()
+}
+
+// reuseExistingFile opens a file from the cache, adding any hashes that aren't in the index to the handle (but not the
+// index itself, so the index doesn't need to be locked)
 func reuseExistingFile(cacheHandle *CacheIndexHandle, hashesToObtain []string, mod *Mod) (CompletedDownload, error) {
 	// Already stored; try using it!
 	file, err := cacheHandle.Open()
 	if err == nil {
 		remainingHashes := cacheHandle.GetRemainingHashes(hashesToObtain)
-		var warnings []error
 		if len(remainingHashes) > 0 {
 			err = teeHashes(remainingHashes, cacheHandle.Hashes, io.Discard, file)
 			if err != nil {
@@ -135,58 +188,32 @@ func reuseExistingFile(cacheHandle *CacheIndexHandle, hashesToObtain []string, m
 				_ = file.Close()
 				return CompletedDownload{}, fmt.Errorf("failed to seek file %s in cache: %w", cacheHandle.Path(), err)
 			}
-			warnings = cacheHandle.UpdateIndex()
 		}
 
 		return CompletedDownload{
-			File:     file,
-			Mod:      mod,
-			Hashes:   cacheHandle.Hashes,
-			Warnings: warnings,
+			File:   file,
+			Mod:    mod,
+			Hashes: cacheHandle.Hashes,
 		}, nil
 	} else {
 		return CompletedDownload{}, fmt.Errorf("failed to read file %s from cache: %w", cacheHandle.Path(), err)
 	}
 }
 
-func downloadNewFile(task *downloadTask, cacheFolder string, hashesToObtain []string, index *CacheIndex) (CompletedDownload, error) {
-	// Create temp file to download to
-	tempFile, err := os.CreateTemp(filepath.Join(cacheFolder, "temp"), "download-tmp")
+func (d *downloadSessionInternal) downloadNewFile(task *downloadTask) (CompletedDownload, error) {
+	tempFile, hashes, err := d.downloadToTemp(task)
 	if err != nil {
-		return CompletedDownload{}, fmt.Errorf("failed to create temporary file for download: %w", err)
-	}
-
-	hashesToObtain, hashes := getHashListsForDownload(hashesToObtain, task.hashFormat, task.hash)
-	if len(hashesToObtain) > 0 {
-		var data io.ReadCloser
-		if task.url != "" {
-			resp, err := GetWithUA(task.url, "application/octet-stream")
-			if err != nil {
-				return CompletedDownload{}, fmt.Errorf("failed to download %s: %w", task.url, err)
-			}
-			if resp.StatusCode != 200 {
-				_ = resp.Body.Close()
-				return CompletedDownload{}, fmt.Errorf("failed to download %s: invalid status code %v", task.url, resp.StatusCode)
-			}
-			data = resp.Body
-		} else {
-			data, err = task.metaDownloaderData.DownloadFile()
-			if err != nil {
-				return CompletedDownload{}, err
-			}
-		}
-
-		err = teeHashes(hashesToObtain, hashes, tempFile, data)
-		_ = data.Close()
-		if err != nil {
-			return CompletedDownload{}, fmt.Errorf("failed to download: %w", err)
-		}
+		return CompletedDownload{}, err
 	}
 
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	index := &d.cacheIndex
 	// Create handle with calculated hashes
 	cacheHandle, alreadyExists := index.NewHandleFromHashes(hashes)
 	// Update index stored hashes
 	warnings := cacheHandle.UpdateIndex()
+	cacheHandle.markUsed()
 
 	var file *os.File
 	if alreadyExists {
@@ -293,8 +320,11 @@ func teeHashes(hashesToObtain []string, hashes map[string]string,
 const cacheHashFormat = "sha256"
 
 type CacheIndex struct {
-	Version     uint32
-	Hashes      map[string][]string
+	Version uint32
+	Hashes  map[string][]string
+	// LastUsed stores when each file was last used (as a Unix timestamp), keyed by its cache hash; files without an
+	// entry use their modification time
+	LastUsed    map[string]int64 `json:",omitempty"`
 	cachePath   string
 	nextHashIdx int
 }
@@ -484,6 +514,7 @@ func (c *CacheIndex) MoveImportFiles() error {
 			}
 			_ = handle.UpdateIndex()
 		}
+		handle.markUsed()
 		return nil
 	})
 }
@@ -548,13 +579,15 @@ func (h *CacheIndexHandle) UpdateIndex() (warnings []error) {
 }
 
 func (h *CacheIndexHandle) Remove() {
-	for hashFormat := range h.Hashes {
-		hashList := h.index.Hashes[hashFormat]
-		if h.hashIdx < len(hashList) {
-			h.index.Hashes[hashFormat] = slices.Delete(hashList, h.hashIdx, h.hashIdx+1)
-		}
+	h.index.removeEntry(h.hashIdx)
+}
+
+// markUsed records that the file has just been used, so the least recently used files can be pruned
+func (h *CacheIndexHandle) markUsed() {
+	if h.index.LastUsed == nil {
+		h.index.LastUsed = make(map[string]int64)
 	}
-	return
+	h.index.LastUsed[h.Hashes[cacheHashFormat]] = time.Now().Unix()
 }
 
 func removeIndices(hashList []string, indices []int) []string {
@@ -584,8 +617,8 @@ func removeEmpty(hashList []string) ([]string, []int) {
 	return hashList[:i], indices
 }
 
-func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSession, error) {
-	// Load cache index
+// LoadCacheIndex loads the index of the download cache, creating the cache folder if it doesn't exist
+func LoadCacheIndex() (*CacheIndex, error) {
 	cacheIndex := CacheIndex{Version: 1, Hashes: make(map[string][]string)}
 	cachePath, err := GetPackwizCache()
 	if err != nil {
@@ -619,6 +652,9 @@ func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSessio
 	if !hasCacheHashFmt {
 		cacheIndex.Hashes[cacheHashFormat] = make([]string, 0)
 	}
+	if cacheIndex.LastUsed == nil {
+		cacheIndex.LastUsed = make(map[string]int64)
+	}
 	cacheIndex.cachePath = cachePath
 
 	// Clean up empty entries in index
@@ -633,6 +669,30 @@ func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSessio
 	}
 
 	cacheIndex.nextHashIdx = len(cacheIndex.Hashes[cacheHashFormat])
+	return &cacheIndex, nil
+}
+
+// Save writes the cache index to the cache folder
+func (c *CacheIndex) Save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to serialise index: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(c.cachePath, "index.json"), data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSession, error) {
+	// Load cache index
+	cacheIndexPtr, err := LoadCacheIndex()
+	if err != nil {
+		return nil, err
+	}
+	cacheIndex := *cacheIndexPtr
+	cachePath := cacheIndex.cachePath
 
 	// Create import folder
 	err = os.MkdirAll(filepath.Join(cachePath, DownloadCacheImportFolder), 0755)
@@ -645,11 +705,15 @@ func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSessio
 		return nil, fmt.Errorf("error updating cache import folder: %w", err)
 	}
 
+	cleanTempFolder(filepath.Join(cachePath, "temp"))
+
 	// Create session
 	downloadSession := downloadSessionInternal{
 		cacheIndex:     cacheIndex,
 		cacheFolder:    cachePath,
 		hashesToObtain: hashesToObtain,
+		hosts:          newHostLimiter(getDownloadSetting("download.host-concurrency", DefaultDownloadHostConcurrency)),
+		partials:       make(map[string]bool),
 	}
 
 	pendingMetadata := make(map[string][]*Mod)
@@ -688,6 +752,7 @@ func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSessio
 					return nil, fmt.Errorf("failed to lookup manual download %s: %w", v.Name, err)
 				}
 				if handle != nil {
+					handle.markUsed()
 					file, err := handle.Open()
 					if err != nil {
 						return nil, fmt.Errorf("failed to open manual download %s: %w", v.Name, err)
@@ -711,7 +776,7 @@ func CreateDownloadSession(mods []*Mod, hashesToObtain []string) (DownloadSessio
 		}
 	}
 
-	// TODO: index housekeeping? i.e. remove deleted files, remove old files (LRU?)
+	// Old and unused files can be removed with the packwiz cache command
 
 	// Save index after importing and Force index updates
 	err = downloadSession.SaveIndex()
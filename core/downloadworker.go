@@ -0,0 +1,399 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Defaults for the download.concurrency, download.host-concurrency and download.retries options
+const (
+	DefaultDownloadConcurrency     = 8
+	DefaultDownloadHostConcurrency = 4
+	DefaultDownloadRetries         = 3
+)
+
+const (
+	// downloadHostInterval is the minimum time between starting requests to the same host
+	downloadHostInterval = 50 * time.Millisecond
+	downloadRetryDelay   = time.Second
+	downloadMaxDelay     = 30 * time.Second
+	// Partial downloads in the temp folder that haven't been touched for this long are removed
+	partialDownloadMaxAge = 7 * 24 * time.Hour
+)
+
+var downloadClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		// Don't wait forever for a server that has stopped responding
+		t.ResponseHeaderTimeout = time.Minute
+		t.MaxIdleConnsPerHost = DefaultDownloadHostConcurrency
+		return t
+	}(),
+}
+
+// getDownloadSetting reads a numeric download option, using the default if it isn't set or is invalid
+func getDownloadSetting(key string, def int) int {
+	if !viper.IsSet(key) {
+		return def
+	}
+	v := viper.GetInt(key)
+	if v < 0 || (v == 0 && key != "download.retries") {
+		return def
+	}
+	return v
+}
+
+// hostLimiter limits the number of concurrent downloads from each host, and how quickly they are started
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	limit int
+}
+
+type hostState struct {
+	slots chan struct{}
+	mu    sync.Mutex
+	next  time.Time
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{hosts: make(map[string]*hostState), limit: limit}
+}
+
+func (l *hostLimiter) get(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.limit)}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// acquire waits until a download from the host can be started; the returned function must be called when it is done
+func (l *hostLimiter) acquire(host string) func() {
+	h := l.get(host)
+	h.slots <- struct{}{}
+	h.mu.Lock()
+	wait := time.Until(h.next)
+	if wait < 0 {
+		wait = 0
+	}
+	h.next = time.Now().Add(wait + downloadHostInterval)
+	h.mu.Unlock()
+	time.Sleep(wait)
+	return func() {
+		<-h.slots
+	}
+}
+
+// delay prevents any new downloads from the host being started for the given duration (e.g. after a 429 response)
+func (l *hostLimiter) delay(host string, d time.Duration) {
+	h := l.get(host)
+	h.mu.Lock()
+	if until := time.Now().Add(d); until.After(h.next) {
+		h.next = until
+	}
+	h.mu.Unlock()
+}
+
+// transientError is a download failure that is likely to succeed if it is retried
+type transientError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func isTransientStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientError checks if an error was caused by a network problem that might not happen again, rather than e.g. an
+// invalid URL, a certificate error or a disk error
+func isTransientError(err error) bool {
+	var transient *transientError
+	if errors.As(err, &transient) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsTemporary {
+		return true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && errors.Is(urlErr.Err, io.EOF) {
+		// The connection was closed before a response was received
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// parseRetryAfter reads the delay in a Retry-After header, in either seconds or HTTP date format
+func parseRetryAfter(resp *http.Response) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// retryDelay returns the time to wait before the given retry (starting at 0), with exponential backoff and jitter
+func retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := downloadRetryDelay << attempt
+	delay += time.Duration(rand.Int64N(int64(delay) / 2))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return min(delay, downloadMaxDelay)
+}
+
+func getHost(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.Host
+}
+
+// skipWriter discards the first skip bytes written to it, which have already been written to the file
+type skipWriter struct {
+	w    io.Writer
+	skip int64
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if s.skip > 0 {
+		if int64(len(p)) <= s.skip {
+			s.skip -= int64(len(p))
+			return n, nil
+		}
+		p = p[s.skip:]
+		s.skip = 0
+	}
+	_, err := s.w.Write(p)
+	return n, err
+}
+
+// claimPartial marks a partial download file as in use, returning false if another download is already using it
+func (d *downloadSessionInternal) claimPartial(path string) bool {
+	d.partialsMu.Lock()
+	defer d.partialsMu.Unlock()
+	if d.partials[path] {
+		return false
+	}
+	d.partials[path] = true
+	return true
+}
+
+func (d *downloadSessionInternal) releasePartial(path string) {
+	d.partialsMu.Lock()
+	defer d.partialsMu.Unlock()
+	delete(d.partials, path)
+}
+
+// downloadToTemp downloads the file for a task to a file in the cache temp folder, retrying transient failures.
+// Files with a known hash are downloaded to a partial file named after the hash, so that an interrupted download
+// can be resumed by the next download session. It returns the file (positioned at the end) and all obtained hashes.
+func (d *downloadSessionInternal) downloadToTemp(task *downloadTask) (*os.File, map[string]string, error) {
+	hashesToObtain, hashes := getHashListsForDownload(d.hashesToObtain, task.hashFormat, task.hash)
+
+	var file *os.File
+	var err error
+	partialPath := ""
+	if task.hash != "" && task.url != "" {
+		partialPath = filepath.Join(d.cacheFolder, "temp", "partial-"+task.hashFormat+"-"+strings.ToLower(task.hash))
+		if d.claimPartial(partialPath) {
+			defer d.releasePartial(partialPath)
+		} else {
+			partialPath = ""
+		}
+	}
+	if partialPath != "" {
+		file, err = os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
+	} else {
+		file, err = os.CreateTemp(filepath.Join(d.cacheFolder, "temp"), "download-tmp")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary file for download: %w", err)
+	}
+
+	retries := getDownloadSetting("download.retries", DefaultDownloadRetries)
+	restarted := false
+	for attempt := 0; ; attempt++ {
+		resumed, err := d.downloadAttempt(task, file, hashesToObtain, hashes)
+		if err == nil {
+			return file, hashes, nil
+		}
+		if isTransientError(err) {
+			if attempt < retries {
+				var transient *transientError
+				var retryAfter time.Duration
+				if errors.As(err, &transient) {
+					retryAfter = transient.retryAfter
+				}
+				time.Sleep(retryDelay(attempt, retryAfter))
+				continue
+			}
+			_ = file.Close()
+			if partialPath == "" {
+				_ = os.Remove(file.Name())
+			}
+			// Partial files are kept, so the download can be resumed later
+			return nil, nil, err
+		}
+		if resumed && !restarted {
+			// The previously downloaded data might be invalid; start again from the beginning
+			restarted = true
+			if err := file.Truncate(0); err == nil {
+				continue
+			}
+		}
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, nil, err
+	}
+}
+
+// downloadAttempt downloads a file once, resuming from the end of the file if it already has any data. It returns
+// whether the download was resumed.
+func (d *downloadSessionInternal) downloadAttempt(task *downloadTask, file *os.File, hashesToObtain []string, hashes map[string]string) (bool, error) {
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+
+	var data io.ReadCloser
+	if task.url != "" {
+		host := getHost(task.url)
+		release := d.hosts.acquire(host)
+		defer release()
+
+		req, err := http.NewRequest("GET", task.url, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to download %s: %w", task.url, err)
+		}
+		req.Header.Set("User-Agent", UserAgent)
+		req.Header.Set("Accept", "application/octet-stream")
+		if offset > 0 {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		}
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("failed to download %s: %w", task.url, err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusPartialContent && offset > 0 &&
+			strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(offset, 10)+"-"):
+			// Resuming
+		case resp.StatusCode == http.StatusOK:
+			// Range not supported, or nothing downloaded yet
+			offset = 0
+		case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+			// The partial file is at least as long as the whole file; start again
+			_ = resp.Body.Close()
+			if err := file.Truncate(0); err != nil {
+				return false, err
+			}
+			return false, &transientError{err: fmt.Errorf("failed to resume download of %s", task.url)}
+		case isTransientStatus(resp.StatusCode):
+			_ = resp.Body.Close()
+			retryAfter := parseRetryAfter(resp)
+			if retryAfter > 0 {
+				d.hosts.delay(host, retryAfter)
+			}
+			return false, &transientError{
+				err:        fmt.Errorf("failed to download %s: invalid status code %v", task.url, resp.StatusCode),
+				retryAfter: retryAfter,
+			}
+		default:
+			_ = resp.Body.Close()
+			return false, fmt.Errorf("failed to download %s: invalid status code %v", task.url, resp.StatusCode)
+		}
+		data = resp.Body
+	} else {
+		// Metadata downloads can't be resumed
+		offset = 0
+		release := d.hosts.acquire(task.mod.Download.Mode)
+		defer release()
+		data, err = task.metaDownloaderData.DownloadFile()
+		if err != nil {
+			return false, &transientError{err: err}
+		}
+	}
+	defer data.Close()
+
+	if offset == 0 {
+		if err := file.Truncate(0); err != nil {
+			return false, err
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	// Hash the data that has already been downloaded along with the rest of the file; only the new data is written
+	src := io.MultiReader(io.LimitReader(file, offset), data)
+	err = teeHashes(hashesToObtain, hashes, &skipWriter{w: file, skip: offset}, src)
+	if err != nil {
+		if isTransientError(err) {
+			return offset > 0, &transientError{err: fmt.Errorf("failed to download: %w", err)}
+		}
+		return offset > 0, fmt.Errorf("failed to download: %w", err)
+	}
+	return offset > 0, nil
+}
+
+// cleanTempFolder removes old partial downloads and temporary files from the cache temp folder
+func cleanTempFolder(tempFolder string) {
+	entries, err := os.ReadDir(tempFolder)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		if time.Since(info.ModTime()) > partialDownloadMaxAge {
+			_ = os.Remove(filepath.Join(tempFolder, entry.Name()))
+		}
+	}
+}
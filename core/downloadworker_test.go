@@ -0,0 +1,50 @@
+package core
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransientError(t *testing.T) {
+	urlErr := func(err error) error {
+		return fmt.Errorf("failed to download: %w", &url.Error{Op: "Get", URL: "https://example.com/mod.jar", Err: err})
+	}
+	dialErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"transient status", &transientError{err: errors.New("invalid status code 503")}, true},
+		{"timeout", urlErr(timeoutError{}), true},
+		{"connection refused", urlErr(dialErr(syscall.ECONNREFUSED)), true},
+		{"connection reset", urlErr(dialErr(syscall.ECONNRESET)), true},
+		{"connection closed", urlErr(io.EOF), true},
+		{"unexpected EOF", fmt.Errorf("failed to download: %w", io.ErrUnexpectedEOF), true},
+		{"temporary DNS failure", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}}), true},
+		{"unknown host", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}), false},
+		{"unsupported scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"certificate error", urlErr(x509.UnknownAuthorityError{}), false},
+		{"disk error", &os.PathError{Op: "write", Path: "mod.jar", Err: syscall.ENOSPC}, false},
+	}
+	for _, tt := range tests {
+		if got := isTransientError(tt.err); got != tt.want {
+			t.Errorf("isTransientError(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FileIdentifiers stores the sources that can find the project a file belongs to from its hashes, keyed by the name of
+// the matching updater
+var FileIdentifiers = make(map[string]FileIdentifier)
+
+// fileIdentifierOrder is the order that sources are tried in by IdentifyFiles; any other sources are tried afterwards,
+// in alphabetical order
+var fileIdentifierOrder = []string{"modrinth", "curseforge"}
+
+// IdentifyHashFormats are the hash formats calculated by HashIdentifyFile, for use by FileIdentifiers
+var IdentifyHashFormats = []string{"sha1", "sha512", "murmur2"}
+
+// IdentifyFile is a file on disk to be identified, with its hashes in every format in IdentifyHashFormats
+type IdentifyFile struct {
+	Path   string
+	Hashes map[string]string
+}
+
+// IdentifyResult is the result of looking up a file, with new metadata (not yet saved) for the projects it matched
+type IdentifyResult struct {
+	// Mod is set if the file exactly matches a file of a project
+	Mod *Mod
+	// Candidates are files that partially match the file (e.g. a different build of the same version), if there is
+	// no exact match
+	Candidates []*Mod
+}
+
+// FileIdentifier looks up files by their hashes on a single source
+type FileIdentifier interface {
+	// IdentifyFiles looks up the given files, returning the matches for each file on this source. The returned slice
+	// has the same length as the given slice.
+	IdentifyFiles(files []IdentifyFile, pack Pack) ([]IdentifyResult, error)
+}
+
+// HashIdentifyFile reads a file, calculating the hashes needed to identify it
+func HashIdentifyFile(path string) (IdentifyFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return IdentifyFile{}, err
+	}
+	defer f.Close()
+	return HashIdentifyReader(path, f)
+}
+
+// HashIdentifyReader calculates the hashes needed to identify a file from its contents; the path is only used to
+// refer to the file
+func HashIdentifyReader(path string, src io.Reader) (IdentifyFile, error) {
+	hashers := make([]HashStringer, len(IdentifyHashFormats))
+	writers := make([]io.Writer, len(IdentifyHashFormats))
+	for i, format := range IdentifyHashFormats {
+		hasher, err := GetHashImpl(format)
+		if err != nil {
+			return IdentifyFile{}, err
+		}
+		hashers[i] = hasher
+		writers[i] = hasher
+	}
+	_, err := io.Copy(io.MultiWriter(writers...), src)
+	if err != nil {
+		return IdentifyFile{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	file := IdentifyFile{Path: path, Hashes: make(map[string]string, len(hashers))}
+	for i, format := range IdentifyHashFormats {
+		file.Hashes[format] = strings.ToLower(hashers[i].HashToString(hashers[i].Sum(nil)))
+	}
+	return file, nil
+}
+
+// IdentifyFiles looks up files on every source in FileIdentifiers. Files are looked up on each source in turn, so a
+// file found on Modrinth is not looked up on CurseForge; candidates from every source are kept for files without an
+// exact match. Errors from a source are returned along with the results from the other sources.
+func IdentifyFiles(files []IdentifyFile, pack Pack) ([]IdentifyResult, error) {
+	sources := make([]string, 0, len(FileIdentifiers))
+	for name := range FileIdentifiers {
+		sources = append(sources, name)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		a, b := identifierPriority(sources[i]), identifierPriority(sources[j])
+		if a != b {
+			return a < b
+		}
+		return sources[i] < sources[j]
+	})
+
+	results := make([]IdentifyResult, len(files))
+	var errs []error
+	for _, source := range sources {
+		var remaining []IdentifyFile
+		var remainingIdx []int
+		for i, f := range files {
+			if results[i].Mod == nil {
+				remaining = append(remaining, f)
+				remainingIdx = append(remainingIdx, i)
+			}
+		}
+		if len(remaining) == 0 {
+			break
+		}
+		res, err := FileIdentifiers[source].IdentifyFiles(remaining, pack)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up files on %s: %w", source, err))
+			continue
+		}
+		for i, r := range res {
+			result := &results[remainingIdx[i]]
+			if r.Mod != nil {
+				result.Mod = r.Mod
+				result.Candidates = nil
+			} else {
+				result.Candidates = append(result.Candidates, r.Candidates...)
+			}
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func identifierPriority(source string) int {
+	for i, v := range fileIdentifierOrder {
+		if v == source {
+			return i
+		}
+	}
+	return len(fileIdentifierOrder)
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/fs"
@@ -23,6 +24,8 @@ type Index struct {
 	Files      IndexFiles
 	indexFile  string
 	packRoot   string
+	// packFile is the pack file that the index was loaded from; if empty, the pack-file option is used
+	packFile string
 }
 
 // indexTomlRepresentation is the TOML representation of Index (Files must be converted)
@@ -148,6 +151,9 @@ var ignoreDefaults = []string{
 	// Exclude packwiz binaries, if the user puts them in their pack folder
 	"packwiz.exe",
 	"packwiz", // Note: also excludes packwiz/ as a directory - you can negate this pattern if you want a directory called packwiz
+
+	// Exclude files left behind by interrupted transactions
+	"*" + transactionTempSuffix,
 }
 
 func readGitignore(path string) (*gitignore.GitIgnore, bool) {
@@ -164,17 +170,67 @@ func readGitignore(path string) (*gitignore.GitIgnore, bool) {
 	return gitignore.CompileIgnoreLines(lines...), true
 }
 
-// Refresh updates the hashes of all the files in the index, and adds new files to the index
-func (in *Index) Refresh() error {
-	// TODO: If needed, multithreaded hashing
-	// for i := 0; i < runtime.NumCPU(); i++ {}
+// IndexFilter decides which files in the pack root are included in the index, using the default ignore rules and
+// .packwizignore
+type IndexFilter struct {
+	ignore       *gitignore.GitIgnore
+	ignoreExists bool
+	packRoot     string
+	pathPF       string
+	pathIndex    string
+	pathIgnore   string
+}
 
+// Filter loads the rules used to decide which files are included in the index
+func (in Index) Filter() IndexFilter {
 	// Is case-sensitivity a problem?
-	pathPF, _ := filepath.Abs(viper.GetString("pack-file"))
+	packFile := in.packFile
+	if packFile == "" {
+		packFile = viper.GetString("pack-file")
+	}
+	pathPF, _ := filepath.Abs(packFile)
 	pathIndex, _ := filepath.Abs(in.indexFile)
 
 	pathIgnore, _ := filepath.Abs(filepath.Join(in.packRoot, ".packwizignore"))
 	ignore, ignoreExists := readGitignore(pathIgnore)
+	return IndexFilter{
+		ignore:       ignore,
+		ignoreExists: ignoreExists,
+		packRoot:     in.packRoot,
+		pathPF:       pathPF,
+		pathIndex:    pathIndex,
+		pathIgnore:   pathIgnore,
+	}
+}
+
+// Includes checks if a file should be added to the index, or if a directory should be searched for files to add.
+// Paths must be in the same form as those found by walking the pack root.
+func (f IndexFilter) Includes(path string, isDir bool) bool {
+	// Never ignore pack root itself (gitignore doesn't allow ignoring the root)
+	if path == f.packRoot {
+		return isDir
+	}
+	if isDir {
+		// Don't traverse ignored directories (consistent with Git handling of ignored dirs)
+		return !f.ignore.MatchesPath(path)
+	}
+	// Exclude the pack/index files
+	absPath, _ := filepath.Abs(path)
+	if absPath == f.pathPF || absPath == f.pathIndex {
+		return false
+	}
+	if f.ignoreExists && absPath == f.pathIgnore {
+		return false
+	}
+	return !f.ignore.MatchesPath(path)
+}
+
+// Refresh updates the hashes of all the files in the index, and adds new files to the index
+func (in *Index) Refresh() error {
+	// TODO: If needed, multithreaded hashing
+	// for i := 0; i < runtime.NumCPU(); i++ {}
+
+	filter := in.Filter()
 
 	var fileList []string
 	err := filepath.WalkDir(in.packRoot, func(path string, info os.DirEntry, err error) error {
@@ -183,34 +239,16 @@ func (in *Index) Refresh() error {
 			return err
 		}
 
-		// Never ignore pack root itself (gitignore doesn't allow ignoring the root)
-		if path == in.packRoot {
-			return nil
-		}
-
-		if info.IsDir() {
-			// Don't traverse ignored directories (consistent with Git handling of ignored dirs)
-			if ignore.MatchesPath(path) {
+		if !filter.Includes(path, info.IsDir()) {
+			if info.IsDir() {
 				return fs.SkipDir
 			}
-			// Don't add directories to the file list
-			return nil
-		}
-		// Exit if the files are the same as the pack/index files
-		absPath, _ := filepath.Abs(path)
-		if absPath == pathPF || absPath == pathIndex {
 			return nil
 		}
-		if ignoreExists {
-			if absPath == pathIgnore {
-				return nil
-			}
-		}
-		if ignore.MatchesPath(path) {
-			return nil
+		// Don't add directories to the file list
+		if !info.IsDir() {
+			fileList = append(fileList, path)
 		}
-
-		fileList = append(fileList, path)
 		return nil
 	})
 	if err != nil {
@@ -258,29 +296,130 @@ func (in *Index) Refresh() error {
 	return nil
 }
 
+// RefreshPaths updates the index entries for the given files or directories (e.g. after they have been changed on
+// disk), adding new files and removing files that no longer exist or are ignored, without hashing the rest of the
+// pack. Paths must be in the same form as those found by walking the pack root. It returns whether the index changed.
+func (in *Index) RefreshPaths(paths []string) (bool, error) {
+	filter := in.Filter()
+	changed := false
+	for _, p := range paths {
+		rel, err := in.RelIndexPath(p)
+		if err != nil {
+			return changed, err
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return changed, err
+			}
+			changed = in.Files.removeTree(rel) || changed
+			continue
+		}
+		if !info.IsDir() {
+			if filter.Includes(p, false) {
+				fileChanged, err := in.refreshFile(p)
+				if err != nil {
+					return changed, err
+				}
+				changed = fileChanged || changed
+			} else {
+				changed = in.Files.removeTree(rel) || changed
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !filter.Includes(path, d.IsDir()) {
+				rel, err := in.RelIndexPath(path)
+				if err != nil {
+					return err
+				}
+				changed = in.Files.removeTree(rel) || changed
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			fileChanged, err := in.refreshFile(path)
+			changed = fileChanged || changed
+			return err
+		})
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// refreshFile updates the hash of a single file, returning whether it was added or its hash changed
+func (in *Index) refreshFile(path string) (bool, error) {
+	rel, err := in.RelIndexPath(path)
+	if err != nil {
+		return false, err
+	}
+	oldHash, oldFormat, found := in.Files.getHash(rel)
+	if err := in.updateFile(path); err != nil {
+		return false, err
+	}
+	newHash, newFormat, _ := in.Files.getHash(rel)
+	return !found || oldHash != newHash || oldFormat != newFormat, nil
+}
+
+// GetHash returns the hash and hash format of a file in the index, given its path in the index
+func (in Index) GetHash(path string) (string, string, bool) {
+	hash, format, found := in.Files.getHash(path)
+	if format == "" {
+		format = in.HashFormat
+	}
+	return hash, format, found
+}
+
+// Clone returns a copy of the index, which can be changed without affecting the original
+func (in Index) Clone() Index {
+	out := in
+	out.Files = in.Files.clone()
+	return out
+}
+
+// PackRoot returns the folder containing the index file, which paths in the index are relative to
+func (in Index) PackRoot() string {
+	return in.packRoot
+}
+
 // Write saves the index file
 func (in Index) Write() error {
-	// Convert to indexTomlRepresentation
-	rep := indexTomlRepresentation{
-		HashFormat: in.HashFormat,
-		Files:      in.Files.toTomlRep(),
-	}
+	return in.writeTo(in.indexFile)
+}
 
+// writeTo saves the index file to the given path, which may differ from the index file path when staging changes
+func (in Index) writeTo(path string) error {
 	// TODO: calculate and provide hash while writing?
-	f, err := os.Create(in.indexFile)
+	data, err := in.Marshal()
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(path, data, 0666)
+}
 
-	enc := toml.NewEncoder(f)
+// Marshal returns the contents of the index file, as written by Write
+func (in Index) Marshal() ([]byte, error) {
+	// Convert to indexTomlRepresentation
+	rep := indexTomlRepresentation{
+		HashFormat: in.HashFormat,
+		Files:      in.Files.toTomlRep(),
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
 	// Disable indentation
 	enc.Indent = ""
-	err = enc.Encode(rep)
-	if err != nil {
-		_ = f.Close()
-		return err
-	}
-	return f.Close()
+	err := enc.Encode(rep)
+	return buf.Bytes(), err
 }
 
 // RefreshFileWithHash updates a file in the index, given a file hash and whether it should be marked as metafile or not
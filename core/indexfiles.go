@@ -1,6 +1,7 @@
 package core
 
 import (
+	"maps"
 	"path"
 	"slices"
 	"strings"
@@ -120,6 +121,49 @@ func (f *IndexFiles) updateFileEntry(path string, format string, hash string, ma
 	}
 }
 
+// getHash returns the hash and hash format of a file, and whether it is in the index
+func (f IndexFiles) getHash(path string) (string, string, bool) {
+	switch file := f[path].(type) {
+	case *indexFile:
+		return file.Hash, file.HashFormat, true
+	case *indexFileMultipleAlias:
+		for _, v := range *file {
+			return v.Hash, v.HashFormat, true
+		}
+	}
+	return "", "", false
+}
+
+// removeTree removes a file, or every file in a folder, returning whether anything was removed
+func (f IndexFiles) removeTree(path string) bool {
+	removed := false
+	for p := range f {
+		if p == path || path == "." || strings.HasPrefix(p, path+"/") {
+			delete(f, p)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// clone returns a deep copy of the files in the index
+func (f IndexFiles) clone() IndexFiles {
+	out := make(IndexFiles, len(f))
+	for p, v := range f {
+		switch file := v.(type) {
+		case *indexFile:
+			c := *file
+			out[p] = &c
+		case *indexFileMultipleAlias:
+			c := maps.Clone(*file)
+			out[p] = &c
+		default:
+			panic("Unknown type in IndexFiles")
+		}
+	}
+	return out
+}
+
 type indexFilesTomlRepresentation []indexFile
 
 // toMemoryRep converts the TOML representation of IndexFiles to that used in memory
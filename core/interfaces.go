@@ -1,6 +1,9 @@
 package core
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // Updaters stores all the updaters that packwiz can use. Add your own update systems to this map, keyed by the configuration name.
 var Updaters = make(map[string]Updater)
@@ -25,6 +28,15 @@ type UpdateCheck struct {
 	// UpdateString is a string that details the update in some way to the user. Usually this will be in the form of
 	// a version change (1.0.0 -> 1.0.1), or a file name change (thanos-skin-1.0.0.jar -> thanos-skin-1.0.1.jar).
 	UpdateString string
+	// NewFileName is the file name that the mod will have after the update is carried out, if known
+	NewFileName string
+	// NewVersion describes the version that the mod will be updated to, with its changelog, as far as it is known
+	NewVersion Changelog
+	// LoadChangelog retrieves the body of the changelog of NewVersion, if it needs another request and so is only
+	// retrieved when it will be shown; it is nil if the body is already in NewVersion
+	LoadChangelog func() (string, error)
+	// ReleaseChannel is the release channel of the new version (release, beta or alpha), if known
+	ReleaseChannel string
 	// CachedState can be used to preserve per-mod state between CheckUpdate and DoUpdate (e.g. file metadata)
 	CachedState interface{}
 	// Error stores an error for this specific mod
@@ -53,3 +65,25 @@ type ManualDownload struct {
 	FileName string
 	URL      string
 }
+
+// ChangelogProviders stores the sources that mod changelogs can be retrieved from, keyed by the update configuration
+// name (as in Updaters)
+var ChangelogProviders = make(map[string]ChangelogProvider)
+
+// ChangelogProvider retrieves the changelogs of mod versions
+type ChangelogProvider interface {
+	// GetChangelogs returns the changelog of each version of a mod after the version in oldMod, up to and including the
+	// version in newMod, newest first. Both mods use this provider's update configuration.
+	GetChangelogs(oldMod *Mod, newMod *Mod) ([]Changelog, error)
+}
+
+// Changelog is the changelog of a single version of a mod
+type Changelog struct {
+	// Version is the version number or name of the file
+	Version string
+	Date    time.Time
+	// URL is a web page for the version, if there is one
+	URL string
+	// Body is the changelog in Markdown; HTML changelogs should be converted with HTMLToMarkdown
+	Body string
+}
@@ -1,12 +1,14 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -25,6 +27,15 @@ type Mod struct {
 	updateData map[string]interface{}
 
 	Option *ModOption `toml:"option,omitempty"`
+	// ReleaseChannel overrides the release-channel option of the pack for this mod
+	ReleaseChannel string `toml:"release-channel,omitempty"`
+	// Constraint limits the versions this mod can be updated to
+	Constraint *ModConstraint `toml:"constraint,omitempty"`
+	// Dependencies are the dependencies declared by the installed version of this mod
+	Dependencies []ModDependency `toml:"dependencies,omitempty"`
+	// AddedAsDependency is set for mods that were added automatically as a dependency of another mod, so they can be
+	// removed when nothing needs them anymore
+	AddedAsDependency bool `toml:"added-as-dependency,omitempty"`
 }
 
 const (
@@ -92,12 +103,21 @@ func (m *Mod) SetMetaPath(metaFile string) string {
 
 // Write saves the mod file, returning a hash format and the value of the hash of the saved file
 func (m Mod) Write() (string, string, error) {
-	f, err := os.Create(m.metaFile)
+	return m.writeTo(m.metaFile)
+}
+
+// writeTo saves the mod file to the given path, which may differ from the metadata file path when staging changes
+func (m Mod) writeTo(path string) (string, string, error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return "sha256", "", err
+	}
+	f, err := os.Create(path)
 	if err != nil {
 		// Attempt to create the containing directory
-		err2 := os.MkdirAll(filepath.Dir(m.metaFile), os.ModePerm)
+		err2 := os.MkdirAll(filepath.Dir(path), os.ModePerm)
 		if err2 == nil {
-			f, err = os.Create(m.metaFile)
+			f, err = os.Create(path)
 		}
 		if err != nil {
 			return "sha256", "", err
@@ -111,10 +131,7 @@ func (m Mod) Write() (string, string, error) {
 	}
 	w := io.MultiWriter(h, f)
 
-	enc := toml.NewEncoder(w)
-	// Disable indentation
-	enc.Indent = ""
-	err = enc.Encode(m)
+	_, err = w.Write(data)
 	hashString := h.HashToString(h.Sum(nil))
 	if err != nil {
 		_ = f.Close()
@@ -123,6 +140,16 @@ func (m Mod) Write() (string, string, error) {
 	return "sha256", hashString, f.Close()
 }
 
+// Marshal returns the contents of the metadata file for this mod, as written by Write
+func (m Mod) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	// Disable indentation
+	enc.Indent = ""
+	err := enc.Encode(m)
+	return buf.Bytes(), err
+}
+
 // GetParsedUpdateData can be used to retrieve updater-specific information after parsing a mod file
 func (m Mod) GetParsedUpdateData(updaterName string) (interface{}, bool) {
 	upd, ok := m.updateData[updaterName]
@@ -236,3 +263,32 @@ func NormalizeClientPlatforms(platforms []string) []string {
 	
 	return normalized
 }
+
+// The release channels that files can be chosen from; each channel also accepts the more stable channels before it
+const (
+	ReleaseChannelRelease = "release"
+	ReleaseChannelBeta    = "beta"
+	ReleaseChannelAlpha   = "alpha"
+)
+
+// ReleaseChannels lists the valid release channels, from most to least stable
+var ReleaseChannels = []string{ReleaseChannelRelease, ReleaseChannelBeta, ReleaseChannelAlpha}
+
+// ValidateReleaseChannel checks that a release channel is valid; an empty string is valid (the pack default is used)
+func ValidateReleaseChannel(channel string) error {
+	if channel == "" || slices.Contains(ReleaseChannels, channel) {
+		return nil
+	}
+	return fmt.Errorf("invalid release channel '%s'. Valid channels are: %s", channel, strings.Join(ReleaseChannels, ", "))
+}
+
+// ReleaseChannelAllows returns true if a file with the given release type can be used in the given release channel
+func ReleaseChannelAllows(channel string, releaseType string) bool {
+	channelIdx := slices.Index(ReleaseChannels, channel)
+	typeIdx := slices.Index(ReleaseChannels, releaseType)
+	if channelIdx < 0 || typeIdx < 0 {
+		// Unknown channels/types are not filtered
+		return true
+	}
+	return typeIdx <= channelIdx
+}
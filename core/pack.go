@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -30,6 +31,8 @@ type Pack struct {
 	Versions map[string]string                 `toml:"versions"`
 	Export   map[string]map[string]interface{} `toml:"export"`
 	Options  map[string]interface{}            `toml:"options"`
+	// packFile is the path the pack was loaded from; if empty, the pack-file option is used
+	packFile string
 }
 
 const CurrentPackFormat = "packwiz:1.1.0"
@@ -45,12 +48,36 @@ func mustParseConstraint(s string) *semver.Constraints {
 	return c
 }
 
-// LoadPack loads the modpack metadata to a Pack struct
+// LoadPack loads the modpack metadata to a Pack struct, from the file set by the pack-file option, and applies the
+// options set in the pack
 func LoadPack() (Pack, error) {
+	modpack, err := LoadPackFile(viper.GetString("pack-file"))
+	if err != nil {
+		return Pack{}, err
+	}
+
+	// Read options into viper
+	if modpack.Options != nil {
+		err := viper.MergeConfigMap(modpack.Options)
+		if err != nil {
+			return Pack{}, err
+		}
+	}
+	// Unknown release channels don't filter anything, so a typo would silently allow every file
+	if err := ValidateReleaseChannel(viper.GetString("release-channel")); err != nil {
+		return Pack{}, fmt.Errorf("invalid release-channel option: %w", err)
+	}
+	return modpack, nil
+}
+
+// LoadPackFile loads the modpack metadata from the given file. Unlike LoadPack, the options set in the pack are not
+// applied, so that multiple packs can be loaded at once.
+func LoadPackFile(packFile string) (Pack, error) {
 	var modpack Pack
-	if _, err := toml.DecodeFile(viper.GetString("pack-file"), &modpack); err != nil {
+	if _, err := toml.DecodeFile(packFile, &modpack); err != nil {
 		return Pack{}, err
 	}
+	modpack.packFile = packFile
 
 	// Check pack-format
 	if len(modpack.PackFormat) == 0 {
@@ -77,14 +104,6 @@ func LoadPack() (Pack, error) {
 	}
 	// TODO: suggest migration if necessary (primarily for 2.0.0)
 
-	// Read options into viper
-	if modpack.Options != nil {
-		err := viper.MergeConfigMap(modpack.Options)
-		if err != nil {
-			return Pack{}, err
-		}
-	}
-
 	if len(modpack.Index.File) == 0 {
 		modpack.Index.File = "index.toml"
 	}
@@ -93,11 +112,24 @@ func LoadPack() (Pack, error) {
 
 // LoadIndex attempts to load the index file of this modpack
 func (pack Pack) LoadIndex() (Index, error) {
-	if filepath.IsAbs(pack.Index.File) {
-		return LoadIndex(pack.Index.File)
+	indexFile := pack.Index.File
+	if !filepath.IsAbs(indexFile) {
+		indexFile = filepath.Join(filepath.Dir(pack.PackFile()), filepath.FromSlash(indexFile))
 	}
-	fileNative := filepath.FromSlash(pack.Index.File)
-	return LoadIndex(filepath.Join(filepath.Dir(viper.GetString("pack-file")), fileNative))
+	index, err := LoadIndex(indexFile)
+	if err != nil {
+		return Index{}, err
+	}
+	index.packFile = pack.PackFile()
+	return index, nil
+}
+
+// PackFile returns the path to the pack file
+func (pack Pack) PackFile() string {
+	if pack.packFile == "" {
+		return viper.GetString("pack-file")
+	}
+	return pack.packFile
 }
 
 // UpdateIndexHash recalculates the hash of the index file of this modpack
@@ -108,9 +140,17 @@ func (pack *Pack) UpdateIndexHash() error {
 		return nil
 	}
 
+	return pack.updateIndexHashFrom(pack.indexFilePath())
+}
+
+// indexFilePath returns the path to the index file on disk
+func (pack Pack) indexFilePath() string {
 	fileNative := filepath.FromSlash(pack.Index.File)
-	indexFile := filepath.Join(filepath.Dir(viper.GetString("pack-file")), fileNative)
+	return filepath.Join(filepath.Dir(pack.PackFile()), fileNative)
+}
 
+// updateIndexHashFrom sets the hash of the index file, reading it from the given path
+func (pack *Pack) updateIndexHashFrom(indexFile string) error {
 	f, err := os.Open(indexFile)
 	if err != nil {
 		return err
@@ -137,20 +177,26 @@ func (pack *Pack) UpdateIndexHash() error {
 
 // Write saves the pack file
 func (pack Pack) Write() error {
-	f, err := os.Create(viper.GetString("pack-file"))
+	return pack.writeTo(pack.PackFile())
+}
+
+// writeTo saves the pack file to the given path, which may differ from the pack file path when staging changes
+func (pack Pack) writeTo(path string) error {
+	data, err := pack.Marshal()
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(path, data, 0666)
+}
 
-	enc := toml.NewEncoder(f)
+// Marshal returns the contents of the pack file, as written by Write
+func (pack Pack) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
 	// Disable indentation
 	enc.Indent = ""
-	err = enc.Encode(pack)
-	if err != nil {
-		_ = f.Close()
-		return err
-	}
-	return f.Close()
+	err := enc.Encode(pack)
+	return buf.Bytes(), err
 }
 
 // GetMCVersion gets the version of Minecraft this pack uses, if it has been correctly specified
@@ -181,6 +227,20 @@ func (pack Pack) GetSupportedMCVersions() ([]string, error) {
 	return allVersionsDeduped, nil
 }
 
+// GetReleaseChannel gets the release channel that new files should be chosen from for a mod (which may be nil, for
+// new mods), using the release-channel option of the pack unless the mod overrides it
+func (pack Pack) GetReleaseChannel(mod *Mod) string {
+	if mod != nil && mod.ReleaseChannel != "" {
+		return mod.ReleaseChannel
+	}
+	channel := viper.GetString("release-channel")
+	if channel == "" {
+		// Defaults to allowing all files
+		return ReleaseChannelAlpha
+	}
+	return channel
+}
+
 func (pack Pack) GetPackName() string {
 	if pack.Name == "" {
 		return "export"
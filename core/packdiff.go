@@ -0,0 +1,223 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/unascribed/FlexVer/go/flexver"
+)
+
+// PackContents is a pack with its index and every metadata file, loaded from a pack file (which doesn't have to be the
+// current pack)
+type PackContents struct {
+	Pack  Pack
+	Index Index
+	Mods  []*Mod
+}
+
+// LoadPackContents loads a pack file, its index and every metadata file in it
+func LoadPackContents(packFile string) (PackContents, error) {
+	pack, err := LoadPackFile(packFile)
+	if err != nil {
+		return PackContents{}, err
+	}
+	index, err := pack.LoadIndex()
+	if err != nil {
+		return PackContents{}, err
+	}
+	mods, err := index.LoadAllMods()
+	if err != nil {
+		return PackContents{}, err
+	}
+	return PackContents{Pack: pack, Index: index, Mods: mods}, nil
+}
+
+// The kinds of change in a ModChange or FileChange
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeUpgraded   = "upgraded"
+	ChangeDowngraded = "downgraded"
+	// ChangeUpdated is used when a file has changed, but it can't be told whether it is newer or older
+	ChangeUpdated = "updated"
+	// ChangeUnchanged is used for mods where only the side has changed
+	ChangeUnchanged = "unchanged"
+)
+
+// ModChange is a metadata file that differs between two packs
+type ModChange struct {
+	Kind string
+	// Old and New are the metadata in each pack; Old is nil for added mods and New is nil for removed mods
+	Old *Mod
+	New *Mod
+	// SideChanged is set if the mod is in both packs, with different sides
+	SideChanged bool
+}
+
+// FileChange is a file other than a metadata file (e.g. a config file) that differs between two packs
+type FileChange struct {
+	Kind string
+	// Path is the path of the file in the index
+	Path string
+}
+
+// VersionChange is a component in pack.toml (e.g. minecraft, or a mod loader) with a different version in each pack;
+// Old or New are empty if the component isn't in that pack
+type VersionChange struct {
+	Component string
+	Old       string
+	New       string
+}
+
+// PackDiff is the set of differences between two packs
+type PackDiff struct {
+	Versions []VersionChange
+	Mods     []ModChange
+	Files    []FileChange
+}
+
+// Empty returns true if there are no differences
+func (d PackDiff) Empty() bool {
+	return len(d.Versions) == 0 && len(d.Mods) == 0 && len(d.Files) == 0
+}
+
+// ModIdentity returns a key identifying the project that a mod comes from, so that different versions of it can be
+// matched: the source and project ID for mods from a source with a dependency resolver (Modrinth, CurseForge), the
+// repository for GitHub mods, or otherwise the download URL
+func ModIdentity(mod *Mod) string {
+	if source, id, ok := GetModSource(mod); ok {
+		return source + ":" + id
+	}
+	if gh, ok := mod.Update["github"]; ok {
+		if slug, ok := gh["slug"].(string); ok && slug != "" {
+			return "github:" + strings.ToLower(slug)
+		}
+	}
+	return "url:" + mod.Download.URL
+}
+
+// DiffPacks finds the differences between two packs. Mods are matched by ModIdentity, or by the path of their metadata
+// file if they have changed to a different source or URL.
+func DiffPacks(oldPack PackContents, newPack PackContents) PackDiff {
+	var diff PackDiff
+
+	components := make(map[string]bool)
+	for k := range oldPack.Pack.Versions {
+		components[k] = true
+	}
+	for k := range newPack.Pack.Versions {
+		components[k] = true
+	}
+	for component := range components {
+		oldVersion, newVersion := oldPack.Pack.Versions[component], newPack.Pack.Versions[component]
+		if oldVersion != newVersion {
+			diff.Versions = append(diff.Versions, VersionChange{Component: component, Old: oldVersion, New: newVersion})
+		}
+	}
+	sort.Slice(diff.Versions, func(i, j int) bool {
+		return diff.Versions[i].Component < diff.Versions[j].Component
+	})
+
+	oldByIdentity := make(map[string]*Mod, len(oldPack.Mods))
+	for _, m := range oldPack.Mods {
+		oldByIdentity[ModIdentity(m)] = m
+	}
+	matched := make(map[*Mod]bool)
+	var added []*Mod
+	for _, m := range newPack.Mods {
+		if old, ok := oldByIdentity[ModIdentity(m)]; ok && !matched[old] {
+			matched[old] = true
+			diff.addModChange(old, m)
+		} else {
+			added = append(added, m)
+		}
+	}
+
+	// Match the remaining mods by the path of their metadata file
+	oldByPath := make(map[string]*Mod)
+	for _, m := range oldPack.Mods {
+		if !matched[m] {
+			if p, err := oldPack.Index.RelIndexPath(m.GetFilePath()); err == nil {
+				oldByPath[p] = m
+			}
+		}
+	}
+	for _, m := range added {
+		p, err := newPack.Index.RelIndexPath(m.GetFilePath())
+		if old, ok := oldByPath[p]; err == nil && ok && !matched[old] {
+			matched[old] = true
+			diff.addModChange(old, m)
+			continue
+		}
+		diff.Mods = append(diff.Mods, ModChange{Kind: ChangeAdded, New: m})
+	}
+	for _, m := range oldPack.Mods {
+		if !matched[m] {
+			diff.Mods = append(diff.Mods, ModChange{Kind: ChangeRemoved, Old: m})
+		}
+	}
+	sort.SliceStable(diff.Mods, func(i, j int) bool {
+		return strings.ToLower(diff.Mods[i].Name()) < strings.ToLower(diff.Mods[j].Name())
+	})
+
+	for p, f := range newPack.Index.Files {
+		if f.IsMetaFile() {
+			continue
+		}
+		oldHash, oldFormat, found := oldPack.Index.GetHash(p)
+		if !found {
+			diff.Files = append(diff.Files, FileChange{Kind: ChangeAdded, Path: p})
+			continue
+		}
+		newHash, newFormat, _ := newPack.Index.GetHash(p)
+		if oldFormat != newFormat || !strings.EqualFold(oldHash, newHash) {
+			diff.Files = append(diff.Files, FileChange{Kind: ChangeUpdated, Path: p})
+		}
+	}
+	for p, f := range oldPack.Index.Files {
+		if f.IsMetaFile() {
+			continue
+		}
+		if _, _, found := newPack.Index.GetHash(p); !found {
+			diff.Files = append(diff.Files, FileChange{Kind: ChangeRemoved, Path: p})
+		}
+	}
+	sort.Slice(diff.Files, func(i, j int) bool {
+		return diff.Files[i].Path < diff.Files[j].Path
+	})
+	return diff
+}
+
+// addModChange adds a change for a mod that is in both packs, if it is different
+func (d *PackDiff) addModChange(old *Mod, new *Mod) {
+	change := ModChange{Kind: ChangeUnchanged, Old: old, New: new, SideChanged: normalizeDiffSide(old.Side) != normalizeDiffSide(new.Side)}
+	if old.FileName != new.FileName || old.Download.HashFormat != new.Download.HashFormat ||
+		!strings.EqualFold(old.Download.Hash, new.Download.Hash) {
+		switch {
+		case flexver.Less(old.FileName, new.FileName):
+			change.Kind = ChangeUpgraded
+		case flexver.Less(new.FileName, old.FileName):
+			change.Kind = ChangeDowngraded
+		default:
+			change.Kind = ChangeUpdated
+		}
+	}
+	if change.Kind != ChangeUnchanged || change.SideChanged {
+		d.Mods = append(d.Mods, change)
+	}
+}
+
+func normalizeDiffSide(side string) string {
+	if side == EmptySide {
+		return UniversalSide
+	}
+	return side
+}
+
+// Name returns the name of the mod, from the new pack if it is in both
+func (c ModChange) Name() string {
+	if c.New != nil {
+		return c.New.Name
+	}
+	return c.Old.Name
+}
@@ -0,0 +1,317 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// transactionTempSuffix is appended to staged and backup files, so they can be ignored if a transaction is interrupted
+const transactionTempSuffix = ".packwiz-tmp"
+
+// The kinds of file changed by a transaction; files are committed in this order, so the pack file (which contains the
+// hash of the index, which contains the hashes of metadata files) is always replaced last
+const (
+	txKindFile = iota
+	txKindIndex
+	txKindPack
+)
+
+// Transaction stages changes to metadata files, the index and the pack file in temporary files next to their
+// destinations, so that they can all be applied at once with Commit, or discarded with Rollback.
+// Changes made through a Transaction are not visible on disk until it is committed.
+type Transaction struct {
+	entries map[string]*txEntry
+	order   []string
+	// Directories created while staging, removed on rollback if they are still empty
+	createdDirs []string
+	done        bool
+}
+
+type txEntry struct {
+	dest   string
+	staged string // Empty if the file is to be removed
+	kind   int
+	backup string
+	// Whether dest has been replaced/removed by Commit
+	applied bool
+}
+
+// NewTransaction creates an empty Transaction
+func NewTransaction() *Transaction {
+	return &Transaction{entries: make(map[string]*txEntry)}
+}
+
+func (tx *Transaction) entry(dest string, kind int) (*txEntry, error) {
+	if tx.done {
+		return nil, errors.New("transaction has already been committed or rolled back")
+	}
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := tx.entries[dest]
+	if !ok {
+		e = &txEntry{dest: dest, kind: kind}
+		tx.entries[dest] = e
+		tx.order = append(tx.order, dest)
+	}
+	return e, nil
+}
+
+// stage creates (or truncates) the staging file for the given destination, and returns its path
+func (tx *Transaction) stage(dest string, kind int) (string, error) {
+	e, err := tx.entry(dest, kind)
+	if err != nil {
+		return "", err
+	}
+	if e.staged != "" {
+		return e.staged, nil
+	}
+
+	dir := filepath.Dir(e.dest)
+	if err := tx.mkdirAll(dir); err != nil {
+		return "", err
+	}
+	staged, err := reserveTempName(e.dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", e.dest, err)
+	}
+	// Remove the reserved file so it is recreated with the usual permissions when written
+	_ = os.Remove(staged)
+	e.staged = staged
+	return e.staged, nil
+}
+
+// mkdirAll creates a directory and its parents, recording which ones were created
+func (tx *Transaction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tx.createdDirs = append(tx.createdDirs, missing...)
+	return nil
+}
+
+// stagedPath returns the path that the current contents of a file should be read from, taking staged changes into account
+func (tx *Transaction) stagedPath(dest string) (string, bool) {
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return dest, true
+	}
+	e, ok := tx.entries[abs]
+	if !ok {
+		return dest, true
+	}
+	if e.staged == "" {
+		return "", false
+	}
+	return e.staged, true
+}
+
+// WriteMod stages a metadata file, returning a hash format and the value of the hash of the staged file
+func (tx *Transaction) WriteMod(m *Mod) (string, string, error) {
+	staged, err := tx.stage(m.metaFile, txKindFile)
+	if err != nil {
+		return "sha256", "", err
+	}
+	return m.writeTo(staged)
+}
+
+// RemoveFile stages the removal of a file (e.g. a metadata file); it is not removed from the index
+func (tx *Transaction) RemoveFile(path string) error {
+	e, err := tx.entry(path, txKindFile)
+	if err != nil {
+		return err
+	}
+	if e.staged != "" {
+		_ = os.Remove(e.staged)
+		e.staged = ""
+		return nil
+	}
+	if _, err := os.Stat(e.dest); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteIndex stages the index file
+func (tx *Transaction) WriteIndex(in Index) error {
+	staged, err := tx.stage(in.indexFile, txKindIndex)
+	if err != nil {
+		return err
+	}
+	return in.writeTo(staged)
+}
+
+// UpdateIndexHash recalculates the hash of the index file of a modpack, using the staged index if there is one
+func (tx *Transaction) UpdateIndexHash(pack *Pack) error {
+	if viper.GetBool("no-internal-hashes") {
+		return pack.UpdateIndexHash()
+	}
+	indexFile, ok := tx.stagedPath(pack.indexFilePath())
+	if !ok {
+		return errors.New("index file has been removed")
+	}
+	return pack.updateIndexHashFrom(indexFile)
+}
+
+// WritePack stages the pack file
+func (tx *Transaction) WritePack(pack Pack) error {
+	staged, err := tx.stage(pack.PackFile(), txKindPack)
+	if err != nil {
+		return err
+	}
+	return pack.writeTo(staged)
+}
+
+// WriteIndexAndPack stages the index, updates the index hash in the pack and stages the pack file
+func (tx *Transaction) WriteIndexAndPack(in Index, pack *Pack) error {
+	if err := tx.WriteIndex(in); err != nil {
+		return err
+	}
+	if err := tx.UpdateIndexHash(pack); err != nil {
+		return err
+	}
+	return tx.WritePack(*pack)
+}
+
+// Commit replaces every destination file with its staged contents (or removes it). If any file fails to be replaced,
+// the files that have already been replaced are restored and an error is returned.
+func (tx *Transaction) Commit() error {
+	if tx.done {
+		return errors.New("transaction has already been committed or rolled back")
+	}
+
+	entries := tx.commitOrder()
+	for _, e := range entries {
+		if err := e.apply(); err != nil {
+			tx.restore(entries)
+			tx.Rollback()
+			return fmt.Errorf("failed to commit changes to %s (all changes have been reverted): %w", e.dest, err)
+		}
+	}
+
+	// Everything has been replaced; the backups are no longer needed
+	for _, e := range entries {
+		if e.backup != "" {
+			_ = os.Remove(e.backup)
+		}
+	}
+	tx.done = true
+	return nil
+}
+
+// commitOrder returns the entries in the order they are committed: other files first, then the index, then the pack
+// file, otherwise in the order they were staged
+func (tx *Transaction) commitOrder() []*txEntry {
+	entries := make([]*txEntry, len(tx.order))
+	for i, dest := range tx.order {
+		entries[i] = tx.entries[dest]
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].kind < entries[j].kind
+	})
+	return entries
+}
+
+// apply moves the existing file out of the way (if there is one) and moves the staged file into place
+func (e *txEntry) apply() error {
+	if _, err := os.Lstat(e.dest); err == nil {
+		backup, err := reserveTempName(e.dest)
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(e.dest, backup); err != nil {
+			_ = os.Remove(backup)
+			return err
+		}
+		e.backup = backup
+	} else if e.staged == "" {
+		// Nothing to remove
+		return nil
+	}
+	e.applied = true
+
+	if e.staged != "" {
+		if err := os.Rename(e.staged, e.dest); err != nil {
+			return err
+		}
+		e.staged = ""
+	}
+	return nil
+}
+
+// restore puts back the original files for every entry that has been applied
+func (tx *Transaction) restore(entries []*txEntry) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !e.applied {
+			continue
+		}
+		if e.backup != "" {
+			_ = os.Rename(e.backup, e.dest)
+			e.backup = ""
+		} else {
+			_ = os.Remove(e.dest)
+		}
+		e.applied = false
+	}
+}
+
+// reserveTempName creates an empty temporary file next to a path, so that its name can be used as a rename target
+func reserveTempName(path string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*"+transactionTempSuffix)
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		_ = os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// Rollback discards all staged changes. It does nothing if the transaction has already been committed, so it is
+// safe to defer (or call on every error path) after creating a Transaction.
+func (tx *Transaction) Rollback() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	for _, dest := range tx.order {
+		e := tx.entries[dest]
+		if e.staged != "" {
+			_ = os.Remove(e.staged)
+			e.staged = ""
+		}
+		if e.backup != "" {
+			_ = os.Remove(e.backup)
+			e.backup = ""
+		}
+	}
+	// Remove directories created for staged files, deepest first (only succeeds if they are empty)
+	sort.Slice(tx.createdDirs, func(i, j int) bool {
+		return len(tx.createdDirs[i]) > len(tx.createdDirs[j])
+	})
+	for _, dir := range tx.createdDirs {
+		_ = os.Remove(dir)
+	}
+}
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestPack creates a pack with an empty index and one metadata file in a temporary folder
+func newTestPack(t *testing.T) (string, Pack, Index, *Mod) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "pack.toml"), "name = \"Test\"\npack-format = \""+CurrentPackFormat+
+		"\"\n\n[index]\nfile = \"index.toml\"\nhash-format = \"sha256\"\n\n[versions]\nminecraft = \"1.20.1\"\n")
+	writeTestFile(t, filepath.Join(dir, "index.toml"), "hash-format = \"sha256\"\n")
+	writeTestFile(t, filepath.Join(dir, "mods", "a.pw.toml"), "name = \"A\"\nfilename = \"a.jar\"\n")
+
+	pack, err := LoadPackFile(filepath.Join(dir, "pack.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := pack.LoadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := LoadMod(filepath.Join(dir, "mods", "a.pw.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, pack, index, &mod
+}
+
+func writeTestFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// snapshotDir returns the contents of every file in a folder, keyed by relative path
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = readTestFile(t, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func assertSameFiles(t *testing.T, got map[string]string, want map[string]string) {
+	t.Helper()
+	for name, data := range want {
+		if got[name] != data {
+			t.Errorf("%s = %q, want %q", name, got[name], data)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("unexpected file %s", name)
+		}
+	}
+}
+
+func TestTransactionCommit(t *testing.T) {
+	dir, pack, index, mod := newTestPack(t)
+	mod.Name = "A (updated)"
+	newMod := &Mod{Name: "B", FileName: "b.jar"}
+	newMod.SetMetaPath(filepath.Join(dir, "mods", "new", "b.pw.toml"))
+	before := snapshotDir(t, dir)
+
+	tx := NewTransaction()
+	defer tx.Rollback()
+	for _, m := range []*Mod{mod, newMod} {
+		format, hash, err := tx.WriteMod(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := index.RefreshFileWithHash(m.GetFilePath(), format, hash, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.WriteIndexAndPack(index, &pack); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is changed until the transaction is committed
+	staged := snapshotDir(t, dir)
+	for name := range staged {
+		if strings.HasSuffix(name, transactionTempSuffix) {
+			delete(staged, name)
+		}
+	}
+	assertSameFiles(t, staged, before)
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*Mod{mod, newMod} {
+		want, err := m.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readTestFile(t, m.GetFilePath()); got != string(want) {
+			t.Errorf("%s = %q, want %q", m.GetFilePath(), got, want)
+		}
+	}
+	indexData, err := index.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "index.toml")); got != string(indexData) {
+		t.Errorf("index.toml = %q, want %q", got, indexData)
+	}
+	if !strings.Contains(string(indexData), "mods/new/b.pw.toml") {
+		t.Errorf("index.toml doesn't contain the new metadata file")
+	}
+	packData, err := pack.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "pack.toml")); got != string(packData) {
+		t.Errorf("pack.toml = %q, want %q", got, packData)
+	}
+	h, err := GetHashImpl("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write(indexData)
+	if indexHash := h.HashToString(h.Sum(nil)); pack.Index.Hash != indexHash {
+		t.Errorf("index hash in pack.toml = %s, want %s", pack.Index.Hash, indexHash)
+	}
+	for name := range snapshotDir(t, dir) {
+		if strings.HasSuffix(name, transactionTempSuffix) {
+			t.Errorf("temporary file %s was not removed", name)
+		}
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Error("second Commit() succeeded, want error")
+	}
+}
+
+func TestTransactionCommitOrder(t *testing.T) {
+	dir, pack, index, mod := newTestPack(t)
+	tx := NewTransaction()
+	defer tx.Rollback()
+	// Stage the files in the reverse of the order they have to be committed in
+	if err := tx.WritePack(pack); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WriteIndex(index); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tx.WriteMod(mod); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveFile(filepath.Join(dir, "mods", "a.pw.toml")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "config.txt"), "config")
+	if err := tx.RemoveFile(filepath.Join(dir, "config.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range tx.commitOrder() {
+		rel, err := filepath.Rel(dir, e.dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"mods/a.pw.toml", "config.txt", "index.toml", "pack.toml"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("commitOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestTransactionCommitFailure(t *testing.T) {
+	dir, pack, index, mod := newTestPack(t)
+	before := snapshotDir(t, dir)
+
+	tx := NewTransaction()
+	defer tx.Rollback()
+	mod.Name = "A (updated)"
+	format, hash, err := tx.WriteMod(mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.RefreshFileWithHash(mod.GetFilePath(), format, hash, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WriteIndexAndPack(index, &pack); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "config.txt"), "config")
+	before["config.txt"] = "config"
+	if err := tx.RemoveFile(filepath.Join(dir, "config.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the last rename fail, after the pack file has been moved to its backup
+	packFile, err := filepath.Abs(filepath.Join(dir, "pack.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(tx.entries[packFile].staged); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Fatal("Commit() succeeded, want error")
+	}
+	// Every file is restored, and no temporary files are left behind
+	assertSameFiles(t, snapshotDir(t, dir), before)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	dir, pack, index, mod := newTestPack(t)
+	before := snapshotDir(t, dir)
+
+	tx := NewTransaction()
+	mod.Name = "A (updated)"
+	if _, _, err := tx.WriteMod(mod); err != nil {
+		t.Fatal(err)
+	}
+	newMod := &Mod{Name: "B", FileName: "b.jar"}
+	newMod.SetMetaPath(filepath.Join(dir, "mods", "new", "nested", "b.pw.toml"))
+	if _, _, err := tx.WriteMod(newMod); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WriteIndexAndPack(index, &pack); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveFile(filepath.Join(dir, "index.toml")); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	assertSameFiles(t, snapshotDir(t, dir), before)
+	if _, err := os.Stat(filepath.Join(dir, "mods", "new")); !os.IsNotExist(err) {
+		t.Errorf("created folder mods/new was not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mods")); err != nil {
+		t.Errorf("existing folder mods was removed: %v", err)
+	}
+	if _, _, err := tx.WriteMod(mod); err == nil {
+		t.Error("WriteMod() after Rollback() succeeded, want error")
+	}
+	// Rolling back again (e.g. when deferred) does nothing
+	tx.Rollback()
+}
+
+func TestTransactionRemoveFile(t *testing.T) {
+	dir, pack, index, mod := newTestPack(t)
+	metaFile := mod.GetFilePath()
+
+	tx := NewTransaction()
+	defer tx.Rollback()
+	if err := tx.RemoveFile(filepath.Join(dir, "mods", "missing.pw.toml")); err == nil {
+		t.Error("RemoveFile() of a missing file succeeded, want error")
+	}
+	if err := tx.RemoveFile(metaFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.RemoveFile(metaFile); err != nil {
+		t.Fatal(err)
+	}
+	// A file that is written and then removed in the same transaction is never created
+	newMod := &Mod{Name: "B", FileName: "b.jar"}
+	newMod.SetMetaPath(filepath.Join(dir, "mods", "b.pw.toml"))
+	if _, _, err := tx.WriteMod(newMod); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.RemoveFile(newMod.GetFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WriteIndexAndPack(index, &pack); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(metaFile); err != nil {
+		t.Errorf("file was removed before Commit(): %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(metaFile); !os.IsNotExist(err) {
+		t.Errorf("removed file still exists: %v", err)
+	}
+	files := snapshotDir(t, dir)
+	want := []string{"index.toml", "pack.toml"}
+	if len(files) != len(want) {
+		t.Errorf("files after Commit() = %v, want %v", files, want)
+	}
+}
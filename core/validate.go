@@ -0,0 +1,60 @@
+package core
+
+import (
+	"slices"
+	"sort"
+)
+
+// ModValidators stores the sources that can check mods against their source's API, keyed by the name of the matching
+// updater
+var ModValidators = make(map[string]ModValidator)
+
+// ModValidation is the result of checking a mod against its source
+type ModValidation struct {
+	// Errors are problems that stop the mod from being installed (e.g. the project or file has been deleted)
+	Errors []string
+	// Warnings are problems that may stop the mod from working (e.g. the file isn't marked as supporting the pack's
+	// Minecraft version), or that mean it may not be updated in future (e.g. the project has been archived)
+	Warnings []string
+}
+
+// ModValidator checks mods against the API of a single source
+type ModValidator interface {
+	// ValidateMods checks that the file of each mod is still available, and is marked as compatible with the pack's
+	// Minecraft version and loaders, and that its project hasn't been archived. The mods must all have been installed
+	// from this source. The returned slice has the same length as the given slice.
+	ValidateMods(mods []*Mod, pack Pack) ([]ModValidation, error)
+}
+
+// GetModValidator returns the name and validator of the source a mod was installed from, or false if its source can't
+// validate it
+func GetModValidator(mod *Mod) (string, ModValidator, bool) {
+	keys := make([]string, 0, len(mod.Update))
+	for k := range mod.Update {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if validator, ok := ModValidators[k]; ok {
+			return k, validator, true
+		}
+	}
+	return "", nil, false
+}
+
+// LoadersCompatible returns true if any of the loaders a file is marked as supporting is compatible with the pack's
+// loaders (see Pack.GetCompatibleLoaders); files that aren't marked with any mod loader (e.g. resource packs) are
+// always compatible
+func LoadersCompatible(fileLoaders []string, pack Pack) bool {
+	packLoaders := pack.GetCompatibleLoaders()
+	hasModLoader := false
+	for _, v := range fileLoaders {
+		if _, ok := ModLoaders[v]; ok {
+			hasModLoader = true
+			if slices.Contains(packLoaders, v) {
+				return true
+			}
+		}
+	}
+	return !hasModLoader
+}
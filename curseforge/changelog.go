@@ -0,0 +1,139 @@
+package curseforge
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type cfChangelogProvider struct{}
+
+// GetChangelogs returns the changelogs of the files of the project uploaded after the old file, up to and including the
+// new file, for the same mod loader and one of the same Minecraft versions as the new file
+func (cfChangelogProvider) GetChangelogs(oldMod *core.Mod, newMod *core.Mod) ([]core.Changelog, error) {
+	oldRaw, ok := oldMod.GetParsedUpdateData("curseforge")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	newRaw, ok := newMod.GetParsedUpdateData("curseforge")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	oldData, newData := oldRaw.(cfUpdateData), newRaw.(cfUpdateData)
+
+	info, err := cfDefaultClient.getModInfo(newData.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	fileInfos, err := cfDefaultClient.getFileInfoMultiple([]uint32{oldData.FileID, newData.FileID})
+	if err != nil {
+		return nil, err
+	}
+	var oldFile, newFile *modFileInfo
+	for i, v := range fileInfos {
+		if v.ID == oldData.FileID {
+			oldFile = &fileInfos[i]
+		}
+		if v.ID == newData.FileID {
+			newFile = &fileInfos[i]
+		}
+	}
+	if newFile == nil {
+		return nil, fmt.Errorf("file %d of %s not found", newData.FileID, newMod.Name)
+	}
+
+	var files []modFileInfo
+	// The files in between can't be found if the old file is from another project, or has been deleted
+	if oldFile == nil || oldData.ProjectID != newData.ProjectID {
+		files = []modFileInfo{*newFile}
+	} else if newFile.Date.After(oldFile.Date) {
+		loader := modloaderTypeAny
+		for i, name := range modloaderNames {
+			if i > 0 && slices.Contains(newFile.GameVersions, name) {
+				if loader != modloaderTypeAny {
+					// Files for more than one loader; don't filter by loader
+					loader = modloaderTypeAny
+					break
+				}
+				loader = modloaderType(i)
+			}
+		}
+		// Mods often publish a file for each Minecraft version they support; skip files for other versions
+		mcVersions := slices.DeleteFunc(slices.Clone(newFile.GameVersions), func(v string) bool {
+			return !isMcVersion(v)
+		})
+		err = cfDefaultClient.getModFiles(newData.ProjectID, loader, func(page []modFileInfo) bool {
+			for _, v := range page {
+				if len(mcVersions) > 0 && !slices.ContainsFunc(v.GameVersions, func(gv string) bool {
+					return slices.Contains(mcVersions, gv)
+				}) {
+					continue
+				}
+				if v.Date.After(oldFile.Date) && !v.Date.After(newFile.Date) {
+					files = append(files, v)
+				}
+			}
+			// Files are listed newest first, so stop once the old file has been reached
+			return len(page) > 0 && page[len(page)-1].Date.After(oldFile.Date)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Date.After(files[j].Date)
+	})
+
+	changelogs := make([]core.Changelog, 0, len(files))
+	for _, v := range files {
+		body, err := cfDefaultClient.getFileChangelog(newData.ProjectID, v.ID)
+		if err != nil {
+			return nil, err
+		}
+		changelog := core.Changelog{
+			Version: v.FriendlyName,
+			Date:    v.Date,
+			Body:    core.HTMLToMarkdown(body),
+		}
+		if info.Links.WebsiteURL != "" {
+			changelog.URL = info.Links.WebsiteURL + "/files/" + strconv.FormatUint(uint64(v.ID), 10)
+		}
+		changelogs = append(changelogs, changelog)
+	}
+	return changelogs, nil
+}
+
+// isMcVersion checks if an entry in the game versions of a file is a Minecraft version (e.g. "1.20.1" or
+// "1.20-Snapshot"), rather than a loader, side or Java version
+func isMcVersion(gameVersion string) bool {
+	return len(gameVersion) > 0 && gameVersion[0] >= '0' && gameVersion[0] <= '9'
+}
+
+// newFileChangelog describes the file a mod is being updated to, without the body of its changelog (see
+// loadFileChangelog); the file info may be nil, in which case only the file name is known
+func newFileChangelog(info modInfo, fileID uint32, fileInfoData *modFileInfo, fileName string) core.Changelog {
+	changelog := core.Changelog{Version: fileName}
+	if fileInfoData != nil {
+		changelog.Version = fileInfoData.FriendlyName
+		changelog.Date = fileInfoData.Date
+	}
+	if info.Links.WebsiteURL != "" {
+		changelog.URL = info.Links.WebsiteURL + "/files/" + strconv.FormatUint(uint64(fileID), 10)
+	}
+	return changelog
+}
+
+// loadFileChangelog returns a function that retrieves the changelog of a file, for core.UpdateCheck.LoadChangelog
+func loadFileChangelog(modID uint32, fileID uint32) func() (string, error) {
+	return func() (string, error) {
+		body, err := cfDefaultClient.getFileChangelog(modID, fileID)
+		if err != nil {
+			return "", err
+		}
+		return core.HTMLToMarkdown(body), nil
+	}
+}
@@ -28,7 +28,11 @@ var curseforgeCmd = &cobra.Command{
 func init() {
 	cmd.Add(curseforgeCmd)
 	core.Updaters["curseforge"] = cfUpdater{}
+	core.DependencyResolvers["curseforge"] = cfDependencyResolver{}
 	core.MetaDownloaders["curseforge"] = cfDownloader{}
+	core.FileIdentifiers["curseforge"] = cfFileIdentifier{}
+	core.ChangelogProviders["curseforge"] = cfChangelogProvider{}
+	core.ModValidators["curseforge"] = cfModValidator{}
 }
 
 var snapshotVersionRegex = regexp.MustCompile(`(?:Snapshot )?(\d+)w0?(0|[1-9]\d*)([a-z])`)
@@ -176,10 +180,19 @@ func getPathForFile(gameID uint32, classID uint32, categoryID uint32, slug strin
 	return filepath.Join(viper.GetString("meta-folder-base"), metaFolder, slug+core.MetaExtension)
 }
 
-func createModFile(modInfo modInfo, fileInfo modFileInfo, index *core.Index, optionalDisabled bool, disabledClientPlatforms []string) error {
+func createModFile(modInfo modInfo, fileInfo modFileInfo, index *core.Index, tx *core.Transaction, optionalDisabled bool, disabledClientPlatforms []string) error {
+	modMeta, err := newModFile(modInfo, fileInfo, optionalDisabled, disabledClientPlatforms)
+	if err != nil {
+		return err
+	}
+	return writeModFile(&modMeta, index, tx)
+}
+
+// newModFile creates the metadata for a file of a project, without saving it
+func newModFile(modInfo modInfo, fileInfo modFileInfo, optionalDisabled bool, disabledClientPlatforms []string) (core.Mod, error) {
 	// Validate and normalize disabled client platforms
 	if err := core.ValidateClientPlatforms(disabledClientPlatforms); err != nil {
-		return fmt.Errorf("platform validation error: %v", err)
+		return core.Mod{}, fmt.Errorf("platform validation error: %v", err)
 	}
 	disabledClientPlatforms = core.NormalizeClientPlatforms(disabledClientPlatforms)
 
@@ -191,7 +204,7 @@ func createModFile(modInfo modInfo, fileInfo modFileInfo, index *core.Index, opt
 		FileID:    fileInfo.ID,
 	}.ToMap()
 	if err != nil {
-		return err
+		return core.Mod{}, err
 	}
 
 	hash, hashFormat := fileInfo.getBestHash()
@@ -217,19 +230,23 @@ func createModFile(modInfo modInfo, fileInfo modFileInfo, index *core.Index, opt
 		Option: optional,
 		Update: updateMap,
 	}
-	path := modMeta.SetMetaPath(getPathForFile(modInfo.GameID, modInfo.ClassID, modInfo.PrimaryCategoryID, modInfo.Slug))
+	modMeta.SetMetaPath(getPathForFile(modInfo.GameID, modInfo.ClassID, modInfo.PrimaryCategoryID, modInfo.Slug))
+	return modMeta, nil
+}
 
+// writeModFile stages a metadata file and adds it to the index
+func writeModFile(modMeta *core.Mod, index *core.Index, tx *core.Transaction) error {
 	// If the file already exists, this will overwrite it!!!
 	// TODO: Should this be improved?
 	// Current strategy is to go ahead and do stuff without asking, with the assumption that you are using
 	// VCS anyway.
 
-	format, hash, err := modMeta.Write()
+	format, hash, err := tx.WriteMod(modMeta)
 	if err != nil {
 		return err
 	}
 
-	return index.RefreshFileWithHash(path, format, hash, true)
+	return index.RefreshFileWithHash(modMeta.GetFilePath(), format, hash, true)
 }
 
 func getSearchLoaderType(pack core.Pack) modloaderType {
@@ -292,74 +309,108 @@ func filterFileInfoLoaderIndex(packLoaders []string, fileInfoData modFileInfo) (
 	}
 }
 
+// fileRank is how preferable a file is when finding the latest file
+type fileRank struct {
+	mcVerIdx  int
+	loaderIdx modloaderType
+	id        uint32
+}
+
+// compare returns a positive number if the file is preferred over the other file
+func (r fileRank) compare(other fileRank) int64 {
+	// Compare first by Minecraft version (prefer higher indexes of mcVersions)
+	compare := int64(r.mcVerIdx - other.mcVerIdx)
+	if compare == 0 {
+		// Treat unmarked versions as neutral (i.e. same as others)
+		if other.loaderIdx == modloaderTypeAny || r.loaderIdx == modloaderTypeAny {
+			compare = 0
+		} else {
+			// Prefer higher loader indexes
+			compare = int64(r.loaderIdx) - int64(other.loaderIdx)
+		}
+	}
+	if compare == 0 {
+		// Other comparisons are equal, compare by ID instead
+		compare = int64(r.id) - int64(other.id)
+	}
+	return compare
+}
+
 // findLatestFile looks at mod info, and finds the latest file ID (and potentially the file info for it - may be null)
-func findLatestFile(modInfoData modInfo, mcVersions []string, packLoaders []string) (fileID uint32, fileInfoData *modFileInfo, fileName string) {
+// Only files allowed by the given release channel and version constraint (which may be nil) are considered
+func findLatestFile(modInfoData modInfo, mcVersions []string, packLoaders []string, channel string, constraint *core.ModConstraint) (fileID uint32, fileInfoData *modFileInfo, fileName string, err error) {
 	cfMcVersions := getCurseforgeVersions(mcVersions)
-	bestMcVer := -1
-	bestLoaderType := modloaderTypeAny
+	best := fileRank{mcVerIdx: -1, loaderIdx: modloaderTypeAny}
+	// The best file that isn't allowed by the constraint
+	bestDenied := fileRank{mcVerIdx: -1, loaderIdx: modloaderTypeAny}
 
-	// For snapshots, curseforge doesn't put them in GameVersionLatestFiles
-	for _, v := range modInfoData.LatestFiles {
+	considerFile := func(v modFileInfo) {
 		mcVerIdx := core.HighestSliceIndex(mcVersions, v.GameVersions)
 		loaderIdx, loaderValid := filterFileInfoLoaderIndex(packLoaders, v)
 
-		if mcVerIdx < 0 || !loaderValid {
-			continue
+		if mcVerIdx < 0 || !loaderValid || !core.ReleaseChannelAllows(channel, v.FileType.String()) {
+			return
 		}
-		// Compare first by Minecraft version (prefer higher indexes of mcVersions)
-		compare := int32(mcVerIdx - bestMcVer)
-		if compare == 0 {
-			// Treat unmarked versions as neutral (i.e. same as others)
-			if bestLoaderType == modloaderTypeAny || loaderIdx == modloaderTypeAny {
-				compare = 0
-			} else {
-				// Prefer higher loader indexes
-				compare = int32(loaderIdx) - int32(bestLoaderType)
+		rank := fileRank{mcVerIdx, loaderIdx, v.ID}
+		if !constraint.Allows(core.ModVersion(v.FriendlyName, v.GameVersions...), strconv.FormatUint(uint64(v.ID), 10),
+			v.FileName, v.FriendlyName) {
+			if rank.compare(bestDenied) > 0 {
+				bestDenied = rank
 			}
+			return
 		}
-		if compare == 0 {
-			// Other comparisons are equal, compare by ID instead
-			compare = int32(int64(v.ID) - int64(fileID))
-		}
-		if compare > 0 {
+		if rank.compare(best) > 0 {
 			fileID = v.ID
 			fileInfoDataCopy := v // Fix for loop variable reference (which gets reassigned on every iteration!)
 			fileInfoData = &fileInfoDataCopy
 			fileName = v.FileName
-			bestMcVer = mcVerIdx
-			bestLoaderType = loaderIdx
+			best = rank
 		}
 	}
-	// TODO: manage alpha/beta/release correctly, check update channel?
+
+	// For snapshots, curseforge doesn't put them in GameVersionLatestFiles
+	for _, v := range modInfoData.LatestFiles {
+		considerFile(v)
+	}
 	for _, v := range modInfoData.GameVersionLatestFiles {
 		mcVerIdx := slices.Index(cfMcVersions, v.GameVersion)
 		loaderIdx, loaderValid := filterLoaderTypeIndex(packLoaders, v.Modloader)
 
-		if mcVerIdx < 0 || !loaderValid {
+		if mcVerIdx < 0 || !loaderValid || !core.ReleaseChannelAllows(channel, v.FileType.String()) {
 			continue
 		}
-		// Compare first by Minecraft version (prefer higher indexes of mcVersions)
-		compare := int32(mcVerIdx - bestMcVer)
-		if compare == 0 {
-			// Treat unmarked versions as neutral (i.e. same as others)
-			if bestLoaderType == modloaderTypeAny || loaderIdx == modloaderTypeAny {
-				compare = 0
-			} else {
-				// Prefer higher loader indexes
-				compare = int32(loaderIdx) - int32(bestLoaderType)
+		rank := fileRank{mcVerIdx, loaderIdx, v.ID}
+		// No display name in GameVersionLatestFiles, so the file name is used as the version
+		if !constraint.Allows(core.ModVersion(v.Name, v.GameVersion), strconv.FormatUint(uint64(v.ID), 10), v.Name) {
+			if rank.compare(bestDenied) > 0 {
+				bestDenied = rank
 			}
+			continue
 		}
-		if compare == 0 {
-			// Other comparisons are equal, compare by ID instead
-			compare = int32(int64(v.ID) - int64(fileID))
-		}
-		if compare > 0 {
+		if rank.compare(best) > 0 {
 			fileID = v.ID
 			fileInfoData = nil // (no file info in GameVersionLatestFiles)
 			fileName = v.Name
-			bestMcVer = mcVerIdx
-			bestLoaderType = loaderIdx
+			best = rank
+		}
+	}
+
+	// The latest files only include the newest file for each Minecraft version and loader; if one of them would have
+	// been chosen but isn't allowed by the constraint, an older file for the same version might be, so every file of
+	// the mod has to be checked
+	if bestDenied.mcVerIdx >= 0 && bestDenied.compare(best) > 0 {
+		loader := modloaderTypeAny
+		if len(packLoaders) == 1 {
+			if i := slices.Index(modloaderIds[:], packLoaders[0]); i > 0 {
+				loader = modloaderType(i)
+			}
 		}
+		err = cfDefaultClient.getModFiles(modInfoData.ID, loader, func(page []modFileInfo) bool {
+			for _, v := range page {
+				considerFile(v)
+			}
+			return true
+		})
 	}
 	return
 }
@@ -387,6 +438,8 @@ type cachedStateStore struct {
 	modInfo
 	fileID   uint32
 	fileInfo *modFileInfo
+	// Used to look up the dependencies of the new file
+	pack core.Pack
 }
 
 func (u cfUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateCheck, error) {
@@ -432,13 +485,23 @@ func (u cfUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 		}
 		project := projectRaw.(cfUpdateData)
 
-		fileID, fileInfoData, fileName := findLatestFile(modInfos[i], mcVersions, packLoaders)
+		fileID, fileInfoData, fileName, err := findLatestFile(modInfos[i], mcVersions, packLoaders, pack.GetReleaseChannel(v), v.Constraint)
+		if err != nil {
+			results[i] = core.UpdateCheck{Error: err}
+			continue
+		}
 		if fileID != project.FileID && fileID != 0 {
 			// Update (or downgrade, if changing to an older version) available!
 			results[i] = core.UpdateCheck{
 				UpdateAvailable: true,
 				UpdateString:    v.FileName + " -> " + fileName,
-				CachedState:     cachedStateStore{modInfos[i], fileID, fileInfoData},
+				NewFileName:     fileName,
+				NewVersion:      newFileChangelog(modInfos[i], fileID, fileInfoData, fileName),
+				LoadChangelog:   loadFileChangelog(modInfos[i].ID, fileID),
+				CachedState:     cachedStateStore{modInfos[i], fileID, fileInfoData, pack},
+			}
+			if fileInfoData != nil {
+				results[i].ReleaseChannel = fileInfoData.FileType.String()
 			}
 		} else {
 			// Could not find a file, too old, or up to date: no update available
@@ -451,6 +514,7 @@ func (u cfUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 
 func (u cfUpdater) DoUpdate(mods []*core.Mod, cachedState []interface{}) error {
 	// "Do" isn't really that accurate, more like "Apply", because all the work is done in CheckUpdate!
+	files := make([]*modFileInfo, len(mods))
 	for i, v := range mods {
 		modState := cachedState[i].(cachedStateStore)
 
@@ -476,6 +540,17 @@ func (u cfUpdater) DoUpdate(mods []*core.Mod, cachedState []interface{}) error {
 
 		v.Update["curseforge"]["project-id"] = modState.ID
 		v.Update["curseforge"]["file-id"] = fileInfoData.ID
+		files[i] = &fileInfoData
+	}
+
+	// Record the dependencies of the new files; if they can't be retrieved, the old dependencies are kept
+	if len(mods) > 0 {
+		deps, err := getFileDependencies(files, cachedState[0].(cachedStateStore).pack)
+		if err == nil {
+			for i, v := range mods {
+				v.Dependencies = deps[i]
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,105 @@
+package curseforge
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// withModFiles makes the API client return the given files when listing the files of a mod, and returns a pointer to
+// the requests made
+func withModFiles(t *testing.T, files []modFileInfo) *[]*http.Request {
+	var requests []*http.Request
+	old := cfDefaultClient.httpClient
+	cfDefaultClient.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		if !strings.HasSuffix(req.URL.Path, "/files") {
+			t.Errorf("unexpected request to %s", req.URL)
+			return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: http.NoBody}, nil
+		}
+		var res struct {
+			Data       []modFileInfo `json:"data"`
+			Pagination struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"pagination"`
+		}
+		res.Data = files
+		res.Pagination.TotalCount = len(files)
+		data, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+	})}
+	t.Cleanup(func() { cfDefaultClient.httpClient = old })
+	return &requests
+}
+
+func TestFindLatestFileConstraint(t *testing.T) {
+	latest := modFileInfo{ID: 500, FileName: "jei-1.20.1-forge-16.0.0.jar", FriendlyName: "[1.20.1] JEI 16.0.0",
+		FileType: fileTypeRelease, GameVersions: []string{"1.20.1", "Forge"}}
+	info := modInfo{ID: 238222, LatestFiles: []modFileInfo{latest}}
+	allFiles := []modFileInfo{
+		latest,
+		{ID: 450, FileName: "jei-1.20.1-forge-15.3.0.jar", FriendlyName: "[1.20.1] JEI 15.3.0",
+			FileType: fileTypeBeta, GameVersions: []string{"1.20.1", "Forge"}},
+		{ID: 440, FileName: "jei-1.19.2-forge-15.2.9.jar", FriendlyName: "[1.19.2] JEI 15.2.9",
+			FileType: fileTypeRelease, GameVersions: []string{"1.19.2", "Forge"}},
+		{ID: 430, FileName: "jei-1.20.1-fabric-15.2.5.jar", FriendlyName: "[1.20.1] JEI 15.2.5",
+			FileType: fileTypeRelease, GameVersions: []string{"1.20.1", "Fabric"}},
+		{ID: 420, FileName: "jei-1.20.1-forge-15.2.0.jar", FriendlyName: "[1.20.1] JEI 15.2.0",
+			FileType: fileTypeRelease, GameVersions: []string{"1.20.1", "Forge"}},
+		{ID: 400, FileName: "jei-1.20.1-forge-14.0.0.jar", FriendlyName: "[1.20.1] JEI 14.0.0",
+			FileType: fileTypeRelease, GameVersions: []string{"1.20.1", "Forge"}},
+	}
+	mcVersions := []string{"1.20.1"}
+	packLoaders := []string{"forge"}
+
+	tests := []struct {
+		name         string
+		constraint   *core.ModConstraint
+		channel      string
+		wantID       uint32
+		wantRequests int
+	}{
+		{"no constraint", nil, core.ReleaseChannelRelease, 500, 0},
+		{"latest file allowed", &core.ModConstraint{Version: ">=16"}, core.ReleaseChannelRelease, 500, 0},
+		{"older file in range", &core.ModConstraint{Version: "15.x"}, core.ReleaseChannelRelease, 420, 1},
+		{"older file in range on beta channel", &core.ModConstraint{Version: "15.x"}, core.ReleaseChannelBeta, 450, 1},
+		{"latest file denied", &core.ModConstraint{Deny: []string{"16.0.0"}}, core.ReleaseChannelRelease, 420, 1},
+		{"nothing in range", &core.ModConstraint{Version: "13.x"}, core.ReleaseChannelRelease, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := withModFiles(t, allFiles)
+			fileID, fileInfoData, _, err := findLatestFile(info, mcVersions, packLoaders, tt.channel, tt.constraint)
+			if err != nil {
+				t.Fatalf("findLatestFile() error = %v", err)
+			}
+			if fileID != tt.wantID {
+				t.Errorf("findLatestFile() file ID = %d, want %d", fileID, tt.wantID)
+			}
+			if fileID != 0 && (fileInfoData == nil || fileInfoData.ID != fileID) {
+				t.Errorf("findLatestFile() file info = %+v, want file %d", fileInfoData, fileID)
+			}
+			if len(*requests) != tt.wantRequests {
+				t.Errorf("findLatestFile() made %d requests, want %d", len(*requests), tt.wantRequests)
+			}
+			for _, req := range *requests {
+				if loader := req.URL.Query().Get("modLoaderType"); loader != "1" {
+					t.Errorf("files requested with modLoaderType %q, want 1 (Forge)", loader)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package curseforge
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type cfDependencyResolver struct{}
+
+func (r cfDependencyResolver) GetProjectID(mod *core.Mod) (string, bool) {
+	rawData, ok := mod.GetParsedUpdateData("curseforge")
+	if !ok {
+		return "", false
+	}
+	data, ok := rawData.(cfUpdateData)
+	if !ok || data.ProjectID == 0 {
+		return "", false
+	}
+	return strconv.FormatUint(uint64(data.ProjectID), 10), true
+}
+
+func (r cfDependencyResolver) GetDependencies(mods []*core.Mod, pack core.Pack) ([][]core.ModDependency, error) {
+	fileIDs := make([]uint32, len(mods))
+	for i, mod := range mods {
+		rawData, ok := mod.GetParsedUpdateData("curseforge")
+		if !ok {
+			return nil, fmt.Errorf("failed to read CurseForge update metadata from %s", mod.Name)
+		}
+		fileIDs[i] = rawData.(cfUpdateData).FileID
+	}
+
+	fileData, err := cfDefaultClient.getFileInfoMultiple(fileIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CurseForge file metadata: %w", err)
+	}
+	filesByID := make(map[uint32]*modFileInfo)
+	for i := range fileData {
+		filesByID[fileData[i].ID] = &fileData[i]
+	}
+
+	ordered := make([]*modFileInfo, len(mods))
+	for i, id := range fileIDs {
+		// Files that no longer exist are left as nil, and have no dependencies
+		ordered[i] = filesByID[id]
+	}
+	return getFileDependencies(ordered, pack)
+}
+
+func (r cfDependencyResolver) ResolveDependency(dep core.ModDependency, pack core.Pack) (*core.Mod, error) {
+	modID, err := strconv.ParseUint(dep.ProjectID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CurseForge project ID %s: %w", dep.ProjectID, err)
+	}
+	mcVersions, err := pack.GetSupportedMCVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	modInfoData, err := cfDefaultClient.getModInfo(uint32(modID))
+	if err != nil {
+		return nil, err
+	}
+	fileInfoData, err := getLatestFile(modInfoData, mcVersions, 0, pack.GetCompatibleLoaders(), pack.GetReleaseChannel(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	modMeta, err := newModFile(modInfoData, fileInfoData, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := getFileDependencies([]*modFileInfo{&fileInfoData}, pack)
+	if err != nil {
+		return nil, err
+	}
+	modMeta.Dependencies = deps[0]
+	return &modMeta, nil
+}
+
+// getDependencyType converts a CurseForge relation type to the dependency type stored in metadata files
+func getDependencyType(t dependencyType) string {
+	switch t {
+	case dependencyTypeRequired:
+		return core.DependencyRequired
+	case dependencyTypeIncompatible:
+		return core.DependencyIncompatible
+	case dependencyTypeEmbedded, dependencyTypeInclude:
+		return core.DependencyEmbedded
+	default:
+		// Tools and optional dependencies aren't needed for the mod to work
+		return core.DependencyOptional
+	}
+}
+
+// getFileDependencies converts the dependencies of each file to the format stored in metadata files, looking up the
+// projects they refer to; nil files have no dependencies
+func getFileDependencies(files []*modFileInfo, pack core.Pack) ([][]core.ModDependency, error) {
+	isQuilt := slices.Contains(pack.GetCompatibleLoaders(), "quilt")
+	mcVersion, err := pack.GetMCVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]core.ModDependency, len(files))
+	var modIDs []uint32
+	for i, f := range files {
+		if f == nil {
+			continue
+		}
+		for _, dep := range f.Dependencies {
+			depType := getDependencyType(dep.Type)
+			modID := dep.ModID
+			if depType == core.DependencyRequired {
+				modID = mapDepOverride(modID, isQuilt, mcVersion)
+			}
+			projectID := strconv.FormatUint(uint64(modID), 10)
+			if slices.ContainsFunc(results[i], func(d core.ModDependency) bool {
+				return d.ProjectID == projectID && d.Type == depType
+			}) {
+				continue
+			}
+
+			results[i] = append(results[i], core.ModDependency{
+				Source:    "curseforge",
+				ProjectID: projectID,
+				Type:      depType,
+			})
+			modIDs = append(modIDs, modID)
+		}
+	}
+	if len(modIDs) == 0 {
+		return results, nil
+	}
+
+	// Look up slugs and names, so dependencies can be matched to mods from other sources and displayed nicely
+	slices.Sort(modIDs)
+	modInfos, err := cfDefaultClient.getModInfoMultiple(slices.Compact(modIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve dependency data: %w", err)
+	}
+	modInfosByID := make(map[string]modInfo)
+	for _, v := range modInfos {
+		modInfosByID[strconv.FormatUint(uint64(v.ID), 10)] = v
+	}
+	for _, deps := range results {
+		for i := range deps {
+			if v, ok := modInfosByID[deps[i].ProjectID]; ok {
+				deps[i].Slug = v.Slug
+				deps[i].Name = v.Name
+			}
+		}
+	}
+	return results, nil
+}
@@ -68,9 +68,11 @@ var detectCmd = &cobra.Command{
 
 		fmt.Printf("Successfully matched %d files\n", len(res.ExactFingerprints))
 		if len(res.PartialMatches) > 0 {
-			fmt.Println("The following fingerprints were partial and I don't know what to do!!!")
+			fmt.Println("The following files only partially matched, and were not converted (use packwiz adopt to choose a match):")
 			for _, v := range res.PartialMatches {
-				fmt.Printf("%s (%d)", modPaths[v], v)
+				for _, fingerprint := range partialMatchFingerprints(res, v) {
+					fmt.Printf("%s (%d) partially matches %s\n", modPaths[fingerprint], fingerprint, v.File.FileName)
+				}
 			}
 		}
 		if len(res.UnmatchedFingerprints) > 0 {
@@ -96,44 +98,42 @@ var detectCmd = &cobra.Command{
 		}
 
 		fmt.Println("Creating metadata files...")
+		// Detected files are only replaced with metadata files once every metadata file has been created
+		tx := core.NewTransaction()
+		defer tx.Rollback()
 		for _, v := range res.ExactMatches {
-			err = createModFile(modInfosMap[v.ID], v.File, &index, false, []string{})
+			err = createModFile(modInfosMap[v.ID], v.File, &index, tx, false, []string{})
 			if err != nil {
+				tx.Rollback()
 				fmt.Println(err)
 				os.Exit(1)
 			}
 
 			path, ok := modPaths[v.File.Fingerprint]
 			if ok {
-				err = os.Remove(path)
+				err = tx.RemoveFile(path)
+				if err == nil {
+					err = index.RemoveFile(path)
+				}
 				if err != nil {
+					tx.Rollback()
 					fmt.Println(err)
 					os.Exit(1)
 				}
 			}
 		}
-		fmt.Println("Detection complete!")
 
-		err = index.Refresh()
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		err = index.Write()
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		err = pack.UpdateIndexHash()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
+		fmt.Println("Detection complete!")
 	},
 }
 
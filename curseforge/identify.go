@@ -0,0 +1,104 @@
+package curseforge
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type cfFileIdentifier struct{}
+
+// IdentifyFiles looks up files on CurseForge by their murmur2 fingerprints
+func (cfFileIdentifier) IdentifyFiles(files []core.IdentifyFile, pack core.Pack) ([]core.IdentifyResult, error) {
+	results := make([]core.IdentifyResult, len(files))
+	fingerprints := make([]uint32, 0, len(files))
+	fileIdx := make(map[uint32][]int)
+	for i, f := range files {
+		fingerprint, err := strconv.ParseUint(f.Hashes["murmur2"], 10, 32)
+		if err != nil {
+			continue
+		}
+		if _, ok := fileIdx[uint32(fingerprint)]; !ok {
+			fingerprints = append(fingerprints, uint32(fingerprint))
+		}
+		fileIdx[uint32(fingerprint)] = append(fileIdx[uint32(fingerprint)], i)
+	}
+	if len(fingerprints) == 0 {
+		return results, nil
+	}
+
+	res, err := cfDefaultClient.getFingerprintInfo(fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.ExactMatches) == 0 && len(res.PartialMatches) == 0 {
+		return results, nil
+	}
+
+	ids := make([]uint32, 0, len(res.ExactMatches)+len(res.PartialMatches))
+	for _, v := range res.ExactMatches {
+		ids = append(ids, v.ID)
+	}
+	for _, v := range res.PartialMatches {
+		ids = append(ids, v.ID)
+	}
+	modInfos, err := cfDefaultClient.getModInfoMultiple(ids)
+	if err != nil {
+		return nil, err
+	}
+	modInfosMap := make(map[uint32]modInfo)
+	for _, v := range modInfos {
+		modInfosMap[v.ID] = v
+	}
+
+	for _, v := range res.ExactMatches {
+		info, ok := modInfosMap[v.ID]
+		if !ok {
+			continue
+		}
+		for _, i := range fileIdx[v.File.Fingerprint] {
+			modMeta, err := newModFile(info, v.File, false, []string{})
+			if err != nil {
+				return nil, err
+			}
+			results[i].Mod = &modMeta
+		}
+	}
+	for _, v := range res.PartialMatches {
+		info, ok := modInfosMap[v.ID]
+		if !ok {
+			continue
+		}
+		for _, fingerprint := range partialMatchFingerprints(res, v) {
+			for _, i := range fileIdx[fingerprint] {
+				if results[i].Mod != nil {
+					continue
+				}
+				modMeta, err := newModFile(info, v.File, false, []string{})
+				if err != nil {
+					return nil, err
+				}
+				results[i].Candidates = append(results[i].Candidates, &modMeta)
+			}
+		}
+	}
+	return results, nil
+}
+
+// partialMatchFingerprints returns the submitted fingerprints that a partial match was found for
+func partialMatchFingerprints(res addonFingerprintResponse, match addonFingerprintMatch) []uint32 {
+	if fingerprints, ok := res.PartialMatchFingerprints[strconv.FormatUint(uint64(match.File.Fingerprint), 10)]; ok {
+		return fingerprints
+	}
+	// The map may also be keyed by the submitted fingerprints
+	var fingerprints []uint32
+	for k, v := range res.PartialMatchFingerprints {
+		if slices.Contains(v, match.File.Fingerprint) {
+			if fingerprint, err := strconv.ParseUint(k, 10, 32); err == nil {
+				fingerprints = append(fingerprints, uint32(fingerprint))
+			}
+		}
+	}
+	return fingerprints
+}
@@ -246,6 +246,8 @@ var importCmd = &cobra.Command{
 		}
 
 		// 3rd pass: create mod files for every file
+		tx := core.NewTransaction()
+		defer tx.Rollback()
 		for _, v := range modsList {
 			modInfoValue, ok := modInfosMap[v.ProjectID]
 			if !ok {
@@ -259,8 +261,9 @@ var importCmd = &cobra.Command{
 				continue
 			}
 
-			err = createModFile(modInfoValue, modFileInfoValue, &index, v.OptionalDisabled, []string{})
+			err = createModFile(modInfoValue, modFileInfoValue, &index, tx, v.OptionalDisabled, []string{})
 			if err != nil {
+				tx.Rollback()
 				fmt.Printf("Failed to save project \"%s\": %s\n", modInfoValue.Name, err)
 				os.Exit(1)
 			}
@@ -275,6 +278,18 @@ var importCmd = &cobra.Command{
 			successes++
 		}
 
+		// Save the metadata files before copying overrides, so they are found when the index is refreshed
+		err = tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		fmt.Printf("Successfully imported %d/%d dependencies!\n", successes, len(modsList))
 
 		fmt.Println("Reading override files...")
@@ -353,17 +368,14 @@ var importCmd = &cobra.Command{
 			fmt.Println("No files copied!")
 		}
 
-		err = index.Write()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = pack.UpdateIndexHash()
+		tx = core.NewTransaction()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"slices"
 	"strings"
 
 	"github.com/codecraft3r/packwiz/cmdshared"
@@ -16,13 +15,6 @@ import (
 	"gopkg.in/dixonwille/wmenu.v4"
 )
 
-const maxCycles = 20
-
-type installableDep struct {
-	modInfo
-	fileInfo modFileInfo
-}
-
 // installCmd represents the install command
 var installCmd = &cobra.Command{
 	Use:     "add [URL|slug|search]",
@@ -45,11 +37,6 @@ var installCmd = &cobra.Command{
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		primaryMCVersion, err := pack.GetMCVersion()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
 
 		game := gameFlag
 		category := categoryFlag
@@ -121,140 +108,49 @@ var installCmd = &cobra.Command{
 		}
 
 		var fileInfoData modFileInfo
-		fileInfoData, err = getLatestFile(modInfoData, mcVersions, fileID, pack.GetCompatibleLoaders())
+		fileInfoData, err = getLatestFile(modInfoData, mcVersions, fileID, pack.GetCompatibleLoaders(), pack.GetReleaseChannel(nil))
 		if err != nil {
 			fmt.Printf("Failed to get file for project: %v\n", err)
 			os.Exit(1)
 		}
 
-		if len(fileInfoData.Dependencies) > 0 {
-			isQuilt := slices.Contains(pack.GetCompatibleLoaders(), "quilt")
-
-			var depsInstallable []installableDep
-			var depIDPendingQueue []uint32
-			for _, dep := range fileInfoData.Dependencies {
-				if dep.Type == dependencyTypeRequired {
-					depIDPendingQueue = append(depIDPendingQueue, mapDepOverride(dep.ModID, isQuilt, primaryMCVersion))
-				}
-			}
-
-			if len(depIDPendingQueue) > 0 {
-				fmt.Println("Finding dependencies...")
-
-				cycles := 0
-				var installedIDList []uint32
-				for len(depIDPendingQueue) > 0 && cycles < maxCycles {
-					if installedIDList == nil {
-						// Get modids of all mods
-						mods, err := index.LoadAllMods()
-						if err != nil {
-							fmt.Printf("Failed to determine existing projects: %v\n", err)
-						} else {
-							for _, mod := range mods {
-								data, ok := mod.GetParsedUpdateData("curseforge")
-								if ok {
-									updateData, ok := data.(cfUpdateData)
-									if ok {
-										if updateData.ProjectID > 0 {
-											installedIDList = append(installedIDList, updateData.ProjectID)
-										}
-									}
-								}
-							}
-						}
-					}
-
-					// Remove installed IDs from dep queue
-					i := 0
-					for _, id := range depIDPendingQueue {
-						contains := slices.Contains(installedIDList, id)
-						for _, data := range depsInstallable {
-							if id == data.ID {
-								contains = true
-								break
-							}
-						}
-						if !contains {
-							depIDPendingQueue[i] = id
-							i++
-						}
-					}
-					depIDPendingQueue = depIDPendingQueue[:i]
-
-					if len(depIDPendingQueue) == 0 {
-						break
-					}
-
-					depInfoData, err := cfDefaultClient.getModInfoMultiple(depIDPendingQueue)
-					if err != nil {
-						fmt.Printf("Error retrieving dependency data: %s\n", err.Error())
-					}
-					depIDPendingQueue = depIDPendingQueue[:0]
-
-					for _, currData := range depInfoData {
-						depFileInfo, err := getLatestFile(currData, mcVersions, 0, pack.GetCompatibleLoaders())
-						if err != nil {
-							fmt.Printf("Error retrieving dependency data: %s\n", err.Error())
-							continue
-						}
-
-						for _, dep := range depFileInfo.Dependencies {
-							if dep.Type == dependencyTypeRequired {
-								depIDPendingQueue = append(depIDPendingQueue, mapDepOverride(dep.ModID, isQuilt, primaryMCVersion))
-							}
-						}
-
-						depsInstallable = append(depsInstallable, installableDep{
-							currData, depFileInfo,
-						})
-					}
-
-					cycles++
-				}
-				if cycles >= maxCycles {
-					fmt.Println("Dependencies recurse too deeply! Try increasing maxCycles.")
-					os.Exit(1)
-				}
+		// The project and its dependencies are added together, or not at all
+		tx := core.NewTransaction()
+		defer tx.Rollback()
 
-				if len(depsInstallable) > 0 {
-					fmt.Println("Dependencies found:")
-					for _, v := range depsInstallable {
-						fmt.Println(v.Name)
-					}
-
-					if cmdshared.PromptYesNo("Would you like to add them? [Y/n]: ") {
-						for _, v := range depsInstallable {
-							err = createModFile(v.modInfo, v.fileInfo, &index, false, []string{})
-							if err != nil {
-								fmt.Println(err)
-								os.Exit(1)
-							}
-							fmt.Printf("Dependency \"%s\" successfully added! (%s)\n", v.modInfo.Name, v.fileInfo.FileName)
-						}
-					}
-				} else {
-					fmt.Println("All dependencies are already added!")
-				}
-			}
+		modMeta, err := newModFile(modInfoData, fileInfoData, false, disabledClientPlatformsFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		deps, err := getFileDependencies([]*modFileInfo{&fileInfoData}, pack)
+		if err != nil {
+			fmt.Printf("Error retrieving dependency data: %v\n", err)
+		} else {
+			modMeta.Dependencies = deps[0]
 		}
 
-		err = createModFile(modInfoData, fileInfoData, &index, false, disabledClientPlatformsFlag)
+		err = cmdshared.AddDependencies([]*core.Mod{&modMeta}, pack, &index, tx)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		err = index.Write()
+		err = writeModFile(&modMeta, &index, tx)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.UpdateIndexHash()
+
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -413,14 +309,18 @@ func searchCurseforgeInternal(searchTerm string, isSlug bool, game string, categ
 	}
 }
 
-func getLatestFile(modInfoData modInfo, mcVersions []string, fileID uint32, packLoaders []string) (modFileInfo, error) {
+func getLatestFile(modInfoData modInfo, mcVersions []string, fileID uint32, packLoaders []string, channel string) (modFileInfo, error) {
 	if fileID == 0 {
 		if len(modInfoData.LatestFiles) == 0 && len(modInfoData.GameVersionLatestFiles) == 0 {
 			return modFileInfo{}, fmt.Errorf("addon %d has no files", modInfoData.ID)
 		}
 
 		var fileInfoData *modFileInfo
-		fileID, fileInfoData, _ = findLatestFile(modInfoData, mcVersions, packLoaders)
+		var err error
+		fileID, fileInfoData, _, err = findLatestFile(modInfoData, mcVersions, packLoaders, channel, nil)
+		if err != nil {
+			return modFileInfo{}, err
+		}
 		if fileInfoData != nil {
 			return *fileInfoData, nil
 		}
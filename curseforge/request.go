@@ -93,6 +93,19 @@ const (
 	fileTypeAlpha
 )
 
+// String returns the name of the release channel this file type belongs to
+func (t fileType) String() string {
+	switch t {
+	case fileTypeRelease:
+		return core.ReleaseChannelRelease
+	case fileTypeBeta:
+		return core.ReleaseChannelBeta
+	case fileTypeAlpha:
+		return core.ReleaseChannelAlpha
+	}
+	return ""
+}
+
 type dependencyType uint8
 
 // noinspection GoUnusedConst
@@ -170,8 +183,26 @@ type modInfo struct {
 	Links      struct {
 		WebsiteURL string `json:"websiteUrl"`
 	} `json:"links"`
+	Status      modStatus `json:"status"`
+	IsAvailable bool      `json:"isAvailable"`
 }
 
+type modStatus uint8
+
+// noinspection GoUnusedConst
+const (
+	modStatusNew modStatus = iota + 1
+	modStatusChangesRequired
+	modStatusUnderSoftReview
+	modStatusApproved
+	modStatusRejected
+	modStatusChangesMade
+	modStatusInactive
+	modStatusAbandoned
+	modStatusDeleted
+	modStatusUnderReview
+)
+
 func (c *cfApiClient) getModInfo(modID uint32) (modInfo, error) {
 	var infoRes struct {
 		Data modInfo `json:"data"`
@@ -235,6 +266,7 @@ type modFileInfo struct {
 	DownloadURL  string   `json:"downloadUrl"`
 	GameVersions []string `json:"gameVersions"`
 	Fingerprint  uint32   `json:"fileFingerprint"`
+	IsAvailable  bool     `json:"isAvailable"`
 	Dependencies []struct {
 		ModID uint32         `json:"modId"`
 		Type  dependencyType `json:"relationType"`
@@ -438,18 +470,21 @@ func (c *cfApiClient) getCategories(gameID uint32) ([]cfCategory, error) {
 	return infoRes.Data, nil
 }
 
+type addonFingerprintMatch struct {
+	ID          uint32        `json:"id"`
+	File        modFileInfo   `json:"file"`
+	LatestFiles []modFileInfo `json:"latestFiles"`
+}
+
 type addonFingerprintResponse struct {
-	IsCacheBuilt bool `json:"isCacheBuilt"`
-	ExactMatches []struct {
-		ID          uint32        `json:"id"`
-		File        modFileInfo   `json:"file"`
-		LatestFiles []modFileInfo `json:"latestFiles"`
-	} `json:"exactMatches"`
-	ExactFingerprints        []uint32 `json:"exactFingerprints"`
-	PartialMatches           []uint32 `json:"partialMatches"`
-	PartialMatchFingerprints struct{} `json:"partialMatchFingerprints"`
-	InstalledFingerprints    []uint32 `json:"installedFingerprints"`
-	UnmatchedFingerprints    []uint32 `json:"unmatchedFingerprints"`
+	IsCacheBuilt      bool                    `json:"isCacheBuilt"`
+	ExactMatches      []addonFingerprintMatch `json:"exactMatches"`
+	ExactFingerprints []uint32                `json:"exactFingerprints"`
+	PartialMatches    []addonFingerprintMatch `json:"partialMatches"`
+	// PartialMatchFingerprints maps the fingerprints of partially matched files to the fingerprints that matched them
+	PartialMatchFingerprints map[string][]uint32 `json:"partialMatchFingerprints"`
+	InstalledFingerprints    []uint32            `json:"installedFingerprints"`
+	UnmatchedFingerprints    []uint32            `json:"unmatchedFingerprints"`
 }
 
 func (c *cfApiClient) getFingerprintInfo(hashes []uint32) (addonFingerprintResponse, error) {
@@ -478,3 +513,62 @@ func (c *cfApiClient) getFingerprintInfo(hashes []uint32) (addonFingerprintRespo
 
 	return infoRes.Data, nil
 }
+
+// getModFiles returns the files of a mod, newest first, optionally only those for a mod loader; the callback is called
+// with each page of files, and returns false to stop retrieving more pages
+func (c *cfApiClient) getModFiles(modID uint32, loader modloaderType, callback func([]modFileInfo) bool) error {
+	const pageSize = 50
+	modIDStr := strconv.FormatUint(uint64(modID), 10)
+	for index := 0; ; index += pageSize {
+		var infoRes struct {
+			Data       []modFileInfo `json:"data"`
+			Pagination struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"pagination"`
+		}
+
+		q := url.Values{}
+		q.Set("index", strconv.Itoa(index))
+		q.Set("pageSize", strconv.Itoa(pageSize))
+		if loader != modloaderTypeAny {
+			q.Set("modLoaderType", strconv.FormatUint(uint64(loader), 10))
+		}
+
+		resp, err := c.makeGet("/v1/mods/" + modIDStr + "/files?" + q.Encode())
+		if err != nil {
+			return fmt.Errorf("failed to request files for project ID %d: %w", modID, err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&infoRes)
+		_ = resp.Body.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to request files for project ID %d: %w", modID, err)
+		}
+
+		if !callback(infoRes.Data) || len(infoRes.Data) == 0 || index+pageSize >= infoRes.Pagination.TotalCount {
+			return nil
+		}
+	}
+}
+
+// getFileChangelog returns the changelog of a file, in HTML
+func (c *cfApiClient) getFileChangelog(modID uint32, fileID uint32) (string, error) {
+	var infoRes struct {
+		Data string `json:"data"`
+	}
+
+	modIDStr := strconv.FormatUint(uint64(modID), 10)
+	fileIDStr := strconv.FormatUint(uint64(fileID), 10)
+
+	resp, err := c.makeGet("/v1/mods/" + modIDStr + "/files/" + fileIDStr + "/changelog")
+	if err != nil {
+		return "", fmt.Errorf("failed to request changelog for project ID %d, file ID %d: %w", modID, fileID, err)
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&infoRes)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to request changelog for project ID %d, file ID %d: %w", modID, fileID, err)
+	}
+
+	return infoRes.Data, nil
+}
@@ -0,0 +1,99 @@
+package curseforge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type cfModValidator struct{}
+
+// ValidateMods checks that the project and file of each mod are still available on CurseForge, that the file is marked
+// as compatible with the pack, and that the project hasn't been abandoned or removed
+func (cfModValidator) ValidateMods(mods []*core.Mod, pack core.Pack) ([]core.ModValidation, error) {
+	results := make([]core.ModValidation, len(mods))
+	mcVersions, err := pack.GetSupportedMCVersions()
+	if err != nil {
+		return nil, err
+	}
+	cfMcVersions := getCurseforgeVersions(mcVersions)
+
+	data := make([]cfUpdateData, len(mods))
+	var modIDs, fileIDs []uint32
+	for i, mod := range mods {
+		rawData, ok := mod.GetParsedUpdateData("curseforge")
+		if !ok {
+			results[i].Errors = append(results[i].Errors, "failed to parse update metadata")
+			continue
+		}
+		data[i] = rawData.(cfUpdateData)
+		modIDs = append(modIDs, data[i].ProjectID)
+		fileIDs = append(fileIDs, data[i].FileID)
+	}
+	if len(modIDs) == 0 {
+		return results, nil
+	}
+
+	modInfos, err := cfDefaultClient.getModInfoMultiple(modIDs)
+	if err != nil {
+		return nil, err
+	}
+	fileInfos, err := cfDefaultClient.getFileInfoMultiple(fileIDs)
+	if err != nil {
+		return nil, err
+	}
+	modInfosMap := make(map[uint32]modInfo)
+	for _, v := range modInfos {
+		modInfosMap[v.ID] = v
+	}
+	fileInfosMap := make(map[uint32]modFileInfo)
+	for _, v := range fileInfos {
+		fileInfosMap[v.ID] = v
+	}
+
+	for i, mod := range mods {
+		if data[i].ProjectID == 0 {
+			continue
+		}
+		info, ok := modInfosMap[data[i].ProjectID]
+		if !ok || info.Status == modStatusDeleted {
+			results[i].Errors = append(results[i].Errors, fmt.Sprintf("project %d has been deleted from CurseForge", data[i].ProjectID))
+			continue
+		}
+		switch {
+		case !info.IsAvailable || info.Status == modStatusRejected:
+			results[i].Errors = append(results[i].Errors, "project is no longer available on CurseForge")
+		case info.Status == modStatusAbandoned || info.Status == modStatusInactive:
+			results[i].Warnings = append(results[i].Warnings, "project has been marked as abandoned, and won't be updated")
+		}
+
+		file, ok := fileInfosMap[data[i].FileID]
+		if !ok {
+			results[i].Errors = append(results[i].Errors, fmt.Sprintf("file %d has been deleted from CurseForge", data[i].FileID))
+			continue
+		}
+		if !file.IsAvailable {
+			results[i].Errors = append(results[i].Errors, fmt.Sprintf("file %d is no longer available on CurseForge", data[i].FileID))
+		}
+		if file.FileName != mod.FileName {
+			results[i].Warnings = append(results[i].Warnings, "file is named "+file.FileName+" on CurseForge")
+		}
+		if !slices.ContainsFunc(file.GameVersions, func(v string) bool { return slices.Contains(cfMcVersions, v) }) {
+			results[i].Warnings = append(results[i].Warnings, fmt.Sprintf("file isn't marked as supporting Minecraft %s (only %s)",
+				strings.Join(mcVersions, ", "), strings.Join(file.GameVersions, ", ")))
+		}
+		var loaders []string
+		for j, name := range modloaderNames {
+			if j > 0 && slices.Contains(file.GameVersions, name) {
+				loaders = append(loaders, modloaderIds[j])
+			}
+		}
+		if !core.LoadersCompatible(loaders, pack) {
+			results[i].Warnings = append(results[i].Warnings, "file isn't marked as supporting the pack's loaders (only "+
+				strings.Join(loaders, ", ")+")")
+		}
+	}
+	return results, nil
+}
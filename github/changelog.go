@@ -0,0 +1,76 @@
+package github
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type ghChangelogProvider struct{}
+
+// GetChangelogs returns the bodies of the releases after the old release, up to and including the new release, on the
+// same branch as the new release
+func (ghChangelogProvider) GetChangelogs(oldMod *core.Mod, newMod *core.Mod) ([]core.Changelog, error) {
+	oldRaw, ok := oldMod.GetParsedUpdateData("github")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	newRaw, ok := newMod.GetParsedUpdateData("github")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	oldData, newData := oldRaw.(ghUpdateData), newRaw.(ghUpdateData)
+
+	releases, err := fetchReleases(newData.Slug)
+	if err != nil {
+		return nil, err
+	}
+	sameRepo := strings.EqualFold(oldData.Slug, newData.Slug)
+
+	var changelogs []core.Changelog
+	found := false
+	for _, r := range releases {
+		if r.TagName == newData.Tag {
+			found = true
+		}
+		if !found {
+			continue
+		}
+		if sameRepo && r.TagName == oldData.Tag {
+			return changelogs, nil
+		}
+		if newData.Branch != "" && r.TargetCommitish != newData.Branch {
+			continue
+		}
+		changelogs = append(changelogs, releaseChangelog(r))
+		// Without the old release, only the new release can be included
+		if !sameRepo {
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New("release " + newData.Tag + " of " + newData.Slug + " not found")
+	}
+	if len(changelogs) > 1 {
+		// The old release wasn't found (e.g. it has been deleted, or is too old to be listed)
+		return changelogs[:1], nil
+	}
+	return changelogs, nil
+}
+
+func releaseChangelog(r Release) core.Changelog {
+	changelog := core.Changelog{
+		Version: r.TagName,
+		URL:     r.HTMLURL,
+		Body:    r.Body,
+	}
+	if r.Name != "" {
+		changelog.Version = r.Name
+	}
+	if date, err := time.Parse(time.RFC3339, r.CreatedAt); err == nil {
+		changelog.Date = date
+	}
+	return changelog
+}
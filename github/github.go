@@ -20,6 +20,8 @@ var githubCmd = &cobra.Command{
 func init() {
 	cmd.Add(githubCmd)
 	core.Updaters["github"] = ghUpdater{}
+	core.ChangelogProviders["github"] = ghChangelogProvider{}
+	core.ModValidators["github"] = ghModValidator{}
 }
 
 func fetchRepo(slug string) (Repo, error) {
@@ -53,6 +55,7 @@ type Repo struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`      // "hello_world"
 	FullName string `json:"full_name"` // "owner/hello_world"
+	Archived bool   `json:"archived"`
 }
 
 type Release struct {
@@ -61,6 +64,8 @@ type Release struct {
 	TargetCommitish string  `json:"target_commitish"` // The branch of the release
 	Name            string  `json:"name"`
 	CreatedAt       string  `json:"created_at"`
+	HTMLURL         string  `json:"html_url"`
+	Body            string  `json:"body"`
 	Assets          []Asset `json:"assets"`
 }
 
@@ -81,7 +81,7 @@ var installCmd = &cobra.Command{
 }
 
 func installMod(repo Repo, branch string, regex string, pack core.Pack) error {
-	latestRelease, err := getLatestRelease(repo.FullName, branch)
+	latestRelease, err := getLatestRelease(repo.FullName, branch, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get latest release: %v", err)
 	}
@@ -89,36 +89,71 @@ func installMod(repo Repo, branch string, regex string, pack core.Pack) error {
 	return installRelease(repo, latestRelease, regex, pack)
 }
 
-func getLatestRelease(slug string, branch string) (Release, error) {
+// fetchReleases returns the releases of a repository, newest first
+func fetchReleases(slug string) ([]Release, error) {
 	var releases []Release
-	var release Release
 
 	resp, err := ghDefaultClient.getReleases(slug)
 	if err != nil {
-		return release, err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return release, err
+		return nil, err
 	}
 
 	err = json.Unmarshal(body, &releases)
+	if err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// fetchReleaseByTag returns the release of a repository with the given tag
+func fetchReleaseByTag(slug string, tag string) (Release, error) {
+	var release Release
+
+	resp, err := ghDefaultClient.getReleaseByTag(slug, tag)
 	if err != nil {
 		return release, err
 	}
 
-	if branch != "" {
-		for _, r := range releases {
-			if r.TargetCommitish == branch {
-				return r, nil
-			}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	if err != nil {
+		return release, err
+	}
+	return release, nil
+}
+
+// getLatestRelease finds the newest release of a repository (optionally on a branch) allowed by the version constraint,
+// which may be nil
+func getLatestRelease(slug string, branch string, constraint *core.ModConstraint) (Release, error) {
+	var release Release
+
+	releases, err := fetchReleases(slug)
+	if err != nil {
+		return release, err
+	}
+
+	for _, r := range releases {
+		if branch != "" && r.TargetCommitish != branch {
+			continue
+		}
+		if !constraint.Allows(core.ModVersion(r.TagName), r.TagName, r.Name) {
+			continue
 		}
+		return r, nil
+	}
+	if branch != "" {
 		return release, fmt.Errorf("failed to find release for branch %v", branch)
 	}
-
-	return releases[0], nil
+	if !constraint.IsEmpty() {
+		return release, fmt.Errorf("no releases match the version constraint (%s)", constraint)
+	}
+	return release, errors.New("no releases found")
 }
 
 func installRelease(repo Repo, release Release, regex string, pack core.Pack) error {
@@ -202,7 +237,9 @@ func installRelease(repo Repo, release Release, regex string, pack core.Pack) er
 	// Current strategy is to go ahead and do stuff without asking, with the assumption that you are using
 	// VCS anyway.
 
-	format, hash, err := modMeta.Write()
+	tx := core.NewTransaction()
+	defer tx.Rollback()
+	format, hash, err := tx.WriteMod(&modMeta)
 	if err != nil {
 		return err
 	}
@@ -211,15 +248,11 @@ func installRelease(repo Repo, release Release, regex string, pack core.Pack) er
 	if err != nil {
 		return err
 	}
-	err = index.Write()
-	if err != nil {
-		return err
-	}
-	err = pack.UpdateIndexHash()
+	err = tx.WriteIndexAndPack(index, &pack)
 	if err != nil {
 		return err
 	}
-	err = pack.Write()
+	err = tx.Commit()
 	if err != nil {
 		return err
 	}
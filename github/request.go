@@ -1,8 +1,10 @@
 package github
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/codecraft3r/packwiz/core"
@@ -17,6 +19,22 @@ type ghApiClient struct {
 
 var ghDefaultClient = ghApiClient{&http.Client{}}
 
+// statusError is returned for unsuccessful responses from the API
+type statusError struct {
+	statusCode int
+	status     string
+}
+
+func (e *statusError) Error() string {
+	return "invalid response status: " + e.status
+}
+
+// isNotFound checks if an error is from a response saying that the requested repository or release doesn't exist
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.statusCode == http.StatusNotFound
+}
+
 func (c *ghApiClient) makeGet(url string) (*http.Response, error) {
 	ghApiToken := viper.GetString("github.token")
 
@@ -51,7 +69,8 @@ func (c *ghApiClient) makeGet(url string) (*http.Response, error) {
 		return nil, fmt.Errorf("GitHub API ratelimit exceeded; time of reset: %v", resp.Header.Get("x-ratelimit-reset"))
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("invalid response status: %v", resp.Status)
+		_ = resp.Body.Close()
+		return nil, &statusError{statusCode: resp.StatusCode, status: resp.Status}
 	}
 
 	if ratelimit < 10 {
@@ -71,6 +90,10 @@ func (c *ghApiClient) getRepo(slug string) (*http.Response, error) {
 	return resp, nil
 }
 
+func (c *ghApiClient) getReleaseByTag(slug string, tag string) (*http.Response, error) {
+	return c.getRepo(slug + "/releases/tags/" + url.PathEscape(tag))
+}
+
 func (c *ghApiClient) getReleases(slug string) (*http.Response, error) {
 	resp, err := c.getRepo(slug + "/releases")
 	if err != nil {
@@ -42,7 +42,7 @@ func (u ghUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 
 		data := rawData.(ghUpdateData)
 
-		newRelease, err := getLatestRelease(data.Slug, data.Branch)
+		newRelease, err := getLatestRelease(data.Slug, data.Branch, mod.Constraint)
 		if err != nil {
 			results[i] = core.UpdateCheck{Error: fmt.Errorf("failed to get latest release: %v", err)}
 			continue
@@ -85,6 +85,8 @@ func (u ghUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 		results[i] = core.UpdateCheck{
 			UpdateAvailable: true,
 			UpdateString:    mod.FileName + " -> " + newFile.Name,
+			NewFileName:     newFile.Name,
+			NewVersion:      releaseChangelog(newRelease),
 			CachedState:     cachedStateStore{data.Slug, newRelease},
 		}
 	}
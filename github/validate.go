@@ -0,0 +1,60 @@
+package github
+
+import (
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type ghModValidator struct{}
+
+// ValidateMods checks that the repository, release and asset of each mod still exist on GitHub, and that the repository
+// hasn't been archived
+func (ghModValidator) ValidateMods(mods []*core.Mod, _ core.Pack) ([]core.ModValidation, error) {
+	results := make([]core.ModValidation, len(mods))
+	repos := make(map[string]*Repo)
+	for i, mod := range mods {
+		rawData, ok := mod.GetParsedUpdateData("github")
+		if !ok {
+			results[i].Errors = append(results[i].Errors, "failed to parse update metadata")
+			continue
+		}
+		data := rawData.(ghUpdateData)
+
+		repo, ok := repos[data.Slug]
+		if !ok {
+			fetched, err := fetchRepo(data.Slug)
+			if err == nil {
+				repo = &fetched
+			} else if !isNotFound(err) {
+				return nil, err
+			}
+			// Deleted repositories are stored as nil
+			repos[data.Slug] = repo
+		}
+		if repo == nil {
+			results[i].Errors = append(results[i].Errors, "repository "+data.Slug+" has been deleted or made private")
+			continue
+		}
+		if repo.Archived {
+			results[i].Warnings = append(results[i].Warnings, "repository has been archived, and won't be updated")
+		}
+
+		release, err := fetchReleaseByTag(data.Slug, data.Tag)
+		if isNotFound(err) {
+			results[i].Errors = append(results[i].Errors, "release "+data.Tag+" has been deleted from "+data.Slug)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, a := range release.Assets {
+			if a.Name == mod.FileName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			results[i].Errors = append(results[i].Errors, "file "+mod.FileName+" isn't in release "+data.Tag)
+		}
+	}
+	return results, nil
+}
@@ -7,6 +7,7 @@ import (
 	_ "github.com/codecraft3r/packwiz/github"
 	_ "github.com/codecraft3r/packwiz/migrate"
 	_ "github.com/codecraft3r/packwiz/modrinth"
+	_ "github.com/codecraft3r/packwiz/prism"
 	_ "github.com/codecraft3r/packwiz/settings"
 	_ "github.com/codecraft3r/packwiz/url"
 	_ "github.com/codecraft3r/packwiz/utils"
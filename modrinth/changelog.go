@@ -0,0 +1,87 @@
+package modrinth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type mrChangelogProvider struct{}
+
+// GetChangelogs returns the changelogs of the versions of the project published after the old version, up to and
+// including the new version, that support one of the new version's loaders and one of its Minecraft versions
+func (mrChangelogProvider) GetChangelogs(oldMod *core.Mod, newMod *core.Mod) ([]core.Changelog, error) {
+	oldRaw, ok := oldMod.GetParsedUpdateData("modrinth")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	newRaw, ok := newMod.GetParsedUpdateData("modrinth")
+	if !ok {
+		return nil, errors.New("failed to parse update metadata")
+	}
+	oldData, newData := oldRaw.(mrUpdateData), newRaw.(mrUpdateData)
+
+	versions, err := mrDefaultClient.Versions.GetMultiple([]string{oldData.InstalledVersion, newData.InstalledVersion})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch versions of %s: %w", newMod.Name, err)
+	}
+	var oldVersion, newVersion *modrinthApi.Version
+	for _, v := range versions {
+		if *v.ID == oldData.InstalledVersion {
+			oldVersion = v
+		}
+		if *v.ID == newData.InstalledVersion {
+			newVersion = v
+		}
+	}
+	if newVersion == nil {
+		return nil, fmt.Errorf("version %s of %s not found", newData.InstalledVersion, newMod.Name)
+	}
+	// The versions in between can't be found if the old version is from another project, or has been deleted
+	if oldVersion == nil || oldData.ProjectID != newData.ProjectID || oldVersion.DatePublished == nil ||
+		newVersion.DatePublished == nil {
+		return []core.Changelog{versionChangelog(newVersion)}, nil
+	}
+	if !newVersion.DatePublished.After(*oldVersion.DatePublished) {
+		return nil, nil
+	}
+
+	versions, err = mrDefaultClient.Versions.ListVersions(newData.ProjectID, modrinthApi.ListVersionsOptions{
+		Loaders:      newVersion.Loaders,
+		GameVersions: newVersion.GameVersions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch versions of %s: %w", newMod.Name, err)
+	}
+	var changelogs []core.Changelog
+	for _, v := range versions {
+		if v.DatePublished == nil || !v.DatePublished.After(*oldVersion.DatePublished) ||
+			v.DatePublished.After(*newVersion.DatePublished) {
+			continue
+		}
+		changelogs = append(changelogs, versionChangelog(v))
+	}
+	sort.SliceStable(changelogs, func(i, j int) bool {
+		return changelogs[i].Date.After(changelogs[j].Date)
+	})
+	return changelogs, nil
+}
+
+func versionChangelog(v *modrinthApi.Version) core.Changelog {
+	changelog := core.Changelog{
+		URL: "https://modrinth.com/project/" + *v.ProjectID + "/version/" + *v.ID,
+	}
+	if v.VersionNumber != nil {
+		changelog.Version = *v.VersionNumber
+	}
+	if v.DatePublished != nil {
+		changelog.Date = *v.DatePublished
+	}
+	if v.Changelog != nil {
+		changelog.Body = *v.Changelog
+	}
+	return changelog
+}
@@ -0,0 +1,199 @@
+package modrinth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type mrDependencyResolver struct{}
+
+func (r mrDependencyResolver) GetProjectID(mod *core.Mod) (string, bool) {
+	rawData, ok := mod.GetParsedUpdateData("modrinth")
+	if !ok {
+		return "", false
+	}
+	data, ok := rawData.(mrUpdateData)
+	if !ok || data.ProjectID == "" {
+		return "", false
+	}
+	return data.ProjectID, true
+}
+
+func (r mrDependencyResolver) GetDependencies(mods []*core.Mod, pack core.Pack) ([][]core.ModDependency, error) {
+	versionIDs := make([]string, 0, len(mods))
+	for _, mod := range mods {
+		rawData, ok := mod.GetParsedUpdateData("modrinth")
+		if !ok {
+			return nil, fmt.Errorf("failed to parse update metadata for %s", mod.Name)
+		}
+		versionIDs = append(versionIDs, rawData.(mrUpdateData).InstalledVersion)
+	}
+
+	versions, err := mrDefaultClient.Versions.GetMultiple(versionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch versions: %v", err)
+	}
+	versionsByID := make(map[string]*modrinthApi.Version)
+	for _, v := range versions {
+		if v.ID != nil {
+			versionsByID[*v.ID] = v
+		}
+	}
+
+	ordered := make([]*modrinthApi.Version, len(mods))
+	for i, id := range versionIDs {
+		// Versions that no longer exist are left as nil, and have no dependencies
+		ordered[i] = versionsByID[id]
+	}
+	return getVersionDependencies(ordered, pack)
+}
+
+func (r mrDependencyResolver) ResolveDependency(dep core.ModDependency, pack core.Pack) (*core.Mod, error) {
+	project, err := mrDefaultClient.Projects.Get(dep.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch project %s: %v", dep.ProjectID, err)
+	}
+	if project.ID == nil {
+		return nil, errors.New("failed to get dependency data: invalid response")
+	}
+	latestVersion, err := getLatestVersion(*project.ID, *project.Title, pack, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest version: %v", err)
+	}
+	if latestVersion.ID == nil {
+		return nil, errors.New("mod not available for the configured Minecraft version(s) (use the 'packwiz settings acceptable-versions' command to accept more) or loader")
+	}
+	if len(latestVersion.Files) == 0 {
+		return nil, errors.New("version doesn't have any files attached")
+	}
+
+	var file = latestVersion.Files[0]
+	// Prefer the primary file
+	for _, v := range latestVersion.Files {
+		if *v.Primary {
+			file = v
+		}
+	}
+
+	modMeta, err := newFileMeta(project, latestVersion, file, pack)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := getVersionDependencies([]*modrinthApi.Version{latestVersion}, pack)
+	if err != nil {
+		return nil, err
+	}
+	modMeta.Dependencies = deps[0]
+	return &modMeta, nil
+}
+
+// getVersionDependencies converts the dependencies of each version to the format stored in metadata files, looking
+// up the projects they refer to; nil versions have no dependencies (and a nil slice)
+func getVersionDependencies(versions []*modrinthApi.Version, pack core.Pack) ([][]core.ModDependency, error) {
+	isQuilt := slices.Contains(pack.GetCompatibleLoaders(), "quilt")
+	mcVersion, err := pack.GetMCVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	// Some dependencies only specify a version; look up the projects they belong to
+	var depVersionIDs []string
+	for _, v := range versions {
+		if v == nil {
+			continue
+		}
+		for _, dep := range v.Dependencies {
+			if dep.ProjectID == nil && dep.VersionID != nil {
+				depVersionIDs = append(depVersionIDs, *dep.VersionID)
+			}
+		}
+	}
+	versionProjects := make(map[string]string)
+	if len(depVersionIDs) > 0 {
+		depVersions, err := mrDefaultClient.Versions.GetMultiple(depVersionIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch dependency versions: %v", err)
+		}
+		for _, v := range depVersions {
+			if v.ID != nil && v.ProjectID != nil {
+				versionProjects[*v.ID] = *v.ProjectID
+			}
+		}
+	}
+
+	results := make([][]core.ModDependency, len(versions))
+	var projectIDs []string
+	for i, v := range versions {
+		if v == nil {
+			continue
+		}
+		// Not nil, so versions without dependencies replace the dependencies recorded for an older version
+		results[i] = []core.ModDependency{}
+		for _, dep := range v.Dependencies {
+			var projectID string
+			if dep.ProjectID != nil {
+				projectID = *dep.ProjectID
+			} else if dep.VersionID != nil {
+				projectID = versionProjects[*dep.VersionID]
+			}
+			if projectID == "" {
+				// Dependencies on a file name only can't be resolved
+				continue
+			}
+
+			depType := core.DependencyOptional
+			if dep.DependencyType != nil {
+				depType = *dep.DependencyType
+			}
+			if depType == core.DependencyRequired {
+				projectID = mapDepOverride(projectID, isQuilt, mcVersion)
+			}
+			// Remove duplicates (from dependencies on both QFAPI + FAPI)
+			if slices.ContainsFunc(results[i], func(d core.ModDependency) bool {
+				return d.ProjectID == projectID && d.Type == depType
+			}) {
+				continue
+			}
+
+			results[i] = append(results[i], core.ModDependency{
+				Source:    "modrinth",
+				ProjectID: projectID,
+				Type:      depType,
+			})
+			projectIDs = append(projectIDs, projectID)
+		}
+	}
+	if len(projectIDs) == 0 {
+		return results, nil
+	}
+
+	// Look up slugs and names, so dependencies can be matched to mods from other sources and displayed nicely
+	slices.Sort(projectIDs)
+	projects, err := mrDefaultClient.Projects.GetMultiple(slices.Compact(projectIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dependency projects: %v", err)
+	}
+	projectsByID := make(map[string]*modrinthApi.Project)
+	for _, p := range projects {
+		if p.ID != nil {
+			projectsByID[*p.ID] = p
+		}
+	}
+	for _, deps := range results {
+		for i := range deps {
+			if p, ok := projectsByID[deps[i].ProjectID]; ok {
+				if p.Slug != nil {
+					deps[i].Slug = *p.Slug
+				}
+				if p.Title != nil {
+					deps[i].Name = *p.Title
+				}
+			}
+		}
+	}
+	return results, nil
+}
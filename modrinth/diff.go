@@ -4,12 +4,16 @@ import (
 	"archive/zip"
 	"fmt"
 	"os"
+	"path"
 	"slices"
 	"strings"
 	"time"
 
+	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
+	"github.com/codecraft3r/packwiz/cmdshared"
 	"github.com/codecraft3r/packwiz/core"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/vbauerster/mpb/v4"
 	"github.com/vbauerster/mpb/v4/decor"
 )
@@ -22,11 +26,24 @@ var diffCmd = &cobra.Command{
 - Mods that are in the mrpack but not in your pack (missing)
 - Mods that are in your pack but not in the mrpack (extra)  
 - Mods that are in both but have different versions (different)
-- Summary of total differences`,
+- Summary of total differences
+
+With --apply, the current pack is changed to match the mrpack: missing projects are installed at the version in the
+mrpack, extra projects are removed, and different projects are switched to the version in the mrpack. Use --categories
+to choose which of these changes are made; each category is confirmed before any changes are made.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		mrpackFilePath := args[0]
 
+		apply := viper.GetBool("modrinth.diff.apply")
+		categories := viper.GetStringSlice("modrinth.diff.categories")
+		for _, v := range categories {
+			if !slices.Contains(diffCategories, v) {
+				fmt.Printf("Unknown category %s; must be one of %s\n", v, strings.Join(diffCategories, ", "))
+				os.Exit(1)
+			}
+		}
+
 		// Load current pack
 		pack, err := core.LoadPack()
 		if err != nil {
@@ -120,9 +137,21 @@ var diffCmd = &cobra.Command{
 			fmt.Printf("\nℹ️  Note: %d non-Modrinth mods in your pack are not compared\n",
 				otherSourceMods.CurseForge+otherSourceMods.URL+otherSourceMods.Other)
 		}
+
+		if apply && totalDiffs > 0 {
+			fmt.Println()
+			err = applyDifferences(missing, extra, different, categories, pack, &index)
+			if err != nil {
+				fmt.Printf("Failed to apply differences: %v\nNo files have been changed.\n", err)
+				os.Exit(1)
+			}
+		}
 	},
 }
 
+// diffCategories are the categories of differences that can be applied
+var diffCategories = []string{"missing", "extra", "different"}
+
 // ModInfo represents information about a mod
 type ModInfo struct {
 	ProjectID   string
@@ -130,6 +159,10 @@ type ModInfo struct {
 	ProjectName string
 	FileName    string
 	Side        string
+	// CurrentVersionID is the version in the current pack, for mods with different versions
+	CurrentVersionID string
+	// MetaFile is the path of the metadata file, for mods in the current pack
+	MetaFile string
 }
 
 // ModSources represents mod counts by source
@@ -213,6 +246,7 @@ func getCurrentModrinthMods(index *core.Index, progressContainer *mpb.Progress)
 						ProjectName: mod.Name,
 						FileName:    mod.FileName,
 						Side:        mod.Side,
+						MetaFile:    modPath,
 					}
 					sources.Modrinth++
 					hasModrinth = true
@@ -283,7 +317,7 @@ func getMrpackModsWithInfo(mrpackData *ModrinthIndexFile, progressContainer *mpb
 	}
 
 	// Look up version IDs from hashes
-	versionMap, err := lookupVersionsByHash(hashes)
+	versionMap, err := lookupVersionsByHash(hashes, "sha512")
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup versions by hash: %v", err)
 	}
@@ -384,12 +418,14 @@ func compareMods(current, mrpack map[string]ModInfo) (missing, extra, different
 	for projectID, currentMod := range current {
 		if mrpackMod, exists := mrpack[projectID]; exists {
 			if currentMod.VersionID != mrpackMod.VersionID {
-				// Create a combined info for display
 				diffMod := ModInfo{
-					ProjectID:   projectID,
-					ProjectName: currentMod.ProjectName,
-					VersionID:   fmt.Sprintf("%s → %s", currentMod.VersionID, mrpackMod.VersionID),
-					Side:        currentMod.Side,
+					ProjectID:        projectID,
+					ProjectName:      currentMod.ProjectName,
+					VersionID:        mrpackMod.VersionID,
+					FileName:         mrpackMod.FileName,
+					Side:             currentMod.Side,
+					CurrentVersionID: currentMod.VersionID,
+					MetaFile:         currentMod.MetaFile,
 				}
 				different = append(different, diffMod)
 			}
@@ -431,7 +467,7 @@ func displayDifferences(missing, extra, different []ModInfo) {
 	if len(different) > 0 {
 		fmt.Printf("- Version differences (%d):\n", len(different))
 		for _, mod := range different {
-			fmt.Printf("  ~ %s [%s] (side: %s)\n", mod.ProjectName, mod.VersionID, mod.Side)
+			fmt.Printf("  ~ %s [%s → %s] (side: %s)\n", mod.ProjectName, mod.CurrentVersionID, mod.VersionID, mod.Side)
 		}
 		fmt.Println()
 	}
@@ -446,6 +482,168 @@ func openMrpackFile(mrpackPath string) (*zip.ReadCloser, error) {
 	return zip.OpenReader(mrpackPath)
 }
 
+// applyDifferences changes the current pack to match the mrpack, for each of the given categories that the user
+// confirms; all changes are made together, or not at all
+func applyDifferences(missing, extra, different []ModInfo, categories []string, pack core.Pack, index *core.Index) error {
+	tx := core.NewTransaction()
+	defer tx.Rollback()
+
+	if slices.Contains(categories, "missing") && len(missing) > 0 &&
+		cmdshared.PromptYesNo(fmt.Sprintf("Install %d missing projects? [Y/n]: ", len(missing))) {
+		err := installMrpackVersions(missing, pack, index, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(categories, "extra") && len(extra) > 0 &&
+		cmdshared.PromptYesNo(fmt.Sprintf("Remove %d extra projects? [Y/n]: ", len(extra))) {
+		for _, mod := range extra {
+			err := tx.RemoveFile(mod.MetaFile)
+			if err == nil {
+				err = index.RemoveFile(mod.MetaFile)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to remove %s: %w", mod.ProjectName, err)
+			}
+			fmt.Printf("%s removed\n", mod.ProjectName)
+		}
+	}
+
+	if slices.Contains(categories, "different") && len(different) > 0 &&
+		cmdshared.PromptYesNo(fmt.Sprintf("Switch %d projects to the version in the mrpack? [Y/n]: ", len(different))) {
+		err := switchMrpackVersions(different, pack, index, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tx.WriteIndexAndPack(*index, &pack)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Differences applied!")
+	return nil
+}
+
+// fetchMrpackVersions retrieves the versions and projects of the given mods
+func fetchMrpackVersions(mods []ModInfo) (map[string]*modrinthApi.Version, map[string]*modrinthApi.Project, error) {
+	versionIDs := make([]string, len(mods))
+	projectIDs := make([]string, len(mods))
+	for i, mod := range mods {
+		versionIDs[i] = mod.VersionID
+		projectIDs[i] = mod.ProjectID
+	}
+	versionList, err := mrDefaultClient.Versions.GetMultiple(versionIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch versions: %w", err)
+	}
+	projectList, err := mrDefaultClient.Projects.GetMultiple(projectIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch projects: %w", err)
+	}
+	versions := make(map[string]*modrinthApi.Version)
+	for _, v := range versionList {
+		versions[*v.ID] = v
+	}
+	projects := make(map[string]*modrinthApi.Project)
+	for _, v := range projectList {
+		projects[*v.ID] = v
+	}
+	return versions, projects, nil
+}
+
+// installMrpackVersions installs the exact versions of missing mods that are in the mrpack
+func installMrpackVersions(mods []ModInfo, pack core.Pack, index *core.Index, tx *core.Transaction) error {
+	versions, projects, err := fetchMrpackVersions(mods)
+	if err != nil {
+		return err
+	}
+	for _, mod := range mods {
+		version, project := versions[mod.VersionID], projects[mod.ProjectID]
+		if version == nil || project == nil {
+			return fmt.Errorf("version %s of %s not found", mod.VersionID, mod.ProjectName)
+		}
+		// Use the same file as the mrpack, if the version has more than one
+		fileName := path.Base(mod.FileName)
+		if !slices.ContainsFunc(version.Files, func(f *modrinthApi.File) bool { return *f.Filename == fileName }) {
+			fileName = ""
+		}
+		err = installVersionWithSide(project, version, fileName, mod.Side, pack, index, tx)
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", mod.ProjectName, err)
+		}
+		fmt.Printf("%s installed (%s)\n", mod.ProjectName, path.Base(mod.FileName))
+	}
+	return nil
+}
+
+// switchMrpackVersions changes the metadata of mods in the current pack to the versions in the mrpack, keeping any
+// other changes made to the metadata (e.g. the side)
+func switchMrpackVersions(mods []ModInfo, pack core.Pack, index *core.Index, tx *core.Transaction) error {
+	versions, _, err := fetchMrpackVersions(mods)
+	if err != nil {
+		return err
+	}
+	modVersions := make([]*modrinthApi.Version, len(mods))
+	for i, mod := range mods {
+		modVersions[i] = versions[mod.VersionID]
+		if modVersions[i] == nil {
+			return fmt.Errorf("version %s of %s not found", mod.VersionID, mod.ProjectName)
+		}
+	}
+	// The recorded dependencies must match the new versions; if they can't be retrieved, the old dependencies are kept
+	deps, err := getVersionDependencies(modVersions, pack)
+	if err != nil {
+		fmt.Printf("Error retrieving dependency data: %v\n", err)
+		deps = make([][]core.ModDependency, len(mods))
+	}
+	for i, mod := range mods {
+		version := modVersions[i]
+		modData, err := core.LoadMod(mod.MetaFile)
+		if err != nil {
+			return err
+		}
+		if modData.Pin {
+			fmt.Printf("Skipping pinned project %s\n", modData.Name)
+			continue
+		}
+		// Use the same file as the mrpack, if the version has more than one
+		fileName := path.Base(mod.FileName)
+		if !slices.ContainsFunc(version.Files, func(f *modrinthApi.File) bool { return *f.Filename == fileName }) {
+			fileName = ""
+		}
+		err = mrUpdater{}.DoUpdate([]*core.Mod{&modData}, []interface{}{cachedStateStore{
+			ProjectID:    mod.ProjectID,
+			Version:      version,
+			Dependencies: deps[i],
+			FileName:     fileName,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to switch %s to version %s: %w", mod.ProjectName, mod.VersionID, err)
+		}
+		format, hash, err := tx.WriteMod(&modData)
+		if err != nil {
+			return err
+		}
+		err = index.RefreshFileWithHash(mod.MetaFile, format, hash, true)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s switched to %s\n", modData.Name, modData.FileName)
+	}
+	return nil
+}
+
 func init() {
 	modrinthCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().Bool("apply", false, "Change the current pack to match the mrpack")
+	_ = viper.BindPFlag("modrinth.diff.apply", diffCmd.Flags().Lookup("apply"))
+	diffCmd.Flags().StringSlice("categories", diffCategories, "The categories of differences to apply (missing, extra, different)")
+	_ = viper.BindPFlag("modrinth.diff.categories", diffCmd.Flags().Lookup("categories"))
 }
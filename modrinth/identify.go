@@ -0,0 +1,81 @@
+package modrinth
+
+import (
+	"strings"
+
+	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type mrFileIdentifier struct{}
+
+// IdentifyFiles looks up files on Modrinth by their SHA-1 hashes
+func (mrFileIdentifier) IdentifyFiles(files []core.IdentifyFile, pack core.Pack) ([]core.IdentifyResult, error) {
+	results := make([]core.IdentifyResult, len(files))
+	hashes := make([]string, len(files))
+	for i, f := range files {
+		hashes[i] = f.Hashes["sha1"]
+	}
+	versionMap, err := lookupVersionsByHash(hashes, "sha1")
+	if err != nil {
+		return nil, err
+	}
+	if len(versionMap) == 0 {
+		return results, nil
+	}
+
+	versionIDs := make([]string, 0, len(versionMap))
+	for _, v := range versionMap {
+		versionIDs = append(versionIDs, v.ID)
+	}
+	versions, err := mrDefaultClient.Versions.GetMultiple(versionIDs)
+	if err != nil {
+		return nil, err
+	}
+	versionsByID := make(map[string]*modrinthApi.Version, len(versions))
+	projectIDs := make([]string, 0, len(versions))
+	for _, v := range versions {
+		versionsByID[*v.ID] = v
+		projectIDs = append(projectIDs, *v.ProjectID)
+	}
+	projects, err := mrDefaultClient.Projects.GetMultiple(projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	projectsByID := make(map[string]*modrinthApi.Project, len(projects))
+	for _, p := range projects {
+		projectsByID[*p.ID] = p
+	}
+
+	for i, hash := range hashes {
+		res, ok := versionMap[hash]
+		if !ok {
+			continue
+		}
+		version, ok := versionsByID[res.ID]
+		if !ok {
+			continue
+		}
+		project, ok := projectsByID[*version.ProjectID]
+		if !ok {
+			continue
+		}
+		// Use the file of the version that has this hash, rather than the primary file
+		var file *modrinthApi.File
+		for _, f := range version.Files {
+			if strings.EqualFold(f.Hashes["sha1"], hash) {
+				file = f
+				break
+			}
+		}
+		if file == nil {
+			continue
+		}
+		modMeta, err := newFileMeta(project, version, file, pack)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Mod = &modMeta
+	}
+	return results, nil
+}
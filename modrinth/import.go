@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
@@ -116,7 +117,7 @@ var importCmd = &cobra.Command{
 		}
 
 		// Look up version IDs from hashes
-		versionMap, err := lookupVersionsByHash(hashes)
+		versionMap, err := lookupVersionsByHash(hashes, "sha512")
 		if err != nil {
 			fmt.Printf("Failed to lookup versions by hash: %v\n", err)
 			os.Exit(1)
@@ -133,21 +134,24 @@ var importCmd = &cobra.Command{
 		skippedCount := 0
 		totalMods := len(versionMap)
 
+		// Metadata files are staged in a transaction, which is committed at each checkpoint so that
+		// the files on disk are always consistent with the index
+		var txMutex sync.Mutex
+		tx := core.NewTransaction()
+
 		// Set up crash recovery - save progress periodically and on exit
 		saveProgress := func() {
+			txMutex.Lock()
+			defer txMutex.Unlock()
 			if successCount > 0 {
 				fmt.Printf("Saving progress (%d mods installed)...\n", successCount)
-				if writeErr := index.Write(); writeErr != nil {
-					fmt.Printf("Warning: Failed to save progress to index: %v\n", writeErr)
-				} else {
-					if hashErr := pack.UpdateIndexHash(); hashErr != nil {
-						fmt.Printf("Warning: Failed to update pack hash: %v\n", hashErr)
-					} else {
-						if packErr := pack.Write(); packErr != nil {
-							fmt.Printf("Warning: Failed to save pack: %v\n", packErr)
-						}
-					}
+				if writeErr := tx.WriteIndexAndPack(index, &pack); writeErr != nil {
+					tx.Rollback()
+					fmt.Printf("Warning: Failed to save progress: %v\n", writeErr)
+				} else if commitErr := tx.Commit(); commitErr != nil {
+					fmt.Printf("Warning: Failed to save progress: %v\n", commitErr)
 				}
+				tx = core.NewTransaction()
 			}
 		}
 
@@ -234,7 +238,11 @@ var importCmd = &cobra.Command{
 				*project.Title, processedCount, totalMods, versionInfo.ID, side)
 
 			// Install the mod with API-determined side information
-			err = installVersionByIdWithSide(versionInfo.ID, "", side, pack, &index)
+			err = func() error {
+				txMutex.Lock()
+				defer txMutex.Unlock()
+				return installVersionByIdWithSide(versionInfo.ID, "", side, pack, &index, tx)
+			}()
 			if err != nil {
 				fmt.Printf("Failed to install mod %s with version ID %s: %v\n", *project.Title, versionInfo.ID, err)
 			} else {
@@ -260,25 +268,20 @@ var importCmd = &cobra.Command{
 			fmt.Printf("Warning: Failed to copy overrides: %v\n", err)
 		}
 
-		// Write the updated index
-		err = index.Write()
+		// Write the updated index and pack, along with every metadata file created since the last checkpoint
+		txMutex.Lock()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Printf("Failed to write index: %v\n", err)
 			os.Exit(1)
 		}
-
-		// Update pack hash
-		err = pack.UpdateIndexHash()
-		if err != nil {
-			fmt.Printf("Failed to update pack hash: %v\n", err)
-			os.Exit(1)
-		}
-
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
-			fmt.Printf("Failed to write pack: %v\n", err)
+			fmt.Printf("Failed to save changes: %v\n", err)
 			os.Exit(1)
 		}
+		txMutex.Unlock()
 
 		fmt.Println("Import completed!")
 		failedCount := len(versionMap) - successCount - skippedCount
@@ -315,11 +318,11 @@ func extractModrinthIndex(r *zip.ReadCloser) (*ModrinthIndexFile, error) {
 	return nil, fmt.Errorf("modrinth.index.json not found in .mrpack file")
 }
 
-// lookupVersionsByHash queries the Modrinth API to get version information from file hashes
-func lookupVersionsByHash(hashes []string) (map[string]HashResponse, error) {
+// lookupVersionsByHash queries the Modrinth API to get version information from file hashes of the given algorithm
+func lookupVersionsByHash(hashes []string, algorithm string) (map[string]HashResponse, error) {
 	hashRequest := HashRequest{
 		Hashes:    hashes,
-		Algorithm: "sha512",
+		Algorithm: algorithm,
 	}
 
 	jsonData, err := json.Marshal(hashRequest)
@@ -459,7 +462,7 @@ func copyOverrides(r *zip.ReadCloser, index *core.Index) error {
 }
 
 // installVersionByIdWithSide installs a mod version with a specific side override
-func installVersionByIdWithSide(versionId string, versionFilename string, side string, pack core.Pack, index *core.Index) error {
+func installVersionByIdWithSide(versionId string, versionFilename string, side string, pack core.Pack, index *core.Index, tx *core.Transaction) error {
 	// Get version information from Modrinth API
 	version, err := mrDefaultClient.Versions.Get(versionId)
 	if err != nil {
@@ -473,11 +476,11 @@ func installVersionByIdWithSide(versionId string, versionFilename string, side s
 	}
 
 	// Install the version with custom side
-	return installVersionWithSide(project, version, versionFilename, side, pack, index)
+	return installVersionWithSide(project, version, versionFilename, side, pack, index, tx)
 }
 
 // installVersionWithSide installs a version with a custom side override
-func installVersionWithSide(project *modrinthApi.Project, version *modrinthApi.Version, versionFilename string, customSide string, pack core.Pack, index *core.Index) error {
+func installVersionWithSide(project *modrinthApi.Project, version *modrinthApi.Version, versionFilename string, customSide string, pack core.Pack, index *core.Index, tx *core.Transaction) error {
 	// Find the appropriate file
 	var file *modrinthApi.File
 	if versionFilename == "" {
@@ -498,11 +501,11 @@ func installVersionWithSide(project *modrinthApi.Project, version *modrinthApi.V
 	}
 
 	// Create file metadata with custom side
-	return createFileMetaWithSide(project, version, file, customSide, pack, index)
+	return createFileMetaWithSide(project, version, file, customSide, pack, index, tx)
 }
 
 // createFileMetaWithSide creates mod metadata with a custom side override
-func createFileMetaWithSide(project *modrinthApi.Project, version *modrinthApi.Version, file *modrinthApi.File, customSide string, pack core.Pack, index *core.Index) error {
+func createFileMetaWithSide(project *modrinthApi.Project, version *modrinthApi.Version, file *modrinthApi.File, customSide string, pack core.Pack, index *core.Index, tx *core.Transaction) error {
 	updateMap := make(map[string]map[string]interface{})
 
 	var err error
@@ -545,7 +548,7 @@ func createFileMetaWithSide(project *modrinthApi.Project, version *modrinthApi.V
 		path = modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder, core.SlugifyName(*project.Title)+core.MetaExtension))
 	}
 
-	format, hash, err := modMeta.Write()
+	format, hash, err := tx.WriteMod(&modMeta)
 	if err != nil {
 		return err
 	}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 
 	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
@@ -201,7 +200,7 @@ func installViaSearch(query string, versionFilename string, autoAcceptFirst bool
 }
 
 func installProject(project *modrinthApi.Project, versionFilename string, pack core.Pack, index *core.Index) error {
-	latestVersion, err := getLatestVersion(*project.ID, *project.Title, pack)
+	latestVersion, err := getLatestVersion(*project.ID, *project.Title, pack, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get latest version: %v", err)
 	}
@@ -212,158 +211,14 @@ func installProject(project *modrinthApi.Project, versionFilename string, pack c
 	return installVersion(project, latestVersion, versionFilename, pack, index)
 }
 
-const maxCycles = 20
-
-type depMetadataStore struct {
-	projectInfo *modrinthApi.Project
-	versionInfo *modrinthApi.Version
-	fileInfo    *modrinthApi.File
-}
-
 func installVersion(project *modrinthApi.Project, version *modrinthApi.Version, versionFilename string, pack core.Pack, index *core.Index) error {
 	if len(version.Files) == 0 {
 		return errors.New("version doesn't have any files attached")
 	}
 
-	if len(version.Dependencies) > 0 {
-		// TODO: could get installed version IDs, and compare to install the newest - i.e. preferring pinned versions over getting absolute latest?
-		installedProjects := getInstalledProjectIDs(index)
-		isQuilt := slices.Contains(pack.GetCompatibleLoaders(), "quilt")
-		mcVersion, err := pack.GetMCVersion()
-		if err != nil {
-			return err
-		}
-
-		var depMetadata []depMetadataStore
-		var depProjectIDPendingQueue []string
-		var depVersionIDPendingQueue []string
-
-		for _, dep := range version.Dependencies {
-			// TODO: recommend optional dependencies?
-			if dep.DependencyType != nil && *dep.DependencyType == "required" {
-				if dep.VersionID != nil {
-					depVersionIDPendingQueue = append(depVersionIDPendingQueue, *dep.VersionID)
-				} else {
-					if dep.ProjectID != nil {
-						depProjectIDPendingQueue = append(depProjectIDPendingQueue, mapDepOverride(*dep.ProjectID, isQuilt, mcVersion))
-					}
-				}
-			}
-		}
-
-		if len(depProjectIDPendingQueue)+len(depVersionIDPendingQueue) > 0 {
-			fmt.Println("Finding dependencies...")
-
-			cycles := 0
-			for len(depProjectIDPendingQueue)+len(depVersionIDPendingQueue) > 0 && cycles < maxCycles {
-				// Look up version IDs
-				if len(depVersionIDPendingQueue) > 0 {
-					depVersions, err := mrDefaultClient.Versions.GetMultiple(depVersionIDPendingQueue)
-					if err == nil {
-						for _, v := range depVersions {
-							// Add project ID to queue
-							depProjectIDPendingQueue = append(depProjectIDPendingQueue, mapDepOverride(*v.ProjectID, isQuilt, mcVersion))
-						}
-					} else {
-						fmt.Printf("Error retrieving dependency data: %s\n", err.Error())
-					}
-					depVersionIDPendingQueue = depVersionIDPendingQueue[:0]
-				}
-
-				// Remove installed project IDs from dep queue
-				i := 0
-				for _, id := range depProjectIDPendingQueue {
-					contains := slices.Contains(installedProjects, id)
-					for _, dep := range depMetadata {
-						if *dep.projectInfo.ID == id {
-							contains = true
-							break
-						}
-					}
-					if !contains {
-						depProjectIDPendingQueue[i] = id
-						i++
-					}
-				}
-				depProjectIDPendingQueue = depProjectIDPendingQueue[:i]
-
-				// Clean up duplicates from dep queue (from deps on both QFAPI + FAPI)
-				slices.Sort(depProjectIDPendingQueue)
-				depProjectIDPendingQueue = slices.Compact(depProjectIDPendingQueue)
-
-				if len(depProjectIDPendingQueue) == 0 {
-					break
-				}
-				depProjects, err := mrDefaultClient.Projects.GetMultiple(depProjectIDPendingQueue)
-				if err != nil {
-					fmt.Printf("Error retrieving dependency data: %s\n", err.Error())
-				}
-				depProjectIDPendingQueue = depProjectIDPendingQueue[:0]
-
-				for _, project := range depProjects {
-					if project.ID == nil {
-						return errors.New("failed to get dependency data: invalid response")
-					}
-					// Get latest version - could reuse version lookup data but it's not as easy (particularly since the version won't necessarily be the latest)
-					latestVersion, err := getLatestVersion(*project.ID, *project.Title, pack)
-					if err != nil {
-						fmt.Printf("Failed to get latest version of dependency %v: %v\n", *project.Title, err)
-						continue
-					}
-
-					for _, dep := range version.Dependencies {
-						// TODO: recommend optional dependencies?
-						if dep.DependencyType != nil && *dep.DependencyType == "required" {
-							if dep.ProjectID != nil {
-								depProjectIDPendingQueue = append(depProjectIDPendingQueue, mapDepOverride(*dep.ProjectID, isQuilt, mcVersion))
-							}
-							if dep.VersionID != nil {
-								depVersionIDPendingQueue = append(depVersionIDPendingQueue, *dep.VersionID)
-							}
-						}
-					}
-
-					var file = latestVersion.Files[0]
-					// Prefer the primary file
-					for _, v := range latestVersion.Files {
-						if *v.Primary {
-							file = v
-						}
-					}
-
-					depMetadata = append(depMetadata, depMetadataStore{
-						projectInfo: project,
-						versionInfo: latestVersion,
-						fileInfo:    file,
-					})
-				}
-
-				cycles++
-			}
-			if cycles >= maxCycles {
-				return errors.New("dependencies recurse too deeply, try increasing maxCycles")
-			}
-
-			if len(depMetadata) > 0 {
-				fmt.Println("Dependencies found:")
-				for _, v := range depMetadata {
-					fmt.Println(*v.projectInfo.Title)
-				}
-
-				if cmdshared.PromptYesNo("Would you like to add them? [Y/n]: ") {
-					for _, v := range depMetadata {
-						err := createFileMeta(v.projectInfo, v.versionInfo, v.fileInfo, pack, index)
-						if err != nil {
-							return err
-						}
-						fmt.Printf("Dependency \"%s\" successfully added! (%s)\n", *v.projectInfo.Title, *v.fileInfo.Filename)
-					}
-				}
-			} else {
-				fmt.Println("All dependencies are already added!")
-			}
-		}
-	}
+	// The project and its dependencies are added together, or not at all
+	tx := core.NewTransaction()
+	defer tx.Rollback()
 
 	var file = version.Files[0]
 	// Prefer the primary file
@@ -374,21 +229,33 @@ func installVersion(project *modrinthApi.Project, version *modrinthApi.Version,
 	}
 	// TODO: handle optional/required resource pack files
 
-	// Create the metadata file
-	err := createFileMeta(project, version, file, pack, index)
+	modMeta, err := newFileMeta(project, version, file, pack)
+	if err != nil {
+		return err
+	}
+	deps, err := getVersionDependencies([]*modrinthApi.Version{version}, pack)
+	if err != nil {
+		fmt.Printf("Error retrieving dependency data: %v\n", err)
+	} else {
+		modMeta.Dependencies = deps[0]
+	}
+
+	err = cmdshared.AddDependencies([]*core.Mod{&modMeta}, pack, index, tx)
 	if err != nil {
 		return err
 	}
 
-	err = index.Write()
+	// Create the metadata file
+	err = writeFileMeta(&modMeta, index, tx)
 	if err != nil {
 		return err
 	}
-	err = pack.UpdateIndexHash()
+
+	err = tx.WriteIndexAndPack(*index, &pack)
 	if err != nil {
 		return err
 	}
-	err = pack.Write()
+	err = tx.Commit()
 	if err != nil {
 		return err
 	}
@@ -397,7 +264,8 @@ func installVersion(project *modrinthApi.Project, version *modrinthApi.Version,
 	return nil
 }
 
-func createFileMeta(project *modrinthApi.Project, version *modrinthApi.Version, file *modrinthApi.File, pack core.Pack, index *core.Index) error {
+// newFileMeta creates the metadata for a file of a project, without saving it
+func newFileMeta(project *modrinthApi.Project, version *modrinthApi.Version, file *modrinthApi.File, pack core.Pack) (core.Mod, error) {
 	updateMap := make(map[string]map[string]interface{})
 
 	var err error
@@ -406,7 +274,7 @@ func createFileMeta(project *modrinthApi.Project, version *modrinthApi.Version,
 		InstalledVersion: *version.ID,
 	}.ToMap()
 	if err != nil {
-		return err
+		return core.Mod{}, err
 	}
 
 	side := getSide(project)
@@ -417,12 +285,12 @@ func createFileMeta(project *modrinthApi.Project, version *modrinthApi.Version,
 
 	algorithm, hash := getBestHash(file)
 	if algorithm == "" {
-		return errors.New("file doesn't have a hash")
+		return core.Mod{}, errors.New("file doesn't have a hash")
 	}
 
 	// Validate and normalize disabled client platforms
 	if err := core.ValidateClientPlatforms(disabledClientPlatformsFlag); err != nil {
-		return fmt.Errorf("platform validation error: %v", err)
+		return core.Mod{}, fmt.Errorf("platform validation error: %v", err)
 	}
 	normalizedPlatforms := core.NormalizeClientPlatforms(disabledClientPlatformsFlag)
 
@@ -438,30 +306,33 @@ func createFileMeta(project *modrinthApi.Project, version *modrinthApi.Version,
 		},
 		Update: updateMap,
 	}
-	var path string
 	folder := viper.GetString("meta-folder")
 	if folder == "" {
 		folder, err = getProjectTypeFolder(*project.ProjectType, version.Loaders, pack.GetCompatibleLoaders())
 		if err != nil {
-			return err
+			return core.Mod{}, err
 		}
 	}
 	if project.Slug != nil {
-		path = modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder, *project.Slug+core.MetaExtension))
+		modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder, *project.Slug+core.MetaExtension))
 	} else {
-		path = modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder, core.SlugifyName(*project.Title)+core.MetaExtension))
+		modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder, core.SlugifyName(*project.Title)+core.MetaExtension))
 	}
+	return modMeta, nil
+}
 
+// writeFileMeta stages a metadata file and adds it to the index
+func writeFileMeta(modMeta *core.Mod, index *core.Index, tx *core.Transaction) error {
 	// If the file already exists, this will overwrite it!!!
 	// TODO: Should this be improved?
 	// Current strategy is to go ahead and do stuff without asking, with the assumption that you are using
 	// VCS anyway.
 
-	format, hash, err := modMeta.Write()
+	format, hash, err := tx.WriteMod(modMeta)
 	if err != nil {
 		return err
 	}
-	return index.RefreshFileWithHash(path, format, hash, true)
+	return index.RefreshFileWithHash(modMeta.GetFilePath(), format, hash, true)
 }
 
 var projectIDFlag string
@@ -28,6 +28,10 @@ var mrDefaultClient = modrinthApi.NewClient(&http.Client{})
 func init() {
 	cmd.Add(modrinthCmd)
 	core.Updaters["modrinth"] = mrUpdater{}
+	core.DependencyResolvers["modrinth"] = mrDependencyResolver{}
+	core.FileIdentifiers["modrinth"] = mrFileIdentifier{}
+	core.ChangelogProviders["modrinth"] = mrChangelogProvider{}
+	core.ModValidators["modrinth"] = mrModValidator{}
 
 	mrDefaultClient.UserAgent = core.UserAgent
 }
@@ -307,7 +311,9 @@ func findLatestVersion(versions []*modrinthApi.Version, gameVersions []string, u
 	return latestValidVersion
 }
 
-func getLatestVersion(projectID string, name string, pack core.Pack) (*modrinthApi.Version, error) {
+// getLatestVersion finds the latest version of a project that is compatible with the pack, respecting the release
+// channel and version constraint of the given mod (which may be nil, for new mods)
+func getLatestVersion(projectID string, name string, pack core.Pack, mod *core.Mod) (*modrinthApi.Version, error) {
 	gameVersions, err := pack.GetSupportedMCVersions()
 	if err != nil {
 		return nil, err
@@ -331,6 +337,34 @@ func getLatestVersion(projectID string, name string, pack core.Pack) (*modrinthA
 		return nil, errors.New("no valid versions found\n\tUse the 'packwiz settings acceptable-versions' command to accept more game versions\n\tTo use datapacks, add a datapack loader mod and specify the datapack-folder option with the folder this mod loads datapacks from")
 	}
 
+	// Filter out versions that are less stable than the release channel allows
+	channel := pack.GetReleaseChannel(mod)
+	i := 0
+	for _, v := range result {
+		if v.VersionType == nil || core.ReleaseChannelAllows(channel, *v.VersionType) {
+			result[i] = v
+			i++
+		}
+	}
+	result = result[:i]
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no valid versions found in the %s release channel\n\tUse the release-channel option, or 'packwiz modify --channel', to allow less stable versions", channel)
+	}
+
+	if mod != nil && !mod.Constraint.IsEmpty() {
+		i = 0
+		for _, v := range result {
+			if versionAllowed(mod.Constraint, v) {
+				result[i] = v
+				i++
+			}
+		}
+		result = result[:i]
+		if len(result) == 0 {
+			return nil, fmt.Errorf("no valid versions match the version constraint (%s)", mod.Constraint)
+		}
+	}
+
 	// TODO: option to always compare using flexver?
 	// TODO: ask user which one to use?
 	flexverLatest := findLatestVersion(result, gameVersions, true)
@@ -342,6 +376,25 @@ func getLatestVersion(projectID string, name string, pack core.Pack) (*modrinthA
 	return releaseDateLatest, nil
 }
 
+// versionAllowed checks a version against a mod's version constraint, using its ID, version number and file names
+func versionAllowed(constraint *core.ModConstraint, v *modrinthApi.Version) bool {
+	var versionNumber string
+	var ids []string
+	if v.VersionNumber != nil {
+		versionNumber = core.ModVersion(*v.VersionNumber, v.GameVersions...)
+		ids = append(ids, *v.VersionNumber)
+	}
+	if v.ID != nil {
+		ids = append(ids, *v.ID)
+	}
+	for _, f := range v.Files {
+		if f.Filename != nil {
+			ids = append(ids, *f.Filename)
+		}
+	}
+	return constraint.Allows(versionNumber, ids...)
+}
+
 func getSide(mod *modrinthApi.Project) string {
 	server := shouldDownloadOnSide(*mod.ServerSide)
 	client := shouldDownloadOnSide(*mod.ClientSide)
@@ -33,6 +33,10 @@ func (u mrUpdater) ParseUpdate(updateUnparsed map[string]interface{}) (interface
 type cachedStateStore struct {
 	ProjectID string
 	Version   *modrinthApi.Version
+	// Dependencies of the new version; nil if they couldn't be retrieved
+	Dependencies []core.ModDependency
+	// FileName is the name of the file to use from the new version; the primary file is used if it is empty
+	FileName string
 }
 
 func (u mrUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateCheck, error) {
@@ -47,7 +51,7 @@ func (u mrUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 
 		data := rawData.(mrUpdateData)
 
-		newVersion, err := getLatestVersion(data.ProjectID, mod.Name, pack)
+		newVersion, err := getLatestVersion(data.ProjectID, mod.Name, pack, mod)
 		if err != nil {
 			results[i] = core.UpdateCheck{Error: fmt.Errorf("failed to get latest version: %v", err)}
 			continue
@@ -74,7 +78,35 @@ func (u mrUpdater) CheckUpdate(mods []*core.Mod, pack core.Pack) ([]core.UpdateC
 		results[i] = core.UpdateCheck{
 			UpdateAvailable: true,
 			UpdateString:    mod.FileName + " -> " + *newFilename,
-			CachedState:     cachedStateStore{data.ProjectID, newVersion},
+			NewFileName:     *newFilename,
+			NewVersion:      versionChangelog(newVersion),
+			CachedState:     cachedStateStore{ProjectID: data.ProjectID, Version: newVersion},
+		}
+		if newVersion.VersionType != nil {
+			results[i].ReleaseChannel = *newVersion.VersionType
+		}
+	}
+
+	// Look up the dependencies of the new versions all at once, so they can be recorded when updating
+	var updatedIdxs []int
+	var updatedVersions []*modrinthApi.Version
+	for i, v := range results {
+		if state, ok := v.CachedState.(cachedStateStore); ok {
+			updatedIdxs = append(updatedIdxs, i)
+			updatedVersions = append(updatedVersions, state.Version)
+		}
+	}
+	if len(updatedVersions) > 0 {
+		deps, err := getVersionDependencies(updatedVersions, pack)
+		if err == nil {
+			for j, i := range updatedIdxs {
+				state := results[i].CachedState.(cachedStateStore)
+				state.Dependencies = deps[j]
+				if state.Dependencies == nil {
+					state.Dependencies = []core.ModDependency{}
+				}
+				results[i].CachedState = state
+			}
 		}
 	}
 
@@ -87,10 +119,23 @@ func (u mrUpdater) DoUpdate(mods []*core.Mod, cachedState []interface{}) error {
 		var version = modState.Version
 
 		var file = version.Files[0]
-		// Prefer the primary file
-		for _, v := range version.Files {
-			if *v.Primary {
-				file = v
+		if modState.FileName != "" {
+			found := false
+			for _, v := range version.Files {
+				if *v.Filename == modState.FileName {
+					file = v
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("file with name %s not found in version %s of %s", modState.FileName, *version.ID, mod.Name)
+			}
+		} else {
+			// Prefer the primary file
+			for _, v := range version.Files {
+				if *v.Primary {
+					file = v
+				}
 			}
 		}
 
@@ -106,6 +151,9 @@ func (u mrUpdater) DoUpdate(mods []*core.Mod, cachedState []interface{}) error {
 			Hash:       hash,
 		}
 		mod.Update["modrinth"]["version"] = version.ID
+		if modState.Dependencies != nil {
+			mod.Dependencies = modState.Dependencies
+		}
 	}
 
 	return nil
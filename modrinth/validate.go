@@ -0,0 +1,94 @@
+package modrinth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	modrinthApi "codeberg.org/jmansfield/go-modrinth/modrinth"
+	"github.com/codecraft3r/packwiz/core"
+)
+
+type mrModValidator struct{}
+
+// ValidateMods checks that the project and version of each mod still exist on Modrinth, that the version is marked as
+// compatible with the pack, and that the project hasn't been archived or removed
+func (mrModValidator) ValidateMods(mods []*core.Mod, pack core.Pack) ([]core.ModValidation, error) {
+	results := make([]core.ModValidation, len(mods))
+	mcVersions, err := pack.GetSupportedMCVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]mrUpdateData, len(mods))
+	var projectIDs, versionIDs []string
+	for i, mod := range mods {
+		rawData, ok := mod.GetParsedUpdateData("modrinth")
+		if !ok {
+			results[i].Errors = append(results[i].Errors, "failed to parse update metadata")
+			continue
+		}
+		data[i] = rawData.(mrUpdateData)
+		projectIDs = append(projectIDs, data[i].ProjectID)
+		versionIDs = append(versionIDs, data[i].InstalledVersion)
+	}
+	if len(projectIDs) == 0 {
+		return results, nil
+	}
+
+	projectList, err := mrDefaultClient.Projects.GetMultiple(projectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch projects: %w", err)
+	}
+	versionList, err := mrDefaultClient.Versions.GetMultiple(versionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch versions: %w", err)
+	}
+	projects := make(map[string]*modrinthApi.Project)
+	for _, v := range projectList {
+		projects[*v.ID] = v
+	}
+	versions := make(map[string]*modrinthApi.Version)
+	for _, v := range versionList {
+		versions[*v.ID] = v
+	}
+
+	for i, mod := range mods {
+		if data[i].ProjectID == "" {
+			continue
+		}
+		project, ok := projects[data[i].ProjectID]
+		if !ok {
+			results[i].Errors = append(results[i].Errors, "project "+data[i].ProjectID+" has been deleted from Modrinth")
+			continue
+		}
+		if project.Status != nil {
+			switch *project.Status {
+			case "archived":
+				results[i].Warnings = append(results[i].Warnings, "project has been archived, and won't be updated")
+			case "rejected", "withheld":
+				results[i].Warnings = append(results[i].Warnings, "project has been removed from Modrinth search (status: "+*project.Status+")")
+			}
+		}
+
+		version, ok := versions[data[i].InstalledVersion]
+		if !ok {
+			results[i].Errors = append(results[i].Errors, "version "+data[i].InstalledVersion+" has been deleted from Modrinth")
+			continue
+		}
+		if !slices.ContainsFunc(version.Files, func(f *modrinthApi.File) bool {
+			return f.Filename != nil && *f.Filename == mod.FileName
+		}) {
+			results[i].Errors = append(results[i].Errors, "file "+mod.FileName+" isn't in version "+data[i].InstalledVersion)
+		}
+		if !slices.ContainsFunc(version.GameVersions, func(v string) bool { return slices.Contains(mcVersions, v) }) {
+			results[i].Warnings = append(results[i].Warnings, fmt.Sprintf("version isn't marked as supporting Minecraft %s (only %s)",
+				strings.Join(mcVersions, ", "), strings.Join(version.GameVersions, ", ")))
+		}
+		if !core.LoadersCompatible(version.Loaders, pack) {
+			results[i].Warnings = append(results[i].Warnings, "version isn't marked as supporting the pack's loaders (only "+
+				strings.Join(version.Loaders, ", ")+")")
+		}
+	}
+	return results, nil
+}
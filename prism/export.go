@@ -0,0 +1,300 @@
+package prism
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/codecraft3r/packwiz/cmdshared"
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const bootstrapJarName = "packwiz-installer-bootstrap.jar"
+const bootstrapJarURL = "https://github.com/packwiz/packwiz-installer-bootstrap/releases/latest/download/" + bootstrapJarName
+
+// minecraftFolder is the folder in the instance zip containing the game files
+const minecraftFolder = ".minecraft"
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the current modpack into an instance zip for Prism Launcher or MultiMC",
+	Long: `Export the current modpack into an instance zip for Prism Launcher or MultiMC.
+
+By default, the instance installs and updates the modpack using packwiz-installer when it is launched, from the
+pack.toml URL given by --pack-url. Use --embed to include every file in the instance instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		packURL := viper.GetString("prism.export.pack-url")
+		embed := viper.GetBool("prism.export.embed")
+		if packURL == "" && !embed {
+			fmt.Println("Either --pack-url or --embed must be specified")
+			os.Exit(1)
+		}
+
+		fmt.Println("Loading modpack...")
+		pack, err := core.LoadPack()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		index, err := pack.LoadIndex()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// Do a refresh to ensure files are up to date
+		err = index.Refresh()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = index.Write()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = pack.UpdateIndexHash()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = pack.Write()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		components, err := newMMCPack(pack.Versions)
+		if err != nil {
+			fmt.Println("Error creating component list: " + err.Error())
+			os.Exit(1)
+		}
+
+		name := pack.Name
+		if name == "" {
+			name = pack.GetPackName()
+		}
+		cfg := newInstanceConfig()
+		cfg.Set("InstanceType", "OneSix")
+		cfg.Set("name", name)
+		iconKey := "default"
+		iconPath := viper.GetString("prism.export.icon")
+		if iconPath == "" {
+			// Use the pack icon, if there is one next to pack.toml
+			defaultIcon := filepath.Join(filepath.Dir(pack.PackFile()), "icon.png")
+			if _, err := os.Stat(defaultIcon); err == nil {
+				iconPath = defaultIcon
+			}
+		}
+		if iconPath != "" {
+			iconKey = core.SlugifyName(name)
+			if iconKey == "" {
+				iconKey = "packwiz"
+			}
+		}
+		cfg.Set("iconKey", iconKey)
+		if !embed {
+			cfg.Set("OverrideCommands", "true")
+			cfg.Set("PreLaunchCommand", `"$INST_JAVA" -jar `+bootstrapJarName+" "+packURL)
+		}
+
+		fileName := viper.GetString("prism.export.output")
+		if fileName == "" {
+			fileName = pack.GetPackName() + "-prism.zip"
+		}
+		expFile, err := os.Create(fileName)
+		if err != nil {
+			fmt.Printf("Failed to create zip: %s\n", err.Error())
+			os.Exit(1)
+		}
+		exp := zip.NewWriter(expFile)
+		fail := func(msg string, err error) {
+			_ = exp.Close()
+			_ = expFile.Close()
+			_ = os.Remove(fileName)
+			fmt.Println(msg + ": " + err.Error())
+			os.Exit(1)
+		}
+
+		cfgFile, err := exp.Create("instance.cfg")
+		if err == nil {
+			_, err = cfg.WriteTo(cfgFile)
+		}
+		if err != nil {
+			fail("Error writing instance.cfg", err)
+		}
+
+		packFile, err := exp.Create("mmc-pack.json")
+		if err == nil {
+			w := json.NewEncoder(packFile)
+			w.SetIndent("", "    ")
+			err = w.Encode(components)
+		}
+		if err != nil {
+			fail("Error writing mmc-pack.json", err)
+		}
+
+		if iconPath != "" {
+			err = addFileToZip(exp, iconKey+".png", iconPath)
+			if err != nil {
+				fail("Error adding icon", err)
+			}
+		}
+
+		// Add the game folder even if there are no files to go in it
+		_, err = exp.Create(minecraftFolder + "/")
+		if err != nil {
+			fail("Failed to add "+minecraftFolder+" folder", err)
+		}
+
+		if embed {
+			fmt.Println("Reading external files...")
+			allMods, err := index.LoadAllMods()
+			if err != nil {
+				fail("Error reading file", err)
+			}
+			var mods []*core.Mod
+			for _, mod := range allMods {
+				if mod.Side == core.ServerSide {
+					continue
+				}
+				if mod.Option != nil && mod.Option.Optional && !mod.Option.Default {
+					continue
+				}
+				mods = append(mods, mod)
+			}
+
+			fmt.Printf("Retrieving %v external files...\n", len(mods))
+			cmdshared.PrintDisclaimer(false)
+			session, err := core.CreateDownloadSession(mods, []string{})
+			if err != nil {
+				fail("Error retrieving external files", err)
+			}
+			cmdshared.ListManualDownloads(session)
+
+			failed := false
+			for dl := range session.StartDownloads() {
+				if !cmdshared.AddToZip(dl, exp, minecraftFolder, &index) {
+					failed = true
+				}
+			}
+			err = session.SaveIndex()
+			if err != nil {
+				fail("Error saving cache index", err)
+			}
+			if failed {
+				fail("Error exporting modpack", errors.New("some files could not be added to the instance"))
+			}
+
+			if !cmdshared.AddNonMetafileFiles(&index, exp, minecraftFolder) {
+				fail("Error exporting modpack", errors.New("some files in the pack folder could not be added to the instance"))
+			}
+		} else {
+			fmt.Println("Retrieving packwiz-installer-bootstrap...")
+			err = addBootstrapJar(exp)
+			if err != nil {
+				fail("Error adding "+bootstrapJarName, err)
+			}
+		}
+
+		err = exp.Close()
+		if err != nil {
+			fmt.Println("Error writing export file: " + err.Error())
+			os.Exit(1)
+		}
+		err = expFile.Close()
+		if err != nil {
+			fmt.Println("Error writing export file: " + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println("Instance exported to " + fileName)
+	},
+}
+
+func addFileToZip(exp *zip.Writer, name string, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dest, err := exp.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dest, src)
+	return err
+}
+
+// addBootstrapJar adds packwiz-installer-bootstrap to the game folder, using a local copy if one is given
+func addBootstrapJar(exp *zip.Writer) error {
+	source := bootstrapJarURL
+	var data []byte
+	var err error
+	if path := viper.GetString("prism.export.bootstrap-jar"); path != "" {
+		source = path
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = downloadBootstrapJar()
+	}
+	if err != nil {
+		return err
+	}
+	// The instance can't launch without a working jar, so check that this isn't e.g. an error page
+	jar, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid jar file: %w", source, err)
+	}
+	if !slices.ContainsFunc(jar.File, func(f *zip.File) bool { return f.Name == "META-INF/MANIFEST.MF" }) {
+		return fmt.Errorf("%s is not a valid jar file: no manifest found", source)
+	}
+
+	dest, err := exp.Create(minecraftFolder + "/" + bootstrapJarName)
+	if err != nil {
+		return err
+	}
+	_, err = dest.Write(data)
+	return err
+}
+
+// downloadBootstrapJar downloads the latest release of packwiz-installer-bootstrap
+func downloadBootstrapJar() ([]byte, error) {
+	resp, err := core.GetWithUA(bootstrapJarURL, "application/java-archive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", bootstrapJarURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: invalid status code %v", bootstrapJarURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", bootstrapJarURL, err)
+	}
+	return data, nil
+}
+
+func init() {
+	prismCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("pack-url", "", "The URL of pack.toml, which packwiz-installer installs the modpack from")
+	_ = viper.BindPFlag("prism.export.pack-url", exportCmd.Flags().Lookup("pack-url"))
+	exportCmd.Flags().Bool("embed", false, "Include every file in the instance, instead of installing them with packwiz-installer")
+	_ = viper.BindPFlag("prism.export.embed", exportCmd.Flags().Lookup("embed"))
+	exportCmd.Flags().String("icon", "", "A PNG image to use as the instance icon (defaults to icon.png next to pack.toml, if it exists)")
+	_ = viper.BindPFlag("prism.export.icon", exportCmd.Flags().Lookup("icon"))
+	exportCmd.Flags().String("bootstrap-jar", "", "A local copy of "+bootstrapJarName+" to use, instead of downloading the latest release")
+	_ = viper.BindPFlag("prism.export.bootstrap-jar", exportCmd.Flags().Lookup("bootstrap-jar"))
+	exportCmd.Flags().StringP("output", "o", "", "The file to export the instance to")
+	_ = viper.BindPFlag("prism.export.output", exportCmd.Flags().Lookup("output"))
+}
@@ -0,0 +1,382 @@
+package prism
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// contentFolders are the folders in the game folder that contain files that can be identified on Modrinth or
+// CurseForge; files in them that can't be identified are copied instead
+var contentFolders = []string{"mods", "resourcepacks", "shaderpacks"}
+
+// overrideFolders are the folders in the game folder (other than contentFolders) that are copied into the pack; other
+// files (such as worlds, logs and screenshots) are specific to the player, and are left out
+var overrideFolders = []string{"config", "defaultconfigs", "kubejs", "scripts", "global_packs", "openloader"}
+
+// disabledSuffix is added to the names of files that have been disabled in Prism Launcher / MultiMC
+const disabledSuffix = ".disabled"
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [instance folder or zip]",
+	Short: "Import a Prism Launcher or MultiMC instance, creating metadata files for files found on Modrinth or CurseForge",
+	Long: `Import a Prism Launcher or MultiMC instance, creating metadata files for files found on Modrinth or CurseForge.
+
+The Minecraft and mod loader versions are read from mmc-pack.json. Files in the mods, resourcepacks and shaderpacks
+folders are looked up on Modrinth and then CurseForge by their hashes; files that can't be found are copied into the
+pack, along with configuration folders (` + strings.Join(overrideFolders, ", ") + `) and any paths given by --include.
+Files disabled in the launcher are added as optional files that are disabled by default.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		instance, closeInstance, err := openInstance(args[0])
+		if err != nil {
+			fmt.Printf("Failed to open instance: %v\n", err)
+			os.Exit(1)
+		}
+		defer closeInstance()
+
+		components, err := readMMCPack(instance)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		instanceName := ""
+		if f, err := instance.Open("instance.cfg"); err == nil {
+			cfg, err := readInstanceConfig(f)
+			_ = f.Close()
+			if err != nil {
+				fmt.Printf("Failed to read instance.cfg: %v\n", err)
+				os.Exit(1)
+			}
+			instanceName = cfg.Get("name")
+		}
+		gameDir := findGameFolder(instance)
+		if gameDir == "" {
+			fmt.Println("Can't find the .minecraft folder of the instance")
+			os.Exit(1)
+		}
+		gameFS, err := fs.Sub(instance, gameDir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		pack, err := core.LoadPack()
+		if err != nil {
+			// Don't overwrite a pack that exists but can't be loaded
+			if !errors.Is(err, os.ErrNotExist) {
+				fmt.Printf("Failed to load existing pack: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("No existing pack found, creating a new one...")
+			pack, err = newPack(instanceName)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if pack.Versions == nil {
+			pack.Versions = make(map[string]string)
+		}
+		for component, version := range components.versions() {
+			packVersion, ok := pack.Versions[component]
+			if !ok {
+				fmt.Println("Set " + core.ComponentToFriendlyName(component) + " version to " + version)
+			} else if packVersion != version {
+				fmt.Println("Set " + core.ComponentToFriendlyName(component) + " version to " + version + " (previously " + packVersion + ")")
+			}
+			pack.Versions[component] = version
+		}
+		index, err := pack.LoadIndex()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Hashing files...")
+		var files []core.IdentifyFile
+		var copies []string
+		for _, folder := range contentFolders {
+			err = walkFiles(gameFS, folder, func(p string) error {
+				f, err := gameFS.Open(p)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				file, err := core.HashIdentifyReader(p, f)
+				if err != nil {
+					return err
+				}
+				files = append(files, file)
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("Failed to read instance files: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Looking up %d files...\n", len(files))
+		results, err := core.IdentifyFiles(files, pack)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		identified := 0
+		metaPaths := make(map[string]string)
+		for i, res := range results {
+			p := files[i].Path
+			mod := res.Mod
+			if mod == nil {
+				copies = append(copies, p)
+				continue
+			}
+			if other, ok := metaPaths[mod.GetFilePath()]; ok {
+				fmt.Printf("%s is another version of %s (%s); copying it instead\n", p, mod.Name, other)
+				copies = append(copies, p)
+				continue
+			}
+			metaPaths[mod.GetFilePath()] = p
+			if strings.HasSuffix(p, disabledSuffix) {
+				mod.Option = &core.ModOption{Optional: true, Default: false}
+			}
+			format, hash, err := tx.WriteMod(mod)
+			if err == nil {
+				err = index.RefreshFileWithHash(mod.GetFilePath(), format, hash, true)
+			}
+			if err != nil {
+				tx.Rollback()
+				fmt.Printf("Failed to save metadata for %s: %v\n", mod.Name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s identified as %s (%s)\n", p, mod.Name, mod.FileName)
+			identified++
+		}
+		fmt.Printf("Identified %d/%d files\n", identified, len(files))
+
+		// Save the metadata files before copying overrides, so they are found when the index is refreshed
+		err = tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, folder := range slices.Concat(overrideFolders, viper.GetStringSlice("prism.import.include")) {
+			err = walkFiles(gameFS, path.Clean(filepath.ToSlash(folder)), func(p string) error {
+				copies = append(copies, p)
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("Failed to read instance files: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		copied := 0
+		for _, p := range copies {
+			err = copyInstanceFile(gameFS, p, index.ResolveIndexPath(p))
+			if err != nil {
+				fmt.Printf("Failed to copy file \"%s\": %v\n", p, err)
+				continue
+			}
+			copied++
+		}
+		fmt.Printf("Copied %d/%d files\n", copied, len(copies))
+
+		err = index.Refresh()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		tx = core.NewTransaction()
+		err = tx.WriteIndexAndPack(index, &pack)
+		if err != nil {
+			tx.Rollback()
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = tx.Commit()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Instance imported successfully!")
+	},
+}
+
+// openInstance opens an instance folder or zip; in zips, the instance can be in a subfolder
+func openInstance(p string) (fs.FS, func(), error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(p), func() {}, nil
+	}
+
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeZip := func() {
+		_ = zr.Close()
+	}
+	// Use the shallowest folder containing mmc-pack.json
+	root := ""
+	found := false
+	for _, f := range zr.File {
+		if path.Base(f.Name) != "mmc-pack.json" {
+			continue
+		}
+		dir := path.Dir(f.Name)
+		if !found || zipDepth(dir) < zipDepth(root) {
+			root = dir
+			found = true
+		}
+	}
+	if !found {
+		closeZip()
+		return nil, nil, errors.New("can't find mmc-pack.json, is this a valid instance?")
+	}
+	sub, err := fs.Sub(zr, root)
+	if err != nil {
+		closeZip()
+		return nil, nil, err
+	}
+	return sub, closeZip, nil
+}
+
+func zipDepth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+func readMMCPack(instance fs.FS) (mmcPack, error) {
+	data, err := fs.ReadFile(instance, "mmc-pack.json")
+	if err != nil {
+		return mmcPack{}, fmt.Errorf("failed to read mmc-pack.json: %w", err)
+	}
+	var components mmcPack
+	err = json.Unmarshal(data, &components)
+	if err != nil {
+		return mmcPack{}, fmt.Errorf("failed to parse mmc-pack.json: %w", err)
+	}
+	if _, ok := components.versions()["minecraft"]; !ok {
+		return mmcPack{}, errors.New("mmc-pack.json doesn't contain a Minecraft version")
+	}
+	return components, nil
+}
+
+// findGameFolder returns the name of the folder in the instance containing the game files
+func findGameFolder(instance fs.FS) string {
+	for _, name := range []string{minecraftFolder, "minecraft"} {
+		if info, err := fs.Stat(instance, name); err == nil && info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// walkFiles calls fn for every file in a folder (or for the path itself if it is a file), in lexical order; missing
+// folders are ignored
+func walkFiles(fsys fs.FS, root string, fn func(p string) error) error {
+	var paths []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		err = fn(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyInstanceFile(fsys fs.FS, src string, dest string) error {
+	in, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// newPack creates pack.toml and an empty index for a new modpack
+func newPack(name string) (core.Pack, error) {
+	indexFilePath := viper.GetString("init.index-file")
+	_, err := os.Stat(indexFilePath)
+	if os.IsNotExist(err) {
+		err = os.WriteFile(indexFilePath, []byte{}, 0644)
+		if err != nil {
+			return core.Pack{}, fmt.Errorf("error creating index file: %w", err)
+		}
+		fmt.Println(indexFilePath + " created!")
+	} else if err != nil {
+		return core.Pack{}, fmt.Errorf("error checking index file: %w", err)
+	}
+
+	pack := core.Pack{
+		Name:       name,
+		PackFormat: core.CurrentPackFormat,
+		Versions:   make(map[string]string),
+	}
+	pack.Index.File = indexFilePath
+	return pack, nil
+}
+
+func init() {
+	prismCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringSlice("include", nil, "Additional files or folders in the game folder to copy into the pack")
+	_ = viper.BindPFlag("prism.import.include", importCmd.Flags().Lookup("include"))
+}
@@ -0,0 +1,161 @@
+package prism
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/codecraft3r/packwiz/cmd"
+	"github.com/spf13/cobra"
+)
+
+var prismCmd = &cobra.Command{
+	Use:     "prism",
+	Aliases: []string{"multimc", "mmc"},
+	Short:   "Manage Prism Launcher / MultiMC instances",
+}
+
+func init() {
+	cmd.Add(prismCmd)
+}
+
+// componentUIDs maps the components in pack.toml to the UIDs used in mmc-pack.json
+var componentUIDs = map[string]string{
+	"minecraft":  "net.minecraft",
+	"fabric":     "net.fabricmc.fabric-loader",
+	"quilt":      "org.quiltmc.quilt-loader",
+	"forge":      "net.minecraftforge",
+	"neoforge":   "net.neoforged",
+	"liteloader": "com.mumfrey.liteloader",
+}
+
+// intermediaryUID is the UID of the mappings required by Fabric and Quilt
+const intermediaryUID = "net.fabricmc.intermediary"
+
+// mmcPack is the list of components (Minecraft and mod loaders) installed in an instance, stored in mmc-pack.json
+type mmcPack struct {
+	Components    []mmcComponent `json:"components"`
+	FormatVersion int            `json:"formatVersion"`
+}
+
+type mmcComponent struct {
+	UID            string `json:"uid"`
+	Version        string `json:"version,omitempty"`
+	Important      bool   `json:"important,omitempty"`
+	DependencyOnly bool   `json:"dependencyOnly,omitempty"`
+}
+
+// newMMCPack creates the component list for the given pack.toml versions; Minecraft is always first
+func newMMCPack(versions map[string]string) (mmcPack, error) {
+	mcVersion, ok := versions["minecraft"]
+	if !ok {
+		return mmcPack{}, fmt.Errorf("no minecraft version specified in modpack")
+	}
+	pack := mmcPack{
+		Components:    []mmcComponent{{UID: componentUIDs["minecraft"], Version: mcVersion, Important: true}},
+		FormatVersion: 1,
+	}
+	_, hasFabric := versions["fabric"]
+	_, hasQuilt := versions["quilt"]
+	if hasFabric || hasQuilt {
+		pack.Components = append(pack.Components, mmcComponent{UID: intermediaryUID, Version: mcVersion, DependencyOnly: true})
+	}
+
+	components := make([]string, 0, len(versions))
+	for component := range versions {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		if component == "minecraft" {
+			continue
+		}
+		uid, ok := componentUIDs[component]
+		if !ok {
+			return mmcPack{}, fmt.Errorf("component %s is not supported by Prism Launcher / MultiMC", component)
+		}
+		pack.Components = append(pack.Components, mmcComponent{UID: uid, Version: versions[component]})
+	}
+	return pack, nil
+}
+
+// versions returns the pack.toml versions of the components in the instance, ignoring ones that packwiz doesn't
+// support (such as intermediary mappings)
+func (p mmcPack) versions() map[string]string {
+	versions := make(map[string]string)
+	for _, c := range p.Components {
+		for component, uid := range componentUIDs {
+			if c.UID == uid {
+				versions[component] = c.Version
+			}
+		}
+	}
+	return versions
+}
+
+// instanceConfig is the contents of instance.cfg; keys are written in the order they were set
+type instanceConfig struct {
+	keys   []string
+	values map[string]string
+}
+
+func newInstanceConfig() *instanceConfig {
+	return &instanceConfig{values: make(map[string]string)}
+}
+
+func (c *instanceConfig) Set(key string, value string) {
+	if _, ok := c.values[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.values[key] = value
+}
+
+func (c *instanceConfig) Get(key string) string {
+	return c.values[key]
+}
+
+// escapeConfigValue escapes a value in the same way as Qt's QSettings, which Prism Launcher and MultiMC use
+func escapeConfigValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value)
+	if escaped != value || strings.ContainsAny(value, ",;=#") || strings.TrimSpace(value) != value {
+		return `"` + escaped + `"`
+	}
+	return value
+}
+
+func unescapeConfigValue(value string) string {
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value
+	}
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t").Replace(value[1 : len(value)-1])
+}
+
+func (c *instanceConfig) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	sb.WriteString("[General]\n")
+	for _, key := range c.keys {
+		sb.WriteString(key + "=" + escapeConfigValue(c.values[key]) + "\n")
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// readInstanceConfig parses instance.cfg; section headers are ignored, as every key is in the General section
+func readInstanceConfig(r io.Reader) (*instanceConfig, error) {
+	c := newInstanceConfig()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		c.Set(strings.TrimSpace(key), unescapeConfigValue(strings.TrimSpace(value)))
+	}
+	return c, scanner.Err()
+}
@@ -109,27 +109,27 @@ var installCmd = &cobra.Command{
 		destPath := modMeta.SetMetaPath(filepath.Join(viper.GetString("meta-folder-base"), folder,
 			destPathName+core.MetaExtension))
 
-		format, hash, err := modMeta.Write()
+		tx := core.NewTransaction()
+		defer tx.Rollback()
+		format, hash, err := tx.WriteMod(&modMeta)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
 		err = index.RefreshFileWithHash(destPath, format, hash, true)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = index.Write()
+		err = tx.WriteIndexAndPack(index, &pack)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = pack.UpdateIndexHash()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		err = pack.Write()
+		err = tx.Commit()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)